@@ -1,14 +1,14 @@
 package clive
 
 import (
-	"errors"
 	"fmt"
+	"os"
 	"reflect"
-	"strconv"
 	"strings"
+	"time"
 
+	core "github.com/ASMfreaK/clive2/internal/clive"
 	"github.com/hashicorp/go-multierror"
-	"github.com/iancoleman/strcase"
 	"github.com/urfave/cli/v2"
 )
 
@@ -25,14 +25,33 @@ type (
 	HasAfter interface {
 		After(*cli.Context) error
 	}
-	WithVersion interface {
-		Version() string
+	// HasOnUsageError is implemented by commands handling their errors
+	// parsing the command line, see cli.OnUsageErrorFunc.
+	HasOnUsageError interface {
+		OnUsageError(ctx *cli.Context, err error, isSubcommand bool) error
 	}
-	WithDescription interface {
-		Description() string
+)
+
+// Interfaces only the root command struct can implement, they set the fields
+// of the App of the same names.
+type (
+	WithAuthors interface {
+		Authors() []*cli.Author
+	}
+	WithCopyright interface {
+		Copyright() string
 	}
-	HasVariants interface {
-		Variants() []string
+	WithCompiled interface {
+		Compiled() time.Time
+	}
+	HasCommandNotFound interface {
+		CommandNotFound(ctx *cli.Context, command string)
+	}
+	HasExitErrHandler interface {
+		ExitErrHandler(ctx *cli.Context, err error)
+	}
+	HasInvalidFlagAccessHandler interface {
+		InvalidFlagAccessHandler(ctx *cli.Context, name string)
 	}
 )
 
@@ -75,7 +94,7 @@ func (c *Command) Current(ctx *cli.Context) interface{} {
 }
 
 func ErrCommandNotImplemented() error {
-	return errors.New("command not implemented")
+	return core.ErrCommandNotImplemented()
 }
 
 func (c *Command) Action(ctx *cli.Context) error {
@@ -86,12 +105,15 @@ func (c *Command) Action(ctx *cli.Context) error {
 	root := c.Root(ctx)
 	current := c.Current(ctx)
 
+	if err := unknownCommand(ctx); err != nil {
+		return err
+	}
 	var err error
 	if root == current {
 		err = cli.ShowAppHelp(ctx)
 	} else {
 		// parent := c.Parent(ctx)
-		err = cli.ShowSubcommandHelp(ctx)
+		err = showSubcommandHelp(ctx)
 		// if parent == root {
 		// } else {
 		// 	err = cli.ShowSubcommandHelp(ctx)
@@ -103,16 +125,6 @@ func (c *Command) Action(ctx *cli.Context) error {
 	return err
 }
 
-var ErrNil = errors.New("obj is n ull")
-
-type ByValueError struct {
-	Type string
-}
-
-func (e *ByValueError) Error() string {
-	return fmt.Sprintf("command struct %s is passed by value, pass by reference", e.Type)
-}
-
 type ActionableNotImplementedError struct {
 	Type string
 }
@@ -121,40 +133,51 @@ func (e *ActionableNotImplementedError) Error() string {
 	return fmt.Sprintf("command struct %s must implement Actionable", e.Type)
 }
 
-type WrongFirstFieldError struct {
-	NumFields int
-
-	FieldName string
-	Type      string
-}
-
-func (e *WrongFirstFieldError) Error() string {
-	return fmt.Sprintf(`
-	command struct:
-	* should have at least one field (have %d)
-	* its first field must be an embedded *clive.Command (name: %s, type: %s)
-	`, e.NumFields, e.FieldName, e.Type)
-}
-
-type HiddenPositionalError struct {
-	Name string
-}
-
-func (e *HiddenPositionalError) Error() string {
-	return fmt.Sprintf("positional argument %s cannot be Hidden", e.Name)
-}
-
-type PositionalAfterVariadicError struct {
-	CurrentName string
-	FirstName   string
+type BuildOptions struct {
+	EnvPrefix string
+	// HelpRenderer renders help for every command of the App, if nil
+	// DefaultHelpRenderer is used.
+	HelpRenderer HelpRenderer
+	// IgnoreGenerated makes Build use reflection even for command structs
+	// implementing Generated.
+	IgnoreGenerated bool
+	// NegatableBools adds a --no-<name> flag to every bool flag defaulting to
+	// true, unless it is tagged with negatable:false.
+	NegatableBools bool
+	// StrictEnv fails the App when environment variables with EnvPrefix set
+	// no flag of any command, instead of printing a warning to ErrWriter.
+	StrictEnv bool
+	// Middleware wraps the steps of every command, the first one
+	// outermost.
+	Middleware []Middleware
+	// ExitCodes sets the exit codes of classes of errors returned by ExitCode,
+	// falling back to DefaultExitCodes.
+	ExitCodes map[ErrorClass]int
+	// ExitOnError makes Apps print the errors of Run and exit with their exit
+	// codes, unless the root command struct implements ExitErrHandler.
+	ExitOnError bool
+	// Providers construct the values of fields tagged inject, see Provide.
+	// A provider replaces the earlier ones of the same type.
+	Providers []Provider
 }
 
-func (e *PositionalAfterVariadicError) Error() string {
-	return fmt.Sprintf("cant add positional argument %s after variadic (slice of x) argument %s", e.CurrentName, e.FirstName)
+// EnvVar returns the name of the environment variable for a flag with
+// EnvPrefix applied.
+func (bo *BuildOptions) EnvVar(name string) string {
+	return bo.options().EnvVar(name)
 }
 
-type BuildOptions struct {
-	EnvPrefix string
+// options returns the options of parsing command structs.
+func (bo *BuildOptions) options() *core.Options {
+	o := &core.Options{
+		EnvPrefix:      bo.EnvPrefix,
+		HelpRenderer:   bo.HelpRenderer,
+		NegatableBools: bo.NegatableBools,
+	}
+	if o.HelpRenderer == nil {
+		o.HelpRenderer = DefaultHelpRenderer
+	}
+	return o
 }
 
 var DefaultBuildOptions = BuildOptions{
@@ -178,6 +201,47 @@ func BuildCustom(obj interface{}, o BuildOptions) (c *cli.App) {
 	return
 }
 
+// Describe parses obj through reflection, the way Build does for command
+// structs without generated code, and returns the tree of commands, flags
+// and positional arguments it found. Unlike Build it returns
+// errors instead of panicking, and doesn't depend on urfave/cli: commands
+// don't have to be Actionable and subcommands constructed by hand (see
+// HasSubcommand) are left out.
+func Describe(obj interface{}) (*CommandSpec, error) {
+	return DescribeCustom(obj, DefaultBuildOptions)
+}
+
+func DescribeCustom(obj interface{}, o BuildOptions) (*CommandSpec, error) {
+	model, err := core.ParseCommand(obj, "", o.options())
+	if err != nil {
+		return nil, err
+	}
+	if model.Foreign {
+		return nil, fmt.Errorf("command %T is constructed by hand and can't be described", obj)
+	}
+	spec := model.Spec(o.options())
+	if versioned, ok := obj.(WithVersion); ok {
+		spec.Version = versioned.Version()
+	}
+	spec.SetPaths("")
+	return spec, nil
+}
+
+// describedModel returns model, or for command structs with generated code,
+// which leave the fields of the model empty, the model parsed through
+// reflection. Only the help of commands needs them.
+func describedModel(model *core.CommandModel, o *core.Options) *core.CommandModel {
+	if model.Generated == nil {
+		return model
+	}
+	parsed, err := core.ParseCommand(model.Obj, model.ParentPath, o)
+	if err != nil {
+		return model
+	}
+	parsed.Globals = model.Globals
+	return parsed
+}
+
 func flagsForActionable(act Actionable, c *cli.Context, bo *BuildOptions) (Actionable, error) {
 	objValue := reflect.ValueOf(act)
 	for objValue.Kind() == reflect.Ptr {
@@ -192,531 +256,437 @@ func flagsForActionable(act Actionable, c *cli.Context, bo *BuildOptions) (Actio
 }
 
 func flagsForValue(obj *reflect.Value, objType reflect.Type, c *cli.Context, bo *BuildOptions) error {
-	args := c.Args().Slice()
-	hadPositionals := false
-	for i := 1; i < objType.NumField(); i++ {
-		fieldType := objType.Field(i)
-		if fieldType.Name == "Subcommands" || (fieldType.Name == "Run" && fieldType.Type == reflect.TypeOf((RunFunc)(nil))) {
+	return core.BindValue(*obj, objType, c.Args().Slice(), bo.options(), func(cmdMeta *core.CommandMetadata, field reflect.Value) (string, string, error) {
+		if !c.IsSet(cmdMeta.Name) && cmdMeta.Default == nil {
+			return "", "", nil
+		}
+		return FlagSource(c, cmdMeta.Name), RawFlagValue(c, cmdMeta.Name), setFromContext(cmdMeta, field, c)
+	})
+}
+
+// RawFlagValue returns the value of the flag name in c as a string, for
+// error messages. It is used by generated code.
+func RawFlagValue(c *cli.Context, name string) string {
+	switch v := c.Value(name).(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ",")
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// FlagSource returns where the value of the flag name in c came from, for
+// error messages: "flag <name>" for the command line, "environment variable
+// <ENV>" and "default value of flag <name>". It is used by generated code.
+func FlagSource(c *cli.Context, name string) string {
+	if !c.IsSet(name) {
+		return "default value of flag " + name
+	}
+	// urfave/cli marks flags read from the environment as set, a value given
+	// on the command line replaces the one of the environment then
+	f, ok := lookupFlag(c, name).(cli.DocGenerationFlag)
+	if !ok || !f.IsSet() {
+		return "flag " + name
+	}
+	for _, env := range f.GetEnvVars() {
+		value, ok := os.LookupEnv(strings.TrimSpace(env))
+		if !ok {
 			continue
 		}
-		var flieldMetadata []commandMetadata
-		err := parseFieldOrPositional("", []int{i}, fieldType, &flieldMetadata, &flieldMetadata, bo)
-		if err != nil {
-			return err
+		if fromEnv(f, c.Value(name), value) {
+			return "environment variable " + env
 		}
-		for _, cmdMeta := range flieldMetadata {
-			if cmdMeta.Skipped {
-				continue
-			}
-			currentObj := obj.Addr()
-			var currentField reflect.Value
-			for accessIndex, fieldIndex := range cmdMeta.Accesses {
-				if accessIndex > 0 {
-					currentObj = currentField
-				}
-				currentField = currentObj.Elem().Field(fieldIndex).Addr()
-			}
-			var setFrom string
-			if cmdMeta.Positional {
-				hadPositionals = true
-				if len(args) == 0 {
-					if !cmdMeta.Required {
-						if cmdMeta.Default != nil {
-							err = cmdMeta.SetValueFromString(currentField, *cmdMeta.Default)
-							if err != nil {
-								setFrom = fmt.Sprintf("from default value %s", *cmdMeta.Default)
-							}
-						}
-					} else {
-						err = errors.New("too few positional arguments")
-					}
-				} else {
-					if cmdMeta.IsVariadic() {
-						err = cmdMeta.SetValueFromStrings(currentField, args)
-						args = []string{}
-					} else {
-						err = cmdMeta.SetValueFromString(currentField, args[0])
-						args = args[1:]
-					}
-				}
-				if err != nil {
-					setFrom = fmt.Sprintf("positional argument %s %s", strcase.ToScreamingSnake(cmdMeta.Name), setFrom)
-				}
-			} else {
-				if c.IsSet(cmdMeta.Name) || cmdMeta.Default != nil {
-					err = cmdMeta.SetValueFromContext(currentField, cmdMeta.Name, c)
-				}
-				if err != nil {
-					setFrom = fmt.Sprintf("from flag %s", cmdMeta.Name)
+		break
+	}
+	return "flag " + name
+}
+
+// lookupFlag returns the flag name of the command of c or of its ancestors.
+func lookupFlag(c *cli.Context, name string) cli.Flag {
+	for _, ctx := range c.Lineage() {
+		if ctx.Command == nil {
+			continue
+		}
+		for _, f := range ctx.Command.Flags {
+			for _, n := range f.Names() {
+				if n == name {
+					return f
 				}
 			}
-			if err != nil {
-				return fmt.Errorf("failed to set field %s (type %s) from %s: %s", fieldType.Name, fieldType.Type.String(), setFrom, err.Error())
-			}
 		}
 	}
-	if hadPositionals && len(args) > 0 {
-		return fmt.Errorf("too many arguments: %d left unparsed: %s", len(args), strings.Join(args, " "))
-	}
 	return nil
 }
 
+// fromEnv reports whether the value got of the flag f is the one read from
+// its environment variable value.
+func fromEnv(f cli.Flag, got interface{}, value string) bool {
+	switch got := got.(type) {
+	case []string:
+		var items []string
+		if list, ok := f.(*ListFlag); ok {
+			items, _ = core.SplitEnv(value, list.Sep, list.EnvFormat)
+		} else {
+			for _, item := range strings.Split(value, ",") {
+				items = append(items, strings.TrimSpace(item))
+			}
+		}
+		return reflect.DeepEqual(got, items)
+	case bool:
+		b, err := core.ParseBool(value)
+		return (err == nil && b == got) || (value == "" && !got)
+	}
+	return fmt.Sprint(got) == value
+}
+
+// parseCommand parses the command struct obj and its subcommands for Build,
+// taking the commands with generated code from it.
+func parseCommand(obj interface{}, bo *BuildOptions) (*core.CommandModel, error) {
+	o := bo.options()
+	o.Generated = bo.generated
+	return core.ParseCommand(obj, "", o)
+}
+
+func init() {
+	core.RegisterLibrary(core.Library{
+		Command: reflect.TypeOf((*Command)(nil)),
+		Run:     reflect.TypeOf((RunFunc)(nil)),
+		CommandName: func(command interface{}) string {
+			if c := command.(*Command); c.Command != nil {
+				return c.Name
+			}
+			return ""
+		},
+		Foreign: func(obj interface{}) bool {
+			_, ok := obj.(HasSubcommand)
+			return ok
+		},
+	})
+}
+
 func build(obj interface{}, bo *BuildOptions) (c *cli.App, err error) {
 	c = cli.NewApp()
 	c.Metadata = make(map[string]interface{})
 	c.HideHelpCommand = true
 
-	commands, err := buildCommands(c, "", bo, obj)
+	model, err := parseCommand(obj, bo)
+	if err != nil {
+		return
+	}
+	err = checkInjected(model, bo.providers(), nil)
+	if err != nil {
+		return
+	}
+	command, err := commandFromModel(c, model, bo, "", bo.Middleware)
 	if err != nil {
 		return
 	}
 
 	// if it's a one-command application, there's no need for a subcommand so
 	// just move the command's contents into the root object, aka the 'App'
-	if len(commands) != 1 {
-		panic("this should never happen")
-	}
-	command := commands[0]
 	c.Usage = command.Usage
 	c.Description = command.Description
+	c.ArgsUsage = command.ArgsUsage
 	c.Before = command.Before
 	c.Action = command.Action
+	c.After = command.After
+	c.OnUsageError = command.OnUsageError
 	c.Flags = command.Flags
 	c.Commands = command.Subcommands
 	c.Metadata["cliveRoot"] = obj
 	if versioned, ok := obj.(WithVersion); ok {
 		c.Version = versioned.Version()
 	}
-	c.UseShortOptionHandling = command.UseShortOptionHandling
-	return
-}
-
-func buildCommands(c *cli.App, parentCommandPath string, bo *BuildOptions, objs ...interface{}) (commands []*cli.Command, err error) {
-	for _, obj := range objs {
-		var command *cli.Command
-		command, err = commandFromObject(c, parentCommandPath, obj, bo)
-		if err != nil {
-			return
-		}
-		commands = append(commands, command)
+	if authored, ok := obj.(WithAuthors); ok {
+		c.Authors = authored.Authors()
 	}
-	return
-}
-
-func buildSubcommands(c *cli.App, parentCommandPath string, subcommandsField reflect.Value, bo *BuildOptions) (commands []*cli.Command, err error) {
-	subcommandsFieldValue := subcommandsField
-	for subcommandsFieldValue.Kind() == reflect.Ptr {
-		subcommandsFieldValue = subcommandsFieldValue.Elem()
+	if copyrighted, ok := obj.(WithCopyright); ok {
+		c.Copyright = copyrighted.Copyright()
 	}
-
-	subcommandsType := subcommandsFieldValue.Type()
-	subcommands := make([]interface{}, 0, subcommandsType.NumField())
-	var cmds []*cli.Command
-	for i := 0; i < subcommandsType.NumField(); i++ {
-		subcommandFieldType := subcommandsType.Field(i)
-		subcommand := subcommandsFieldValue.Field(i)
-		if subcommandFieldType.Type.Kind() == reflect.Struct {
-			cmds, err = buildSubcommands(c, parentCommandPath, subcommand, bo)
-			if err != nil {
-				return
-			}
-			commands = append(commands, cmds...)
-			continue
-		}
-
-		if subcommandFieldType.Type.Kind() != reflect.Pointer {
-			return nil, fmt.Errorf("type of subcommand (%s) for %s is passed by value, not by reference", subcommandFieldType.Type.Name(), parentCommandPath)
-		}
-		if subcommandFieldType.Type.Elem().Kind() != reflect.Struct {
-			return nil, fmt.Errorf("type of subcommand (%v) for %s is a double pointer (Kind: %v), should be a pointer to struct", subcommandFieldType.Type.Name(), parentCommandPath, subcommand.Kind())
-		}
-		if subcommand.IsNil() {
-			subcommand.Set(reflect.New(subcommandFieldType.Type.Elem()))
-		}
-		subcommands = append(subcommands, subcommand.Interface())
+	if compiled, ok := obj.(WithCompiled); ok {
+		c.Compiled = compiled.Compiled()
 	}
-	cmds, err = buildCommands(c, parentCommandPath, bo, subcommands...)
-	if err != nil {
-		return
+	if h, ok := obj.(HasCommandNotFound); ok {
+		c.CommandNotFound = h.CommandNotFound
 	}
-	commands = append(commands, cmds...)
-	return
-}
-
-type commandMetadata struct {
-	TypeInterface
-	Name       string
-	Envs       []string
-	Aliases    []string
-	Usage      string
-	Hidden     bool
-	Default    *string
-	Skipped    bool
-	Positional bool
-	Inline     bool
-	Required   bool
-	Accesses   []int
-
-	UseShortOptions bool
-}
-
-func commandFromObject(c *cli.App, parentCommandPath string, obj interface{}, bo *BuildOptions) (*cli.Command, error) {
-	if sc, ok := obj.(HasSubcommand); ok {
-		return sc.Subcommand(c, parentCommandPath), nil
+	if bo.ExitOnError {
+		c.ExitErrHandler = exitErrHandler(bo)
 	}
-	if obj == nil {
-		return nil, ErrNil
+	if h, ok := obj.(HasExitErrHandler); ok {
+		c.ExitErrHandler = h.ExitErrHandler
 	}
-
-	// recursively dereference
-	objValue := reflect.ValueOf(obj)
-	objIsPointer := false
-	for objValue.Kind() == reflect.Ptr {
-		objValue = objValue.Elem()
-		objIsPointer = true
-	}
-	if !objValue.CanAddr() || !objIsPointer {
-		return nil, &ByValueError{objValue.Type().Name()}
+	if h, ok := obj.(HasInvalidFlagAccessHandler); ok {
+		c.InvalidFlagAccessHandler = h.InvalidFlagAccessHandler
 	}
+	c.UseShortOptionHandling = command.UseShortOptionHandling
 
-	objType := objValue.Type()
-
-	if objType.NumField() == 0 {
-		return nil, &WrongFirstFieldError{NumFields: 0}
-	}
+	rootRecord := &commandRecord{}
+	if record, ok := commandRecords(c)[command]; ok {
+		*rootRecord = *record
+	}
+	rootRecord.spec = nil
+	rootRecord.describe = func() *CommandSpec {
+		rootSpec := *commandSpecFor(c, command)
+		rootSpec.Version = c.Version
+		rootSpec.SetPaths("")
+		return &rootSpec
+	}
+	rootRecord.renderer = bo.options().HelpRenderer
+	rootRecord.app = c
+	c.Metadata[cliveRecordKey] = rootRecord
+	c.CustomAppHelpTemplate = appHelpTemplate
+	return
+}
 
-	// the first field must be an embedded *Command struct
-	command, err := getCommand(objType.Field(0), objValue.Field(0), bo)
-	if err != nil {
-		if wffe, ok := err.(*WrongFirstFieldError); ok { //nolint:errorlint // getCommand returns WrongFirstFieldError explicitly
-			wffe.NumFields = objType.NumField()
+// commandFromModel constructs the urfave/cli command for a parsed command
+// struct. specPath is its CommandSpec.Path, middleware the middleware of its
+// ancestors.
+func commandFromModel(c *cli.App, model *core.CommandModel, bo *BuildOptions, specPath string, middleware []Middleware) (*cli.Command, error) {
+	if model.Foreign {
+		return model.Obj.(HasSubcommand).Subcommand(c, model.ParentPath), nil
+	}
+	if _, ok := model.Obj.(Actionable); !ok {
+		return nil, &ActionableNotImplementedError{model.ObjType.Name()}
+	}
+
+	command := model.Command.(*Command)
+	if command.Command == nil {
+		command.Command = &cli.Command{}
+	}
+	command.Name = model.Name
+	command.Usage = model.Meta.Usage
+	command.Aliases = model.Meta.Aliases
+	command.Flags = []cli.Flag{}
+	command.UseShortOptionHandling = model.Meta.UseShortOptions
+	command.run, _ = model.Run.(RunFunc)
+	if model.ParentPath != "" {
+		command.parentPath = model.ParentPath
+	}
+	command.currentPath = model.Path
+	commandPath := model.Path
+	if hm, ok := model.Obj.(HasMiddleware); ok {
+		middleware = append(middleware[:len(middleware):len(middleware)], hm.Middleware()...)
+	}
+
+	var envs []string
+	if model.ParentPath == "" {
+		envs = model.EnvVars()
+	}
+	// command structs with generated code construct their flags themselves,
+	// flagMetas only hold what clive checks for them
+	flagMetas := model.Flags
+	var generatedFlags []cli.Flag
+	if model.Generated != nil {
+		generatedFlags = model.Obj.(Generated).CliveFlags(bo)
+		flagMetas = generatedMetas(model.Generated, generatedFlags, bo)
+	}
+
+	// binding runs inside the middleware, which sees its errors
+	before := chain(middleware, func(inv *Invocation) error {
+		ctx := inv.Context
+		if model.ParentPath == "" {
+			if err := core.CheckEnv(bo.options(), envs); err != nil {
+				if bo.StrictEnv {
+					return err
+				}
+				fmt.Fprintln(ctx.App.ErrWriter, "warning:", err)
+			}
 		}
-		return nil, err
-	}
-
-	// name from tags takes precedence
-	if command.Name == "" {
-		command.Name = strings.ToLower(objType.Name())
-	}
-
-	commandPath := "/"
-	if parentCommandPath != "" {
-		commandPath = fmt.Sprintf("%s%s/", commandPath, parentCommandPath)
-		command.parentPath = parentCommandPath
-	}
-	commandPath = fmt.Sprintf("%s%s", commandPath, command.Name)
-	command.currentPath = commandPath
-
-	command.Before = func(ctx *cli.Context) error {
-		obj := ctx.App.Metadata[commandPath]
+		err := core.CheckRequired(flagMetas, func(cmdMeta *core.CommandMetadata) bool {
+			return ctx.IsSet(cmdMeta.Name) || (cmdMeta.Negatable && ctx.IsSet(core.NegatedName(cmdMeta.Name)))
+		})
+		if err != nil {
+			return err
+		}
+		obj := inv.Command
 		act := obj.(Actionable)
-		flags, berr := flagsForActionable(act, ctx, bo)
-		if berr == nil {
-			ctx.App.Metadata[commandPath] = flags
+		var flags Actionable
+		var berr error
+		groups := core.NilGroups(obj, model.Flags)
+		if model.Generated != nil {
+			flags, berr = act, bindGenerated(ctx, obj.(Generated), flagMetas)
 		} else {
-			sherr := cli.ShowSubcommandHelp(ctx)
+			flags, berr = flagsForActionable(act, ctx, bo)
+		}
+		if berr == nil {
+			// urfave/cli counts only the flags given on the command line
+			given := func(name string) bool { return ctx.Count(name) != 0 }
+			berr = core.CheckNegated(model.Flags, given)
+			for i := 0; berr == nil && i < len(model.Globals); i++ {
+				berr = core.CheckNegated([]core.CommandMetadata{model.Globals[i].CommandMetadata}, given)
+			}
+		}
+		if berr == nil {
+			berr = core.BindNegated(obj, model.Flags, func(name string) (bool, bool) {
+				return ctx.Bool(name), ctx.IsSet(name)
+			})
+		}
+		if berr == nil {
+			berr = core.BindRepeated(obj, model.Flags, func(group []*core.CommandMetadata) []core.Occurrence {
+				given, _ := ctx.Value(group[0].Name).([]core.Occurrence)
+				return given
+			})
+		}
+		if berr == nil {
+			berr = core.CheckVariants(obj, model.Flags, func(cmdMeta *core.CommandMetadata) (bool, bool) {
+				set := ctx.IsSet(cmdMeta.Name)
+				return set, set && core.EnvSet(cmdMeta.Envs)
+			})
+		}
+		if berr == nil {
+			berr = bindGlobals(ctx, model, obj)
+		}
+		if berr != nil {
+			sherr := showSubcommandHelp(ctx)
 			if sherr != nil {
 				berr = multierror.Append(berr, sherr)
 			}
 			return berr
 		}
-		if before, ok := obj.(HasBefore); ok {
-			berr = before.Before(ctx)
+		core.ResetGroups(obj, groups, model.Flags, func(cmdMeta *core.CommandMetadata) bool {
+			return ctx.IsSet(cmdMeta.Name) || (cmdMeta.Negatable && ctx.IsSet(core.NegatedName(cmdMeta.Name)))
+		})
+		ctx.App.Metadata[commandPath] = flags
+
+		// opened fields and injected values are closed by After, which runs
+		// even if Before fails
+		err = core.OpenFields(flags, model.Positionals, model.Flags)
+		if err == nil {
+			err = inject(ctx, model, flags, bo)
+		}
+		if err != nil {
+			return err
+		}
+		if before, ok := flags.(HasBefore); ok {
+			err = before.Before(ctx)
 		}
-		return berr
+		return err
+	})
+	command.Before = func(ctx *cli.Context) error {
+		renderSubcommandHelp(ctx)
+		return before(&Invocation{Context: ctx, Step: StepBefore, Path: specPath, Command: ctx.App.Metadata[commandPath]})
 	}
+	action := chain(middleware, func(inv *Invocation) error {
+		return inv.Command.(Actionable).Action(inv.Context)
+	})
 	command.Command.Action = func(ctx *cli.Context) error {
-		act := ctx.App.Metadata[commandPath].(Actionable)
-		return act.Action(ctx)
+		return action(&Invocation{Context: ctx, Step: StepAction, Path: specPath, Command: ctx.App.Metadata[commandPath]})
 	}
+	after := chain(middleware, func(inv *Invocation) error {
+		if after, ok := inv.Command.(HasAfter); ok {
+			return after.After(inv.Context)
+		}
+		return nil
+	})
 	command.Command.After = func(ctx *cli.Context) (err error) {
 		obj := ctx.App.Metadata[commandPath]
-		if after, ok := obj.(HasAfter); ok {
-			err = after.After(ctx)
+		err = after(&Invocation{Context: ctx, Step: StepAfter, Path: specPath, Command: obj})
+		cerr := core.CloseFields(obj, model.Positionals, model.Flags)
+		if err == nil {
+			err = cerr
+		}
+		if inj, ok := ctx.App.Metadata[cliveInjectorKey].(*injector); ok {
+			if cerr = inj.close(commandPath); err == nil {
+				err = cerr
+			}
 		}
 		return
 	}
+	c.Metadata[commandPath] = model.Obj
 
-	act, ok := objValue.Addr().Interface().(Actionable)
-	if !ok {
-		return nil, &ActionableNotImplementedError{objValue.Type().Name()}
-	}
-	c.Metadata[commandPath] = act
-
-	if desc, ok := objValue.Addr().Interface().(WithDescription); ok {
+	if desc, ok := model.Obj.(WithDescription); ok {
 		command.Description = desc.Description()
 	}
+	command.OnUsageError = onUsageError
+	if h, ok := model.Obj.(HasOnUsageError); ok {
+		command.OnUsageError = h.OnUsageError
+	}
 
-	var positionals []commandMetadata
-	var flags []commandMetadata
+	for _, sub := range model.Subcommands {
+		subcommand, err := commandFromModel(c, sub, bo, strings.TrimSpace(specPath+" "+sub.Name), middleware)
+		if err != nil {
+			return nil, err
+		}
+		command.Subcommands = append(command.Subcommands, subcommand)
+	}
 
-	for i := 1; i < objType.NumField(); i++ {
-		fieldType := objType.Field(i)
-		if fieldType.Name == "Subcommands" {
-			command.Subcommands, err = buildSubcommands(c, commandPath, objValue.Field(i).Addr(), bo)
+	if model.Generated != nil {
+		command.Flags = generatedFlags
+	} else {
+		records := map[string]*repeatedRecord{}
+		for _, flagMeta := range model.Flags {
+			// required flags are checked when binding, urfave/cli's error
+			// isn't typed
+			flagMeta.Required = false
+			newFlag := newCliFlag
+			switch {
+			case flagMeta.Repeated != nil:
+				record, ok := records[flagMeta.Repeated.Group]
+				if !ok {
+					record = &repeatedRecord{}
+					records[flagMeta.Repeated.Group] = record
+				}
+				newFlag = func(cmdMeta core.CommandMetadata) (cli.Flag, error) {
+					return newRepeatedFlag(cmdMeta, record)
+				}
+			case listFlag(&flagMeta):
+				newFlag = newListFlag
+			}
+			flag, err := newFlag(flagMeta)
 			if err != nil {
 				return nil, err
 			}
-			continue
+			command.Flags = append(command.Flags, flag)
 		}
-		if fieldType.Name == "Run" && fieldType.Type == reflect.TypeOf((RunFunc)(nil)) {
-			command.run = objValue.Field(i).Interface().(RunFunc)
+	}
+	// global flags of the ancestors are accepted by every descendant. The
+	// copies only take the command line: the environment and the default are
+	// read by the flag of the declaring command, so that a value given to an
+	// ancestor isn't overridden by the environment variable of a copy.
+	for _, global := range model.InheritedGlobals() {
+		if generatedFlag(generatedFlags, global.Name) {
 			continue
 		}
-		err = parseFieldOrPositional("", nil, fieldType, &positionals, &flags, bo)
-		if err != nil {
-			return nil, err
+		copied := global.CommandMetadata
+		copied.Envs, copied.Default = nil, nil
+		newFlag := newCliFlag
+		if listFlag(&copied) {
+			newFlag = newListFlag
 		}
-	}
-	for _, flagMeta := range flags {
-		var flag cli.Flag
-		flag, err = flagMeta.NewFlag(flagMeta)
+		flag, err := newFlag(copied)
 		if err != nil {
 			return nil, err
 		}
 		command.Flags = append(command.Flags, flag)
+		flagMetas = append(flagMetas[:len(flagMetas):len(flagMetas)], global.CommandMetadata)
 	}
-	command.Args = len(positionals) != 0
-	optionalStarted := false
-	var variadicStarted *string
-	var positionalUsage []string
-	for _, positional := range positionals {
-		if variadicStarted != nil {
-			return nil, &PositionalAfterVariadicError{CurrentName: positional.Name, FirstName: *variadicStarted}
-		}
-		if positional.Hidden {
-			return nil, &HiddenPositionalError{positional.Name}
+	for _, flagMeta := range flagMetas {
+		if flagMeta.Negatable {
+			command.Flags = append(command.Flags, &cli.BoolFlag{
+				Name:   core.NegatedName(flagMeta.Name),
+				Usage:  fmt.Sprintf("set --%s to false", flagMeta.Name),
+				Hidden: flagMeta.Hidden,
+			})
 		}
-		usage := strcase.ToScreamingSnake(positional.Name)
-		if positional.IsVariadic() {
-			variadicStarted = new(string)
-			*variadicStarted = positional.Name
-			usage = fmt.Sprintf("%s [%[1]s]", usage)
-		}
-		optional := !positional.Required
-		if optional {
-			optionalStarted = true
-			usage = fmt.Sprintf("[%s]", usage)
-		} else if optionalStarted {
-			return nil, fmt.Errorf("positional argument %s cannot be non-optional after an optional argument", positional.Name)
-		}
-		positionalUsage = append(positionalUsage, usage)
 	}
-	command.ArgsUsage = strings.Join(positionalUsage, " ")
+	command.Args = len(model.Positionals) != 0
+	command.ArgsUsage = model.ArgsUsage
 	command.HideHelpCommand = true
 
-	return command.Command, nil
-}
-
-func getCommand(fieldType reflect.StructField, fieldValue reflect.Value, bo *BuildOptions) (c *Command, err error) {
-	if fieldType.Name != "Command" || fieldType.Type != reflect.TypeOf((*Command)(nil)) {
-		return nil, &WrongFirstFieldError{
-			NumFields: 0,
-			FieldName: fieldType.Name,
-			Type:      fieldType.Type.String(),
+	o := bo.options()
+	record := &commandRecord{renderer: o.HelpRenderer, bo: bo}
+	record.describe = func() *CommandSpec {
+		spec := core.NewCommandSpec(describedModel(model, o), o)
+		for _, sub := range command.Subcommands {
+			spec.Subcommands = append(spec.Subcommands, commandSpecFor(c, sub))
 		}
+		return spec
 	}
+	commandRecords(c)[command.Command] = record
 
-	if fieldValue.IsNil() {
-		fieldValue.Set(reflect.ValueOf(&Command{}))
-	}
-
-	cmd, ok := fieldValue.Interface().(*Command)
-	if !ok {
-		return nil, errors.New("failed to cast Command field to a clive.Command object")
-	}
-	if cmd.Command == nil {
-		cmd.Command = &cli.Command{}
-	}
-
-	cmdMeta, err := parseMeta("", nil, fieldType, bo)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read cmdMeta tag on the embedded clive.Command struct pointer: %w", err)
-	}
-	if cmdMeta.Name != "" {
-		cmd.Name = cmdMeta.Name
-	}
-	cmd.Usage = cmdMeta.Usage
-	cmd.Aliases = cmdMeta.Aliases
-	cmd.Flags = []cli.Flag{}
-	cmd.UseShortOptionHandling = cmdMeta.UseShortOptions
-
-	return cmd, nil
-}
-
-func parseFieldOrPositional(prefix string, accesses []int, fieldType reflect.StructField, positionals, flags *[]commandMetadata, bo *BuildOptions) (err error) {
-	var cmdMeta commandMetadata
-	cmdMeta, err = parseMeta(prefix, accesses, fieldType, bo)
-	if err != nil {
-		return
-	}
-	if cmdMeta.Skipped {
-		return
-	}
-	if cmdMeta.Inline {
-		structType := fieldType.Type
-		if structType.Kind() != reflect.Struct {
-			err = fmt.Errorf("inline field %s is not a struct", fieldType.Name)
-			return
-		}
-		for i := 0; i < structType.NumField(); i++ {
-			fT := structType.Field(i)
-
-			fAccesses := make([]int, len(cmdMeta.Accesses)+1)
-			copy(fAccesses, cmdMeta.Accesses)
-			fAccesses[len(fAccesses)-1] = i
-
-			err = parseFieldOrPositional(cmdMeta.Name, fAccesses, fT, positionals, flags, bo)
-			if err != nil {
-				err = fmt.Errorf("parsing inline field %s: %w", fieldType.Name, err)
-				return
-			}
-		}
-		return
-	}
-
-	if cmdMeta.Positional {
-		*positionals = append(*positionals, cmdMeta)
-	} else {
-		// automatically turn fields that begin with Flag into cli.Flag objects
-		*flags = append(*flags, cmdMeta)
-	}
-	return
-}
-
-func parseMeta(prefix string, accesses []int, fieldType reflect.StructField, bo *BuildOptions) (cmdMeta commandMetadata, err error) {
-	s := fieldType.Tag.Get("cli")
-
-	cmdMeta.Skipped = false
-	if s == "-" {
-		cmdMeta.Skipped = true
-		return cmdMeta, err
-	}
-	cmdMeta.Accesses = accesses
-	cmdMeta.Required = false
-	cmdMeta.Positional = false
-	cmdMeta.UseShortOptions = false
-	// this code allows strings to be placed inside single-quotes in order to
-	// escape comma characters.
-	quotes := false
-	sections := strings.FieldsFunc(s, func(r rune) bool {
-		if r == '\'' && !quotes {
-			quotes = true
-		} else if r == '\'' && quotes {
-			quotes = false
-		}
-		if r == ',' && !quotes {
-			return true
-		}
-		return false
-	})
-	requiredSetFromTags := false
-	for _, section := range sections {
-		if section == "positional" {
-			cmdMeta.Positional = true
-			continue
-		}
-		if section == "inline" {
-			cmdMeta.Inline = true
-			continue
-		}
-		if section == "required" {
-			cmdMeta.Required = true
-			requiredSetFromTags = true
-			continue
-		}
-		if section == "shortOpt" {
-			cmdMeta.UseShortOptions = true
-			continue
-		}
-		keyValue := strings.SplitN(section, ":", 2)
-		if len(keyValue) == 2 {
-			keyValue[1] = strings.Trim(keyValue[1], "'")
-			switch keyValue[0] {
-			case "name":
-				cmdMeta.Name = keyValue[1]
-			case "usage":
-				cmdMeta.Usage = keyValue[1] // trim single-quotes
-			case "required":
-				cmdMeta.Required, err = strconv.ParseBool(keyValue[1])
-				if err != nil {
-					err = fmt.Errorf("failed to parse 'required' as a bool %s", err.Error())
-				}
-				requiredSetFromTags = true
-			case "env":
-				cmdMeta.Envs = strings.Split(keyValue[1], ",")
-			case "alias":
-				cmdMeta.Aliases = strings.Split(keyValue[1], ",")
-			case "hidden":
-				cmdMeta.Hidden, err = strconv.ParseBool(keyValue[1])
-				if err != nil {
-					err = fmt.Errorf("failed to parse 'hidden' as a bool %s", err.Error())
-				}
-			case "default":
-				cmdMeta.Default = new(string)
-				*cmdMeta.Default = keyValue[1]
-			case "entrypoint":
-			case "shortOpt":
-				cmdMeta.UseShortOptions, err = strconv.ParseBool(keyValue[1])
-				if err != nil {
-					err = fmt.Errorf("failed to parse 'shortOpt' as a bool %s", err.Error())
-				}
-			default:
-				err = fmt.Errorf("unknown command tag: '%s:%s'", keyValue[0], keyValue[1])
-			}
-		} else {
-			err = fmt.Errorf("malformed tag: '%s'", section)
-		}
-		if err != nil {
-			return
-		}
-	}
-	if cmdMeta.Positional {
-		if !requiredSetFromTags {
-			cmdMeta.Required = cmdMeta.Default == nil
-		}
-	}
-	if fieldType.Type != reflect.TypeOf((*Command)(nil)) {
-		if !cmdMeta.Inline {
-			cmdMeta.TypeInterface, err = flagType(fieldType)
-			if err != nil {
-				err = fmt.Errorf("cant find type for %s field: %s", cmdMeta.Name, err.Error())
-				return cmdMeta, err
-			}
-		}
-		if cmdMeta.Name == "" {
-			cmdMeta.Name = fieldType.Name
-		}
-		ft := fieldType.Type
-		for ft.Kind() == reflect.Pointer {
-			ft = ft.Elem()
-		}
-		ft = reflect.PointerTo(ft)
-		if ft.Implements(Reflected[HasVariants]()) {
-			vars := reflect.Zero(ft).Interface().(HasVariants).Variants()
-			var usageArr []string
-			if len(cmdMeta.Usage) > 0 {
-				usageArr = append(usageArr, cmdMeta.Usage)
-			}
-			usageArr = append(usageArr, fmt.Sprintf("possible values: [%s]", strings.Join(vars, ", ")))
-			cmdMeta.Usage = strings.Join(usageArr, ", ")
-		}
-	}
-	if prefix != "" {
-		cmdMeta.Name = prefix + "-" + cmdMeta.Name
-	}
-	if cmdMeta.Name != "" {
-		cmdMeta.Name = strcase.ToKebab(cmdMeta.Name)
-	}
-	if len(cmdMeta.Envs) == 0 {
-		cmdMeta.Envs = []string{
-			strcase.ToScreamingSnake(cmdMeta.Name),
-		}
-		if bo.EnvPrefix != "" {
-			for i, v := range cmdMeta.Envs {
-				cmdMeta.Envs[i] = bo.EnvPrefix + "_" + v
-			}
-		}
-	}
-	return cmdMeta, err
+	return command.Command, nil
 }
@@ -0,0 +1,278 @@
+// Package clivecobra builds spf13/cobra commands from clive command structs,
+// so that they can be embedded into existing cobra trees. Flags keep their
+// clive names, aliases, environment variables and defaults, positional
+// arguments and values are bound by clive the same way clive.Build does.
+package clivecobra
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ASMfreaK/clive2/clivecore"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+type (
+	HasBefore interface {
+		Before(cmd *cobra.Command, args []string) error
+	}
+	Actionable interface {
+		Action(cmd *cobra.Command, args []string) error
+	}
+	HasAfter interface {
+		After(cmd *cobra.Command, args []string) error
+	}
+)
+
+// binding is what clivecobra knows about a command it has built.
+type binding struct {
+	cmd  *cobra.Command
+	spec *clivecore.CommandSpec
+	raw  *clivecore.RawValues
+}
+
+// lineageKey keys the bindings of the commands built by clive from the root
+// down to the one being run in its context.
+type lineageKey struct{}
+
+// Build constructs a cobra Command from an instance of a decorated struct.
+// Like clive.Build it panics on malformed structs.
+func Build(obj interface{}) *cobra.Command {
+	return BuildCustom(obj, clivecore.Options{})
+}
+
+func BuildCustom(obj interface{}, o clivecore.Options) *cobra.Command {
+	spec, err := clivecore.Describe(obj, o)
+	if err == nil {
+		err = spec.CheckPortable()
+	}
+	if err != nil {
+		panic(err)
+	}
+	return command(spec, nil)
+}
+
+// Current returns the command struct cmd was built from, with values bound.
+// It is nil unless cmd is being run or was run.
+func Current(cmd *cobra.Command) interface{} {
+	return find(cmd, cmd)
+}
+
+// Root returns the command struct of the topmost command built by clive
+// above cmd, which is being run or was run.
+func Root(cmd *cobra.Command) interface{} {
+	if commands := lineage(cmd); len(commands) != 0 {
+		return commands[0].spec.Object()
+	}
+	return nil
+}
+
+// Parent returns the command struct of the command cmd is a subcommand of,
+// or nil if it wasn't built by clive.
+func Parent(cmd *cobra.Command) interface{} {
+	if cmd.Parent() == nil {
+		return nil
+	}
+	return find(cmd, cmd.Parent())
+}
+
+// lineage returns the bindings of the commands built by clive from the root
+// down to cmd, which preRun keeps in the context of cmd.
+func lineage(cmd *cobra.Command) []*binding {
+	if cmd.Context() == nil {
+		return nil
+	}
+	commands, _ := cmd.Context().Value(lineageKey{}).([]*binding)
+	return commands
+}
+
+// find returns the command struct of target from the lineage of cmd.
+func find(cmd, target *cobra.Command) interface{} {
+	for _, b := range lineage(cmd) {
+		if b.cmd == target {
+			return b.spec.Object()
+		}
+	}
+	return nil
+}
+
+// command builds the cobra command of spec, parents are the bindings of the
+// commands above it.
+func command(spec *clivecore.CommandSpec, parents []*binding) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     strings.TrimSpace(spec.Name + " " + spec.ArgsUsage()),
+		Aliases: spec.Aliases,
+		Short:   spec.Usage,
+		Long:    strings.TrimSpace(spec.Description),
+		Version: spec.Version,
+		Args:    cobra.ArbitraryArgs,
+		// flags of a command are given before the names of its
+		// subcommands, the same way urfave/cli accepts them
+		TraverseChildren: true,
+	}
+	b := &binding{cmd: cmd, spec: spec, raw: clivecore.NewRawValues(spec)}
+	commands := append(parents[:len(parents):len(parents)], b)
+	flags := cmd.Flags()
+	for _, field := range spec.Flags {
+		value := pflagValue{b.raw.Value(field.Name)}
+		usage := clivecore.UsageWithVariants(field.Usage, field.Variants)
+		if len(field.Envs) != 0 {
+			usage = strings.TrimSpace(usage + " [$" + strings.Join(field.Envs, ", $") + "]")
+		}
+		shorthand := ""
+		var aliases []string
+		for _, alias := range field.Aliases {
+			if len(alias) == 1 && shorthand == "" {
+				shorthand = alias
+			} else {
+				aliases = append(aliases, alias)
+			}
+		}
+		names := append([]string{field.Name}, aliases...)
+		for i, name := range names {
+			short := ""
+			if i == 0 {
+				short = shorthand
+			}
+			flag := flags.VarPF(value, name, short, usage)
+			flag.Hidden = field.Hidden || i != 0
+			value.noOptDefVal(flag)
+			if field.TakesFile {
+				_ = cobra.MarkFlagFilename(flags, name)
+			}
+			if len(field.Variants) != 0 {
+				_ = cmd.RegisterFlagCompletionFunc(name, completeVariants(field))
+			}
+		}
+		if field.Negatable {
+			negated := pflagValue{b.raw.Value(field.NegatedName())}
+			flag := flags.VarPF(negated, field.NegatedName(), "", "set --"+field.Name+" to false")
+			flag.Hidden = field.Hidden
+			negated.noOptDefVal(flag)
+		}
+	}
+	for _, sub := range spec.Subcommands {
+		cmd.AddCommand(command(sub, commands))
+	}
+
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		return preRun(cmd, commands, args)
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		var err error
+		if act, ok := spec.Object().(Actionable); ok {
+			err = act.Action(cmd, args)
+		} else if err = cmd.Help(); err == nil {
+			err = clivecore.ErrCommandNotImplemented()
+		}
+		if err != nil {
+			// cobra skips PostRunE when RunE fails
+			closeAll(commands)
+		}
+		return err
+	}
+	cmd.PostRunE = func(cmd *cobra.Command, args []string) error {
+		return postRun(cmd, commands, args)
+	}
+	return cmd
+}
+
+// pflagValue is a RawValue without IsBoolFlag: pflag takes values having it
+// for bools, whose default is only left out of the help when it is "false".
+type pflagValue struct {
+	raw *clivecore.RawValue
+}
+
+func (v pflagValue) Set(s string) error { return v.raw.Set(s) }
+func (v pflagValue) String() string     { return v.raw.String() }
+func (v pflagValue) Type() string       { return v.raw.Type() }
+
+// noOptDefVal lets bools and counters be given without a value.
+func (v pflagValue) noOptDefVal(flag *pflag.Flag) {
+	switch v.raw.Type() {
+	case "bool":
+		flag.NoOptDefVal = "true"
+	case "count":
+		flag.NoOptDefVal = "+1"
+	}
+}
+
+// completeVariants completes the values of a flag with its variants and
+// their descriptions.
+func completeVariants(field *clivecore.FieldSpec) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		completions := make([]string, len(field.Variants))
+		for i, variant := range field.Variants {
+			completions[i] = variant
+			if description, ok := field.VariantDescriptions[variant]; ok {
+				completions[i] += "\t" + description
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// closeAll closes what preRun opened for commands, see clivecore.Opener.
+func closeAll(commands []*binding) (err error) {
+	for _, b := range commands {
+		if cerr := b.spec.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return
+}
+
+// preRun binds every command from the root down to the one being run, cobra
+// only runs hooks of the latter. It keeps their bindings in the context of
+// cmd for Current, Parent and Root.
+func preRun(cmd *cobra.Command, commands []*binding, args []string) (err error) {
+	if len(lineage(cmd)) == 0 {
+		cmd.SetContext(context.WithValue(cmd.Context(), lineageKey{}, commands))
+	}
+	for _, b := range commands {
+		defer b.raw.Reset()
+	}
+	defer func() {
+		if err != nil {
+			closeAll(commands)
+		}
+	}()
+	for _, b := range commands {
+		var positionals []string
+		if b.cmd == cmd {
+			positionals = args
+		}
+		err = b.spec.Bind(b.raw, positionals)
+		if err == nil {
+			err = b.spec.Open()
+		}
+		if err != nil {
+			return err
+		}
+		if before, ok := b.spec.Object().(HasBefore); ok {
+			err = before.Before(cmd, args)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func postRun(cmd *cobra.Command, commands []*binding, args []string) (err error) {
+	defer func() {
+		if cerr := closeAll(commands); err == nil {
+			err = cerr
+		}
+	}()
+	for i := len(commands) - 1; i >= 0; i-- {
+		if after, ok := commands[i].spec.Object().(HasAfter); ok {
+			err = after.After(cmd, args)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
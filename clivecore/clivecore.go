@@ -0,0 +1,106 @@
+// Package clivecore is the part of clive that doesn't depend on urfave/cli.
+// It describes command structs, binds their values and renders their help,
+// builders for other command line libraries (see the cliveflag and
+// clivecobra packages) are written on top of it. Command structs built with
+// them embed *clivecore.Command instead of *clive.Command, so that programs
+// using them don't link urfave/cli.
+//
+// The types here are the same as the ones of the clive package: a
+// clivecore.Counter is a clive.Counter.
+package clivecore
+
+import (
+	"cmp"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+)
+
+type (
+	// Command is embedded first into command structs, like clive.Command.
+	Command = core.Command
+	// Options control how command structs are described.
+	Options = core.Options
+
+	CommandSpec      = core.CommandSpec
+	FieldSpec        = core.FieldSpec
+	HelpRenderer     = core.HelpRenderer
+	TextHelpRenderer = core.TextHelpRenderer
+	JSONHelpRenderer = core.JSONHelpRenderer
+
+	FlagValues        = core.FlagValues
+	OrderedFlagValues = core.OrderedFlagValues
+	RawValue          = core.RawValue
+	RawValues         = core.RawValues
+
+	WithVersion                = core.WithVersion
+	WithDescription            = core.WithDescription
+	HasVariants                = core.HasVariants
+	HasVariantAliases          = core.HasVariantAliases
+	HasCaseInsensitiveVariants = core.HasCaseInsensitiveVariants
+	HasVariantDescriptions     = core.HasVariantDescriptions
+	Opener                     = core.Opener
+
+	Counter      = core.Counter
+	ByteSize     = core.ByteSize
+	Duration     = core.Duration
+	Percent      = core.Percent
+	Path         = core.Path
+	ExistingFile = core.ExistingFile
+	ExistingDir  = core.ExistingDir
+	InputFile    = core.InputFile
+	OutputFile   = core.OutputFile
+
+	ByValueError                 = core.ByValueError
+	WrongFirstFieldError         = core.WrongFirstFieldError
+	HiddenPositionalError        = core.HiddenPositionalError
+	PositionalAfterVariadicError = core.PositionalAfterVariadicError
+	RequiredFlagsError           = core.RequiredFlagsError
+	NegatedFlagError             = core.NegatedFlagError
+	TooFewArgumentsError         = core.TooFewArgumentsError
+	TooManyArgumentsError        = core.TooManyArgumentsError
+	FieldBindError               = core.FieldBindError
+	InvalidVariantError          = core.InvalidVariantError
+	UnselectedVariantError       = core.UnselectedVariantError
+	UnknownEnvError              = core.UnknownEnvError
+	UnsupportedError             = core.UnsupportedError
+)
+
+var ErrNil = core.ErrNil
+
+// Describe parses obj and returns the tree of commands, flags and positional
+// arguments it found, see clive.Describe.
+func Describe(obj interface{}, o Options) (*CommandSpec, error) {
+	return core.Describe(obj, o)
+}
+
+// NewRawValues returns RawValues for the flags of spec.
+func NewRawValues(spec *CommandSpec) *RawValues {
+	return core.NewRawValues(spec)
+}
+
+// UsageWithVariants appends the list of possible values to a flag usage text.
+func UsageWithVariants(usage string, variants []string) string {
+	return core.UsageWithVariants(usage, variants)
+}
+
+// ErrCommandNotImplemented is returned by commands that have neither an
+// action nor a subcommand to run, after printing their help.
+func ErrCommandNotImplemented() error {
+	return core.ErrCommandNotImplemented()
+}
+
+// RegisterVariant is clive.RegisterVariant.
+func RegisterVariant[I any, K ~string](name K, impl I) {
+	core.RegisterVariant[I](name, impl)
+}
+
+// RegisterEnum is clive.RegisterEnum. Fields have to be of type T, Enum[T]
+// lives in the clive package.
+func RegisterEnum[T cmp.Ordered](names map[T]string) {
+	core.RegisterEnum(names)
+}
+
+// RegisterEnumDescriptions is clive.RegisterEnumDescriptions.
+func RegisterEnumDescriptions[T cmp.Ordered](descriptions map[T]string) {
+	core.RegisterEnumDescriptions(descriptions)
+}
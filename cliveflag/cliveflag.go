@@ -0,0 +1,175 @@
+// Package cliveflag builds commands on top of the standard library flag
+// package from clive command structs, for small tools that don't want
+// another command line library. Flags keep their clive names, aliases,
+// environment variables and defaults, positional arguments and values are
+// bound by clive the same way clivecore.Build does. Command structs embed
+// *clivecore.Command, cliveflag doesn't link urfave/cli.
+package cliveflag
+
+import (
+	"context"
+	"flag"
+	"strings"
+
+	"github.com/ASMfreaK/clive2/clivecore"
+)
+
+type (
+	HasBefore interface {
+		Before(ctx context.Context) error
+	}
+	Actionable interface {
+		Action(ctx context.Context) error
+	}
+	HasAfter interface {
+		After(ctx context.Context) error
+	}
+)
+
+// Command is a command struct built for the flag package. Subcommands are
+// selected by the first argument left after parsing FlagSet.
+type Command struct {
+	FlagSet     *flag.FlagSet
+	Subcommands []*Command
+
+	spec     *clivecore.CommandSpec
+	raw      *clivecore.RawValues
+	renderer clivecore.HelpRenderer
+}
+
+// Build constructs a Command from an instance of a decorated struct. Like
+// clivecore.Build it panics on malformed structs.
+func Build(obj interface{}) *Command {
+	return BuildCustom(obj, clivecore.Options{})
+}
+
+func BuildCustom(obj interface{}, o clivecore.Options) *Command {
+	spec, err := clivecore.Describe(obj, o)
+	if err == nil {
+		err = spec.CheckPortable()
+	}
+	if err != nil {
+		panic(err)
+	}
+	return command(spec, spec.Name, o.Renderer())
+}
+
+func command(spec *clivecore.CommandSpec, helpName string, renderer clivecore.HelpRenderer) *Command {
+	spec.HelpName = helpName
+	c := &Command{
+		FlagSet:  flag.NewFlagSet(helpName, flag.ContinueOnError),
+		spec:     spec,
+		raw:      clivecore.NewRawValues(spec),
+		renderer: renderer,
+	}
+	for _, field := range spec.Flags {
+		value := c.raw.Value(field.Name)
+		usage := clivecore.UsageWithVariants(field.Usage, field.Variants)
+		for _, name := range append([]string{field.Name}, field.Aliases...) {
+			c.FlagSet.Var(value, name, usage)
+		}
+		if field.Negatable {
+			c.FlagSet.Var(c.raw.Value(field.NegatedName()), field.NegatedName(), "set -"+field.Name+" to false")
+		}
+	}
+	c.FlagSet.Usage = func() {
+		_ = renderer.RenderHelp(c.FlagSet.Output(), spec)
+	}
+	for _, sub := range spec.Subcommands {
+		c.Subcommands = append(c.Subcommands, command(sub, helpName+" "+sub.Name, renderer))
+	}
+	return c
+}
+
+// Spec returns the description of the command.
+func (c *Command) Spec() *clivecore.CommandSpec {
+	return c.spec
+}
+
+// Current returns the command struct c was built from.
+func (c *Command) Current() interface{} {
+	return c.spec.Object()
+}
+
+func (c *Command) subcommand(name string) *Command {
+	for _, sub := range c.Subcommands {
+		if sub.spec.Name == name {
+			return sub
+		}
+		for _, alias := range sub.spec.Aliases {
+			if alias == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+// Run parses args, which don't include the program name, binds the command
+// struct and runs its Action, or the one of the subcommand named by the
+// first argument left after flags. Before and After of every command on the
+// way are run around it.
+func (c *Command) Run(ctx context.Context, args []string) (err error) {
+	defer c.raw.Reset()
+	err = c.FlagSet.Parse(args)
+	if err != nil {
+		return err
+	}
+	args = c.FlagSet.Args()
+	var sub *Command
+	if len(args) != 0 {
+		sub = c.subcommand(args[0])
+	}
+	obj := c.spec.Object()
+
+	positionals := args
+	if sub != nil {
+		positionals = nil
+	}
+	err = c.spec.Bind(c.raw, positionals)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		cerr := c.spec.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+	err = c.spec.Open()
+	if err != nil {
+		return err
+	}
+	if before, ok := obj.(HasBefore); ok {
+		err = before.Before(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	defer func() {
+		if after, ok := obj.(HasAfter); ok {
+			aerr := after.After(ctx)
+			if err == nil {
+				err = aerr
+			}
+		}
+	}()
+
+	switch act, ok := obj.(Actionable); {
+	case sub != nil:
+		return sub.Run(ctx, args[1:])
+	case ok:
+		return act.Action(ctx)
+	default:
+		var b strings.Builder
+		err = c.renderer.RenderHelp(&b, c.spec)
+		if err != nil {
+			return err
+		}
+		_, err = c.FlagSet.Output().Write([]byte(b.String()))
+		if err != nil {
+			return err
+		}
+		return clivecore.ErrCommandNotImplemented()
+	}
+}
@@ -0,0 +1,860 @@
+// Package clivegen generates Go code constructing flags and binding values for
+// clive command structs, so that they don't go through reflection at runtime.
+// It is used by the clive-gen command.
+package clivegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/iancoleman/strcase"
+)
+
+const (
+	clivePath = "github.com/ASMfreaK/clive2"
+	// corePath declares the types clive re-exports as aliases.
+	corePath = clivePath + "/internal/clive"
+	marker   = "clive-gen"
+)
+
+// Generate loads the package in dir and returns the source of a file
+// implementing clive.Generated for each command struct declared in it.
+// Commands using features the generator does not support are left to
+// reflection and listed in a comment.
+func Generate(dir string) ([]byte, error) {
+	pkg, files, err := load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, imports: map[string]bool{}}
+	var names []*types.TypeName
+	for _, name := range pkg.Scope().Names() {
+		tn, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() || !isCommandStruct(tn.Type()) {
+			continue
+		}
+		names = append(names, tn)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Pos() < names[j].Pos() })
+
+	body := &bytes.Buffer{}
+	var skipped []string
+	for _, tn := range names {
+		code, err := g.command(tn)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %s", tn.Name(), err))
+			continue
+		}
+		body.Write(code)
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "// Code generated by %s. DO NOT EDIT.\n\n", marker)
+	if len(skipped) != 0 {
+		fmt.Fprintf(out, "// Commands left to reflection:\n")
+		for _, s := range skipped {
+			fmt.Fprintf(out, "//   - %s\n", s)
+		}
+		out.WriteString("\n")
+	}
+	fmt.Fprintf(out, "package %s\n\n", files[0].Name.Name)
+	if body.Len() != 0 {
+		g.imports[clivePath] = true
+		g.imports["github.com/urfave/cli/v2"] = true
+		var std, other []string
+		for path := range g.imports {
+			if strings.Contains(strings.Split(path, "/")[0], ".") {
+				other = append(other, path)
+			} else {
+				std = append(std, path)
+			}
+		}
+		sort.Strings(std)
+		sort.Strings(other)
+		out.WriteString("import (\n")
+		for _, path := range std {
+			fmt.Fprintf(out, "\t%q\n", path)
+		}
+		out.WriteString("\n")
+		for _, path := range other {
+			if path == clivePath {
+				fmt.Fprintf(out, "\tclive %q\n", path)
+			} else {
+				fmt.Fprintf(out, "\t%q\n", path)
+			}
+		}
+		out.WriteString(")\n")
+		out.Write(body.Bytes())
+	}
+	return format.Source(out.Bytes())
+}
+
+// IsGenerated reports whether f was written by Generate.
+func IsGenerated(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() > f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "// Code generated by "+marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func load(dir string) (*types.Package, []*ast.File, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(bp.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		// previously generated code may be out of date, it must not take
+		// part in type checking
+		if !IsGenerated(f) {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	var firstErr error
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			if firstErr == nil {
+				firstErr = err
+			}
+		},
+	}
+	pkg, _ := conf.Check(bp.Name, fset, files, nil)
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+	return pkg, files, nil
+}
+
+func isCliveType(t types.Type, name string) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Name() != name {
+		return false
+	}
+	path := named.Obj().Pkg().Path()
+	return path == clivePath || path == corePath
+}
+
+func isCommandStruct(t types.Type) bool {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || st.NumFields() == 0 {
+		return false
+	}
+	first := st.Field(0)
+	ptr, ok := first.Type().(*types.Pointer)
+	return ok && first.Embedded() && first.Name() == "Command" && isCliveType(ptr.Elem(), "Command")
+}
+
+func hasMethod(t types.Type, name string) bool {
+	return types.NewMethodSet(types.NewPointer(t)).Lookup(nil, name) != nil
+}
+
+type generator struct {
+	pkg     *types.Package
+	imports map[string]bool
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		if p.Path() == corePath {
+			g.imports[clivePath] = true
+			return "clive"
+		}
+		g.imports[p.Path()] = true
+		return p.Name()
+	})
+}
+
+// reflectString formats t the way reflect.Type.String does, which is what
+// clive uses in error messages.
+func reflectString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string { return p.Name() })
+}
+
+// kind describes how values of a field type are handled by clive.
+type kind struct {
+	key      string // std type name, "text" or "[]text"
+	flag     string // cli flag type
+	getter   string // cli.Context method
+	goType   string // the type of the value, with pointers removed
+	elemType string // the element type of slices of text unmarshalers
+	variadic bool
+	variants string // expression listing variants, if any
+}
+
+// supported holds the types clive handles out of the box, keyed by their
+// go/types representation.
+var supported = map[string]kind{
+	"int":             {flag: "IntFlag", getter: "Int"},
+	"int64":           {flag: "Int64Flag", getter: "Int64"},
+	"uint":            {flag: "UintFlag", getter: "Uint"},
+	"uint64":          {flag: "Uint64Flag", getter: "Uint64"},
+	"float32":         {flag: "Float64Flag", getter: "Float64"},
+	"float64":         {flag: "Float64Flag", getter: "Float64"},
+	"string":          {flag: "StringFlag", getter: "String"},
+	"time.Duration":   {flag: "DurationFlag", getter: "Duration"},
+	"bool":            {flag: "BoolFlag", getter: "Bool"},
+	"[]int":           {flag: "IntSliceFlag", getter: "IntSlice", variadic: true},
+	"[]int64":         {flag: "Int64SliceFlag", getter: "Int64Slice", variadic: true},
+	"[]uint":          {flag: "UintSliceFlag", getter: "UintSlice", variadic: true},
+	"[]uint64":        {flag: "Uint64SliceFlag", getter: "Uint64Slice", variadic: true},
+	"[]float32":       {flag: "Float64SliceFlag", getter: "Float64Slice", variadic: true},
+	"[]float64":       {flag: "Float64SliceFlag", getter: "Float64Slice", variadic: true},
+	"[]string":        {flag: "StringSliceFlag", getter: "StringSlice", variadic: true},
+	"[]time.Duration": {flag: "StringSliceFlag", getter: "StringSlice", variadic: true},
+	"[]bool":          {flag: "StringSliceFlag", getter: "StringSlice", variadic: true},
+	"Counter":         {flag: "BoolFlag", getter: "Count"},
+	"text":            {flag: "StringFlag", getter: "String"},
+	"[]text":          {flag: "StringSliceFlag", getter: "StringSlice", variadic: true},
+}
+
+func (g *generator) classify(t types.Type) (k kind, pointer bool, err error) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		pointer = true
+		t = ptr.Elem()
+		if _, ok := t.(*types.Pointer); ok {
+			return k, pointer, fmt.Errorf("multiple pointers in %s are not supported", t)
+		}
+	}
+	key := types.TypeString(t, func(p *types.Package) string { return p.Name() })
+	if isCliveType(t, "Counter") {
+		key = "Counter"
+	}
+	k, ok := supported[key]
+	switch {
+	case ok && key != "text" && key != "[]text":
+	case g.isText(t):
+		key = "text"
+		k = supported[key]
+		if hasMethod(t, "Variants") {
+			k.variants = fmt.Sprintf("(*%s)(nil).Variants()", g.typeString(t))
+		}
+	case isSlice(t) && g.isText(t.Underlying().(*types.Slice).Elem()):
+		key = "[]text"
+		k = supported[key]
+		elem := t.Underlying().(*types.Slice).Elem()
+		k.elemType = g.typeString(elem)
+		if hasMethod(elem, "Variants") {
+			k.variants = fmt.Sprintf("(*%s)(nil).Variants()", k.elemType)
+		}
+	default:
+		return k, pointer, fmt.Errorf("type %s is not supported", t)
+	}
+	k.key = key
+	k.goType = g.typeString(t)
+	return k, pointer, nil
+}
+
+func isSlice(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Slice)
+	return ok
+}
+
+// isText reports whether t is a text unmarshaler declared in the generated
+// package, values of other types can't be named without knowing how they are
+// imported.
+func (g *generator) isText(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() == g.pkg && hasMethod(t, "UnmarshalText")
+}
+
+// field is a flag or positional argument found in a command struct.
+type field struct {
+	clive.Tag
+	kind    kind
+	pointer bool
+	expr    string // Go expression of the field, starting at obj
+	// topName and topType describe the field of the command struct holding
+	// this one, they appear in error messages.
+	topName string
+	topType string
+	envs    []string
+	envVar  string
+	// hash is the clive.FieldHash of the field
+	hash string
+}
+
+// known lists the Tag fields the generator understands. Commands with tags
+// setting anything else are left to reflection.
+var known = map[string]bool{
+	"Name": true, "Usage": true, "Envs": true, "Aliases": true, "Hidden": true, "Default": true,
+	"Skipped": true, "Positional": true, "Inline": true, "Required": true, "RequiredSet": true,
+	"UseShortOptions": true,
+}
+
+func checkTag(tag clive.Tag) error {
+	v := reflect.ValueOf(tag)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if !known[name] && !v.Field(i).IsZero() {
+			return fmt.Errorf("tag option %s is not supported", name)
+		}
+	}
+	return nil
+}
+
+// collect adds the flags and positional arguments of st to fields. typePath
+// and tagPath hold the types and tags of the fields of inline groups leading
+// to st, for their clive.FieldHash.
+func (g *generator) collect(st *types.Struct, prefix, expr, topName, topType string, typePath, tagPath []string, fields *[]*field) error {
+	start := 0
+	if prefix == "" && expr == "obj" {
+		start = 1
+	}
+	for i := start; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if expr == "obj" && (v.Name() == "Subcommands" || (v.Name() == "Run" && isCliveType(v.Type(), "RunFunc"))) {
+			continue
+		}
+		rawTag := reflect.StructTag(st.Tag(i)).Get("cli")
+		tag, err := clive.ParseTag(rawTag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", v.Name(), err)
+		}
+		if tag.Skipped {
+			continue
+		}
+		if err = checkTag(tag); err != nil {
+			return fmt.Errorf("field %s: %w", v.Name(), err)
+		}
+		name := tag.Name
+		if name == "" {
+			name = v.Name()
+		}
+		name = strcase.ToKebab(name)
+		if prefix != "" {
+			name = prefix + "-" + name
+		}
+
+		fTopName, fTopType := topName, topType
+		if expr == "obj" {
+			fTopName, fTopType = v.Name(), reflectString(v.Type())
+		}
+		fExpr := expr + "." + v.Name()
+		fTypePath := append(typePath[:len(typePath):len(typePath)], reflectString(v.Type()))
+		fTagPath := append(tagPath[:len(tagPath):len(tagPath)], rawTag)
+
+		if tag.Inline {
+			inline, ok := v.Type().(*types.Named)
+			if !ok {
+				return fmt.Errorf("inline field %s must have a named struct type", v.Name())
+			}
+			inlineStruct, ok := inline.Underlying().(*types.Struct)
+			if !ok {
+				return fmt.Errorf("inline field %s is not a struct", v.Name())
+			}
+			err = g.collect(inlineStruct, name, fExpr, fTopName, fTopType, fTypePath, fTagPath, fields)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		f := &field{Tag: tag, expr: fExpr, topName: fTopName, topType: fTopType}
+		f.Name = name
+		f.hash = clive.FieldHash(name, fTypePath, fTagPath)
+		f.kind, f.pointer, err = g.classify(v.Type())
+		if err != nil {
+			return fmt.Errorf("field %s: %w", v.Name(), err)
+		}
+		if f.Positional && !f.RequiredSet {
+			f.Required = f.Default == nil
+		}
+		if len(f.Envs) != 0 {
+			f.envs = f.Envs
+		} else {
+			f.envVar = strcase.ToScreamingSnake(name)
+		}
+		*fields = append(*fields, f)
+	}
+	return nil
+}
+
+func (g *generator) command(tn *types.TypeName) ([]byte, error) {
+	st := tn.Type().Underlying().(*types.Struct)
+	var fields []*field
+	err := g.collect(st, "", "obj", "", "", nil, nil, &fields)
+	if err != nil {
+		return nil, err
+	}
+	commandCode, err := g.commandFunc(tn.Name(), st, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	flagsCode := &bytes.Buffer{}
+	bindCode := &bytes.Buffer{}
+	hasPositionals := false
+	var flagHashes, positionalHashes []string
+	for _, f := range fields {
+		if f.Positional {
+			hasPositionals = true
+			positionalHashes = append(positionalHashes, f.hash)
+			err = g.bindPositional(bindCode, f)
+		} else {
+			flagHashes = append(flagHashes, f.hash)
+			err = g.flag(flagsCode, f)
+			if err == nil {
+				err = g.bindFlag(bindCode, f)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.expr, err)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	name := tn.Name()
+	out.Write(commandCode)
+	fmt.Fprintf(out, "\nfunc (obj *%s) CliveFlags(bo *clive.BuildOptions) []cli.Flag {\n", name)
+	fmt.Fprintf(out, "return []cli.Flag{\n%s}\n}\n", flagsCode)
+	fmt.Fprintf(out, "\nfunc (obj *%s) CliveBind(ctx *cli.Context) (err error) {\n", name)
+	if hasPositionals {
+		out.WriteString("args := ctx.Args().Slice()\n")
+	}
+	out.Write(bindCode.Bytes())
+	if hasPositionals {
+		out.WriteString("if len(args) > 0 {\n")
+		out.WriteString("return &clive.TooManyArgumentsError{Args: args}\n}\n")
+	}
+	out.WriteString("return nil\n}\n")
+	fmt.Fprintf(out, "\nfunc (obj *%s) CliveHashes() []string {\n", name)
+	fmt.Fprintf(out, "return []string{%s}\n}\n", quoteAll(append(flagHashes, positionalHashes...)))
+	return out.Bytes(), nil
+}
+
+// commandFunc returns the CliveCommand method of the command struct name, it
+// allocates the embedded command and the subcommands the way clive.Build
+// does.
+func (g *generator) commandFunc(name string, st *types.Struct, fields []*field) ([]byte, error) {
+	first := st.Field(0)
+	tag, err := clive.ParseTag(reflect.StructTag(st.Tag(0)).Get("cli"))
+	if err == nil {
+		err = checkTag(tag)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", first.Name(), err)
+	}
+	argsUsage, err := positionalUsage(fields)
+	if err != nil {
+		return nil, err
+	}
+	var required []string
+	for _, f := range fields {
+		if f.Required && !f.Positional {
+			required = append(required, f.Name)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "\nfunc (obj *%s) CliveCommand() clive.GeneratedCommand {\n", name)
+	fmt.Fprintf(out, "if obj.Command == nil {\nobj.Command = new(%s)\n}\n", g.typeString(first.Type().(*types.Pointer).Elem()))
+	var subcommands []string
+	hasRun := false
+	for i := 1; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		switch {
+		case v.Name() == "Subcommands":
+			if subcommands, err = g.subcommands(out, v.Type(), "obj.Subcommands"); err != nil {
+				return nil, err
+			}
+		case v.Name() == "Run" && isCliveType(v.Type(), "RunFunc"):
+			hasRun = true
+		}
+	}
+	out.WriteString("command := clive.GeneratedCommand{\nCommand: obj.Command,\n")
+	if tag.Name != "" {
+		fmt.Fprintf(out, "Name: %q,\n", tag.Name)
+	}
+	if tag.Usage != "" {
+		fmt.Fprintf(out, "Usage: %q,\n", tag.Usage)
+	}
+	if tag.Aliases != nil {
+		fmt.Fprintf(out, "Aliases: []string{%s},\n", quoteAll(tag.Aliases))
+	}
+	if tag.UseShortOptions {
+		out.WriteString("UseShortOptions: true,\n")
+	}
+	if argsUsage != "" {
+		fmt.Fprintf(out, "ArgsUsage: %q,\n", argsUsage)
+	}
+	if subcommands != nil {
+		fmt.Fprintf(out, "Subcommands: []interface{}{%s},\n", strings.Join(subcommands, ", "))
+	}
+	if required != nil {
+		fmt.Fprintf(out, "Required: []string{%s},\n", quoteAll(required))
+	}
+	out.WriteString("}\n")
+	if hasRun {
+		out.WriteString("if obj.Run != nil {\ncommand.Run = obj.Run\n}\n")
+	}
+	out.WriteString("return command\n}\n")
+	return out.Bytes(), nil
+}
+
+// subcommands emits code allocating the subcommands held by the struct
+// expr of type t and returns their expressions. Groups of subcommands in
+// nested structs are flattened.
+func (g *generator) subcommands(out *bytes.Buffer, t types.Type, expr string) ([]string, error) {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("subcommands %s are not a struct", expr)
+	}
+	var subcommands []string
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		subExpr := expr + "." + v.Name()
+		if _, ok := v.Type().Underlying().(*types.Struct); ok {
+			group, err := g.subcommands(out, v.Type(), subExpr)
+			if err != nil {
+				return nil, err
+			}
+			subcommands = append(subcommands, group...)
+			continue
+		}
+		ptr, ok := v.Type().(*types.Pointer)
+		if !ok {
+			return nil, fmt.Errorf("subcommand %s is not a pointer", subExpr)
+		}
+		if _, ok := ptr.Elem().Underlying().(*types.Struct); !ok {
+			return nil, fmt.Errorf("subcommand %s is not a pointer to struct", subExpr)
+		}
+		fmt.Fprintf(out, "if %s == nil {\n%[1]s = new(%s)\n}\n", subExpr, g.typeString(ptr.Elem()))
+		subcommands = append(subcommands, subExpr)
+	}
+	return subcommands, nil
+}
+
+// positionalUsage returns the usage line of the positional arguments among
+// fields, the way clive.Build writes it. Arguments in an order clive rejects
+// leave the command to reflection, which reports the error.
+func positionalUsage(fields []*field) (string, error) {
+	var usage []string
+	optional, variadic := false, false
+	for _, f := range fields {
+		if !f.Positional {
+			continue
+		}
+		if variadic || f.Hidden || (f.Required && optional) {
+			return "", fmt.Errorf("positional argument %s is out of order", f.Name)
+		}
+		u := strcase.ToScreamingSnake(f.Name)
+		if f.kind.variadic {
+			variadic = true
+			u = fmt.Sprintf("%s [%[1]s]", u)
+		}
+		if !f.Required {
+			optional = true
+			u = "[" + u + "]"
+		}
+		usage = append(usage, u)
+	}
+	return strings.Join(usage, " "), nil
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// defaultValue returns the expression for the Value of a flag with a default.
+func (g *generator) defaultValue(k kind, s string) (string, error) {
+	elemKey := strings.TrimPrefix(k.key, "[]")
+	if k.variadic {
+		items, err := clive.SplitList(s, clive.DefaultSep)
+		if err != nil {
+			return "", err
+		}
+		var values []string
+		for _, item := range items {
+			v, err := g.scalarValue(elemKey, item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, v)
+		}
+		constructor := strings.TrimSuffix(k.flag, "Flag")
+		if elemKey == "float32" {
+			for i, v := range values {
+				values[i] = "float64(" + v + ")"
+			}
+		}
+		return fmt.Sprintf("cli.New%s(%s)", constructor, strings.Join(values, ", ")), nil
+	}
+	v, err := g.scalarValue(elemKey, s)
+	if err == nil && k.key == "float32" {
+		v = "float64(" + v + ")"
+	}
+	return v, err
+}
+
+// scalarValue parses s at generation time and returns it as a Go literal of
+// the type the cli flag holds.
+func (g *generator) scalarValue(key, s string) (string, error) {
+	switch key {
+	case "int", "int64", "Counter":
+		v, err := strconv.ParseInt(s, 0, 64)
+		return strconv.FormatInt(v, 10), err
+	case "uint", "uint64":
+		v, err := strconv.ParseUint(s, 0, 64)
+		return strconv.FormatUint(v, 10), err
+	case "float32":
+		v, err := strconv.ParseFloat(s, 32)
+		return fmt.Sprintf("float32(%s)", strconv.FormatFloat(v, 'g', -1, 32)), err
+	case "float64":
+		v, err := strconv.ParseFloat(s, 64)
+		return strconv.FormatFloat(v, 'g', -1, 64), err
+	case "bool":
+		var v bool
+		err := clive.ParseValue(&v, s)
+		return strconv.FormatBool(v), err
+	case "time.Duration":
+		v, err := time.ParseDuration(s)
+		g.imports["time"] = true
+		return fmt.Sprintf("time.Duration(%d)", v), err
+	default:
+		return strconv.Quote(s), nil
+	}
+}
+
+func (g *generator) flag(out *bytes.Buffer, f *field) error {
+	// bools are wrapped to read yes/no and on/off from the environment
+	if f.kind.key == "bool" {
+		out.WriteString("&clive.BoolFlag{BoolFlag: cli.BoolFlag{\n")
+	} else {
+		fmt.Fprintf(out, "&cli.%s{\n", f.kind.flag)
+	}
+	fmt.Fprintf(out, "Name: %q,\n", f.Name)
+	if f.envs != nil {
+		fmt.Fprintf(out, "EnvVars: []string{%s},\n", quoteAll(f.envs))
+	} else {
+		fmt.Fprintf(out, "EnvVars: []string{bo.EnvVar(%q)},\n", f.envVar)
+	}
+	if f.Aliases != nil {
+		fmt.Fprintf(out, "Aliases: []string{%s},\n", quoteAll(f.Aliases))
+	}
+	if f.Default != nil {
+		k := f.kind
+		switch k.key {
+		case "[]time.Duration", "[]bool":
+			// these are held by string slice flags in their canonical form
+			items, err := clive.SplitList(*f.Default, clive.DefaultSep)
+			if err != nil {
+				return err
+			}
+			var values []string
+			for _, item := range items {
+				var v string
+				var err error
+				if k.key == "[]bool" {
+					var b bool
+					err = clive.ParseValue(&b, item)
+					v = strconv.FormatBool(b)
+				} else {
+					var d time.Duration
+					d, err = time.ParseDuration(item)
+					v = d.String()
+				}
+				if err != nil {
+					return err
+				}
+				values = append(values, v)
+			}
+			fmt.Fprintf(out, "Value: cli.NewStringSlice(%s),\n", quoteAll(values))
+		case "text":
+			fmt.Fprintf(out, "Value: %q,\n", *f.Default)
+		case "[]text":
+			items, err := clive.SplitList(*f.Default, clive.DefaultSep)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Value: cli.NewStringSlice(%s),\n", quoteAll(items))
+		case "Counter":
+			v, err := g.scalarValue(k.key, *f.Default)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Count: func() *int { count := %s; return &count }(),\n", v)
+		default:
+			v, err := g.defaultValue(k, *f.Default)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Value: %s,\n", v)
+		}
+	} else if f.kind.key == "Counter" {
+		out.WriteString("Count: new(int),\n")
+	}
+	if f.Hidden {
+		out.WriteString("Hidden: true,\n")
+	}
+	switch {
+	case f.kind.variants != "":
+		fmt.Fprintf(out, "Usage: clive.UsageWithVariants(%q, %s),\n", f.Usage, f.kind.variants)
+	case f.Usage != "":
+		fmt.Fprintf(out, "Usage: %q,\n", f.Usage)
+	}
+	if f.kind.key == "bool" {
+		out.WriteString("}},\n")
+	} else {
+		out.WriteString("},\n")
+	}
+	return nil
+}
+
+// valueExpr returns the expression of the value of the field, allocating
+// pointers first.
+func valueExpr(out *bytes.Buffer, f *field, goType string) string {
+	if !f.pointer {
+		return f.expr
+	}
+	fmt.Fprintf(out, "if %s == nil {\n%[1]s = new(%s)\n}\n", f.expr, goType)
+	return "(*" + f.expr + ")"
+}
+
+// errorReturn returns the statement returning the FieldBindError of the
+// field topName, source and value are the expressions of where the value
+// came from and of the raw value.
+func errorReturn(f *field, source, value string) string {
+	return fmt.Sprintf("return &clive.FieldBindError{Field: %q, Type: %q, Source: %s, Value: %s, Err: err}\n",
+		f.topName, f.topType, source, value)
+}
+
+// unmarshal returns the expression setting the text unmarshaler v from the
+// string expression s, checking it against the variants of its type.
+func unmarshal(k kind, v, s string) string {
+	if k.variants != "" {
+		return fmt.Sprintf("clive.UnmarshalVariant(&%s, %s)", v, s)
+	}
+	return fmt.Sprintf("%s.UnmarshalText([]byte(%s))", v, s)
+}
+
+func (g *generator) bindFlag(out *bytes.Buffer, f *field) error {
+	k := f.kind
+	fail := errorReturn(f, fmt.Sprintf("clive.FlagSource(ctx, %q)", f.Name), fmt.Sprintf("clive.RawFlagValue(ctx, %q)", f.Name))
+	if f.Default != nil {
+		out.WriteString("{\n")
+	} else {
+		fmt.Fprintf(out, "if ctx.IsSet(%q) {\n", f.Name)
+	}
+	v := valueExpr(out, f, k.goType)
+	get := fmt.Sprintf("ctx.%s(%q)", k.getter, f.Name)
+	switch k.key {
+	case "float32":
+		fmt.Fprintf(out, "%s = float32(%s)\n", v, get)
+	case "Counter":
+		fmt.Fprintf(out, "%s.Value = %s\n", v, get)
+	case "[]float32":
+		fmt.Fprintf(out, "values := %s\n%s = make([]float32, len(values))\n", get, v)
+		fmt.Fprintf(out, "for i, value := range values {\n%s[i] = float32(value)\n}\n", v)
+	case "[]time.Duration", "[]bool":
+		fmt.Fprintf(out, "values := %s\n%s = make(%s, len(values))\n", get, v, k.goType)
+		fmt.Fprintf(out, "for i, value := range values {\nif err = clive.ParseValue(&%s[i], value); err != nil {\n%s}\n}\n", v, fail)
+	case "text":
+		fmt.Fprintf(out, "if err = %s; err != nil {\n%s}\n", unmarshal(k, v, get), fail)
+	case "[]text":
+		g.textSlice(out, v, k, get, fail)
+	default:
+		fmt.Fprintf(out, "%s = %s\n", v, get)
+	}
+	out.WriteString("}\n")
+	return nil
+}
+
+func (g *generator) textSlice(out *bytes.Buffer, v string, k kind, values, fail string) {
+	fmt.Fprintf(out, "values := %s\nconverted := make([]%s, len(values))\n", values, k.elemType)
+	fmt.Fprintf(out, "for i, value := range values {\nif err = %s; err != nil {\n%s}\n}\n", unmarshal(k, "converted[i]", "value"), fail)
+	fmt.Fprintf(out, "%s = converted\n", v)
+}
+
+// setString emits code setting the field from the string expression s, the
+// way TypeInterface.SetValueFromString does.
+func (g *generator) setString(out *bytes.Buffer, f *field, s, fail string) {
+	v := valueExpr(out, f, f.kind.goType)
+	switch f.kind.key {
+	case "text":
+		fmt.Fprintf(out, "if err = %s; err != nil {\n%s}\n", unmarshal(f.kind, v, s), fail)
+	case "[]text":
+		out.WriteString("{\n")
+		fmt.Fprintf(out, "var items []string\nif items, err = clive.SplitList(%s, clive.DefaultSep); err != nil {\n%s}\n", s, fail)
+		g.textSlice(out, v, f.kind, "items", fail)
+		out.WriteString("}\n")
+	default:
+		fmt.Fprintf(out, "if err = clive.ParseValue(&%s, %s); err != nil {\n%s}\n", v, s, fail)
+	}
+}
+
+func (g *generator) bindPositional(out *bytes.Buffer, f *field) error {
+	argName := strcase.ToScreamingSnake(f.Name)
+	source := "positional argument " + argName
+	switch {
+	case f.Required:
+		out.WriteString("if len(args) == 0 {\n")
+		fmt.Fprintf(out, "return &clive.TooFewArgumentsError{Name: %q}\n", argName)
+		out.WriteString("} else {\n")
+	case f.Default != nil:
+		out.WriteString("if len(args) == 0 {\n")
+		fail := errorReturn(f, strconv.Quote("default value of "+source), strconv.Quote(*f.Default))
+		g.setString(out, f, strconv.Quote(*f.Default), fail)
+		out.WriteString("} else {\n")
+	default:
+		out.WriteString("if len(args) > 0 {\n")
+	}
+	fail := errorReturn(f, strconv.Quote(source), "args[0]")
+	if f.kind.variadic {
+		g.imports["strings"] = true
+		fail = errorReturn(f, strconv.Quote(source), `strings.Join(args, " ")`)
+	}
+	if f.kind.variadic {
+		v := valueExpr(out, f, f.kind.goType)
+		if f.kind.key == "[]text" {
+			out.WriteString("{\n")
+			g.textSlice(out, v, f.kind, "args", fail)
+			out.WriteString("}\n")
+		} else {
+			fmt.Fprintf(out, "if err = clive.ParseValues(&%s, args); err != nil {\n%s}\n", v, fail)
+		}
+		out.WriteString("args = []string{}\n")
+	} else {
+		g.setString(out, f, "args[0]", fail)
+		out.WriteString("args = args[1:]\n")
+	}
+	out.WriteString("}\n")
+	return nil
+}
@@ -0,0 +1,437 @@
+// Package clivevet defines an Analyzer reporting mistakes in clive command
+// structs that would otherwise make clive.Build panic at runtime.
+package clivevet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	clive "github.com/ASMfreaK/clive2"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const clivePath = "github.com/ASMfreaK/clive2"
+
+// corePath declares the types clive re-exports as aliases.
+const corePath = clivePath + "/internal/clive"
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "clivevet",
+	Doc:      "check clive command structs for mistakes that make clive.Build panic",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// buildFuncs take a command struct as their first argument.
+var buildFuncs = map[string]bool{
+	"Build":          true,
+	"BuildCustom":    true,
+	"Describe":       true,
+	"DescribeCustom": true,
+	"JSONSchema":     true,
+	"BindJSON":       true,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.Preorder([]ast.Node{(*ast.StructType)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.StructType:
+			if st, ok := pass.TypesInfo.TypeOf(n).(*types.Struct); ok && isCommandStruct(st) {
+				checkCommand(pass, st)
+			}
+		case *ast.CallExpr:
+			checkBuildCall(pass, n)
+		}
+	})
+	return nil, nil
+}
+
+func typeString(pass *analysis.Pass, t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		if pkg == pass.Pkg {
+			return ""
+		}
+		return pkg.Name()
+	})
+}
+
+func isClive(obj types.Object, name string) bool {
+	return obj != nil && obj.Pkg() != nil && (obj.Pkg().Path() == clivePath || obj.Pkg().Path() == corePath) && obj.Name() == name
+}
+
+func isCliveType(t types.Type, name string) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	return ok && isClive(named.Obj(), name)
+}
+
+func isCommandStruct(t types.Type) bool {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok || st.NumFields() == 0 {
+		return false
+	}
+	first := st.Field(0)
+	ptr, ok := first.Type().(*types.Pointer)
+	return ok && first.Embedded() && first.Name() == "Command" && isCliveType(ptr.Elem(), "Command")
+}
+
+func hasMethod(t types.Type, name string) bool {
+	return types.NewMethodSet(types.NewPointer(t)).Lookup(nil, name) != nil ||
+		types.NewMethodSet(t).Lookup(nil, name) != nil
+}
+
+func checkBuildCall(pass *analysis.Pass, call *ast.CallExpr) {
+	var fn *ast.Ident
+	switch f := ast.Unparen(call.Fun).(type) {
+	case *ast.SelectorExpr:
+		fn = f.Sel
+	case *ast.Ident:
+		fn = f
+	default:
+		return
+	}
+	if !buildFuncs[fn.Name] || !isClive(pass.TypesInfo.Uses[fn], fn.Name) || len(call.Args) == 0 {
+		return
+	}
+	arg := call.Args[0]
+	t := pass.TypesInfo.TypeOf(arg)
+	if t == nil || types.IsInterface(t) {
+		return
+	}
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		pass.Reportf(arg.Pos(), "command struct %s is passed by value, pass by reference", typeString(pass, t))
+		return
+	}
+	if !isCommandStruct(ptr.Elem()) && !hasMethod(ptr.Elem(), "Subcommand") {
+		pass.Reportf(arg.Pos(), "the first field of command struct %s must be an embedded *clive.Command", typeString(pass, ptr.Elem()))
+	}
+}
+
+// positional tracks positional arguments to check their order the same way
+// clive.Build does.
+type positional struct {
+	name     string
+	variadic bool
+	optional bool
+}
+
+func checkCommand(pass *analysis.Pass, st *types.Struct) {
+	if _, err := clive.ParseTag(reflect.StructTag(st.Tag(0)).Get("cli")); err != nil {
+		pass.Reportf(st.Field(0).Pos(), "bad cli tag on the embedded clive.Command: %s", err)
+	}
+	if !hasMethod(st, "Action") {
+		pass.Reportf(st.Field(0).Pos(), "command struct must implement Actionable")
+	}
+	var positionals []positional
+	for i := 1; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if field.Name() == "Subcommands" {
+			checkSubcommands(pass, field.Type(), field.Pos())
+			continue
+		}
+		if field.Name() == "Run" && isCliveType(field.Type(), "RunFunc") {
+			continue
+		}
+		checkField(pass, field, st.Tag(i), field.Pos(), &positionals)
+	}
+}
+
+func checkSubcommands(pass *analysis.Pass, t types.Type, pos token.Pos) {
+	for {
+		ptr, ok := t.(*types.Pointer)
+		if !ok {
+			break
+		}
+		t = ptr.Elem()
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		pass.Reportf(pos, "Subcommands must be a struct, got %s", typeString(pass, t))
+		return
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		fieldPos := reportPos(pass, field.Pos(), pos)
+		switch ft := field.Type().(type) {
+		case *types.Pointer:
+			if _, ok := ft.Elem().Underlying().(*types.Struct); !ok {
+				pass.Reportf(fieldPos, "subcommand %s has type %s, should be a pointer to struct", field.Name(), typeString(pass, ft))
+				continue
+			}
+			if !isCommandStruct(ft.Elem()) && !hasMethod(ft.Elem(), "Subcommand") {
+				pass.Reportf(fieldPos, "the first field of subcommand %s must be an embedded *clive.Command", field.Name())
+			}
+		default:
+			if _, ok := ft.Underlying().(*types.Struct); ok && !isCommandStruct(ft) {
+				checkSubcommands(pass, ft, fieldPos)
+				continue
+			}
+			pass.Reportf(fieldPos, "subcommand %s (type %s) is passed by value, not by reference", field.Name(), typeString(pass, ft))
+		}
+	}
+}
+
+// reportPos returns pos if it is inside the package being analyzed and
+// fallback otherwise.
+func reportPos(pass *analysis.Pass, pos, fallback token.Pos) token.Pos {
+	for _, f := range pass.Files {
+		if f.FileStart <= pos && pos <= f.FileEnd {
+			return pos
+		}
+	}
+	return fallback
+}
+
+func checkField(pass *analysis.Pass, field *types.Var, rawTag string, pos token.Pos, positionals *[]positional) {
+	tag, err := clive.ParseTag(reflect.StructTag(rawTag).Get("cli"))
+	if err != nil {
+		pass.Reportf(pos, "bad cli tag on field %s: %s", field.Name(), err)
+		return
+	}
+	if tag.Skipped || tag.Inject {
+		return
+	}
+	if field.Embedded() && !tag.Inline && embeddedGroup(field.Type()) {
+		tag.Inline = true
+	}
+	if tag.PrefixSet && !tag.Inline {
+		pass.Reportf(pos, "prefix is only supported for inline fields, %s is not one", field.Name())
+	}
+	if tag.Inline {
+		t := deref(field.Type())
+		slice, repeated := t.Underlying().(*types.Slice)
+		if repeated {
+			t = deref(slice.Elem())
+		}
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			pass.Reportf(pos, "inline field %s is not a struct or a slice of structs", field.Name())
+			return
+		}
+		inner := positionals
+		var elementPositionals []positional
+		if repeated {
+			inner = &elementPositionals
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			checkField(pass, st.Field(i), st.Tag(i), reportPos(pass, st.Field(i).Pos(), pos), inner)
+		}
+		if len(elementPositionals) != 0 {
+			pass.Reportf(pos, "repeated field %s can't have positional arguments", field.Name())
+		}
+		return
+	}
+	if tag.Default != nil {
+		if err := checkDefault(field.Type(), *tag.Default, tag); err != nil {
+			pass.Reportf(pos, "bad default value %q for field %s of type %s: %s", *tag.Default, field.Name(), typeString(pass, field.Type()), err)
+		}
+	}
+	if tag.Negatable {
+		if b, ok := deref(field.Type()).(*types.Basic); !ok || b.Kind() != types.Bool || tag.Positional {
+			pass.Reportf(pos, "negatable field %s is not a bool flag", field.Name())
+		}
+	}
+	if !tag.Positional {
+		return
+	}
+	name := field.Name()
+	if tag.Name != "" {
+		name = tag.Name
+	}
+	if tag.Hidden {
+		pass.Reportf(pos, "positional argument %s cannot be Hidden", name)
+	}
+	current := positional{
+		name:     name,
+		variadic: isSlice(field.Type()),
+		optional: (tag.RequiredSet && !tag.Required) || (!tag.RequiredSet && tag.Default != nil),
+	}
+	if n := len(*positionals); n > 0 {
+		last := (*positionals)[n-1]
+		switch {
+		case last.variadic:
+			pass.Reportf(pos, "cant add positional argument %s after variadic (slice of x) argument %s", name, last.name)
+		case last.optional && !current.optional:
+			pass.Reportf(pos, "positional argument %s cannot be non-optional after an optional argument", name)
+		}
+	}
+	*positionals = append(*positionals, current)
+}
+
+func deref(t types.Type) types.Type {
+	for {
+		ptr, ok := t.(*types.Pointer)
+		if !ok {
+			return t
+		}
+		t = ptr.Elem()
+	}
+}
+
+// embeddedGroup reports whether an embedded field of type t is an inline
+// group: a struct clive has no flag type for.
+func embeddedGroup(t types.Type) bool {
+	t = types.Unalias(deref(t))
+	if _, ok := t.Underlying().(*types.Struct); !ok {
+		return false
+	}
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+		if _, ok := stdlibDefaults[named.Obj().Pkg().Path()+"."+named.Obj().Name()]; ok {
+			return false
+		}
+	}
+	return !hasMethod(t, "UnmarshalText")
+}
+
+func isSlice(t types.Type) bool {
+	_, ok := deref(t).Underlying().(*types.Slice)
+	return ok
+}
+
+// stdlibDefaults parse default values of the standard library types clive
+// has built-in support for, keyed by package path and type name.
+var stdlibDefaults = map[string]func(s, layout string) error{
+	"net.IP": func(s, _ string) error {
+		if net.ParseIP(s) == nil {
+			return fmt.Errorf("invalid IP address %q", s)
+		}
+		return nil
+	},
+	"net.IPNet": func(s, _ string) error {
+		_, _, err := net.ParseCIDR(s)
+		return err
+	},
+	"net/netip.Addr": func(s, _ string) error {
+		_, err := netip.ParseAddr(s)
+		return err
+	},
+	"net/netip.AddrPort": func(s, _ string) error {
+		_, err := netip.ParseAddrPort(s)
+		return err
+	},
+	"net/netip.Prefix": func(s, _ string) error {
+		_, err := netip.ParsePrefix(s)
+		return err
+	},
+	"net/url.URL": func(s, _ string) error {
+		_, err := url.Parse(s)
+		return err
+	},
+	"regexp.Regexp": func(s, _ string) error {
+		_, err := regexp.Compile(s)
+		return err
+	},
+	"time.Time": func(s, layout string) error {
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		_, err := time.Parse(layout, s)
+		return err
+	},
+	"time.Location": func(s, _ string) error {
+		_, err := time.LoadLocation(s)
+		return err
+	},
+	"io/fs.FileMode": func(s, _ string) error {
+		if _, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32); err != nil {
+			return fmt.Errorf("invalid file mode %q, expected octal permissions like 0644", s)
+		}
+		return nil
+	},
+	"math/big.Int": func(s, _ string) error {
+		if _, ok := new(big.Int).SetString(s, 0); !ok {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		return nil
+	},
+	"math/big.Float": func(s, _ string) error {
+		if _, ok := new(big.Float).SetString(s); !ok {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		return nil
+	},
+}
+
+// checkDefault parses a default value the way clive would for types whose
+// syntax is known statically. Types implementing encoding.TextUnmarshaler
+// are only checked at runtime.
+func checkDefault(t types.Type, s string, tag clive.Tag) error {
+	t = types.Unalias(deref(t))
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+		obj := named.Obj()
+		if parse, ok := stdlibDefaults[obj.Pkg().Path()+"."+obj.Name()]; ok {
+			return parse(s, tag.Layout)
+		}
+		switch {
+		case obj.Pkg().Path() == "time" && obj.Name() == "Duration":
+			_, err := time.ParseDuration(s)
+			return err
+		case isClive(obj, "Counter"):
+			_, err := strconv.ParseInt(s, 0, strconv.IntSize)
+			return err
+		case isClive(obj, "ByteSize"):
+			unit := tag.Unit
+			if unit == "" {
+				unit = "B"
+			}
+			_, err := clive.ParseByteSize(s, unit)
+			return err
+		case isClive(obj, "Percent"):
+			_, err := clive.ParsePercent(s)
+			return err
+		case isClive(obj, "Duration"):
+			_, err := clive.ParseDuration(s, tag.Unit)
+			return err
+		}
+	}
+	if hasMethod(t, "UnmarshalText") {
+		return nil
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Slice:
+		items, err := clive.SplitList(s, tag.Sep)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := checkDefault(u.Elem(), item, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *types.Basic:
+		return checkBasic(u, s)
+	}
+	return nil
+}
+
+func checkBasic(b *types.Basic, s string) (err error) {
+	sizes := types.SizesFor("gc", "amd64")
+	bits := int(sizes.Sizeof(b)) * 8
+	switch {
+	case b.Info()&types.IsUnsigned != 0:
+		_, err = strconv.ParseUint(s, 0, bits)
+	case b.Info()&types.IsInteger != 0:
+		_, err = strconv.ParseInt(s, 0, bits)
+	case b.Info()&types.IsFloat != 0:
+		_, err = strconv.ParseFloat(s, bits)
+	case b.Info()&types.IsBoolean != 0:
+		var v bool
+		err = clive.ParseValue(&v, s)
+	}
+	return
+}
@@ -0,0 +1,35 @@
+// Command clive-gen writes code constructing flags and binding values for the
+// clive command structs of a package, so that they are handled without
+// reflection at runtime. It is meant to be run with go generate:
+//
+//	//go:generate go run github.com/ASMfreaK/clive2/cmd/clive-gen
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ASMfreaK/clive2/clivegen"
+)
+
+func main() {
+	output := flag.String("output", "clive_gen.go", "name of the generated file, relative to the package directory")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	src, err := clivegen.Generate(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "clive-gen: %s\n", err)
+		os.Exit(1)
+	}
+	err = os.WriteFile(filepath.Join(dir, *output), src, 0o644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "clive-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
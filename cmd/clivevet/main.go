@@ -0,0 +1,14 @@
+// Command clivevet checks clive command structs. It can be run directly or
+// through go vet:
+//
+//	go vet -vettool=$(which clivevet) ./...
+package main
+
+import (
+	"github.com/ASMfreaK/clive2/clivevet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(clivevet.Analyzer)
+}
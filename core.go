@@ -0,0 +1,186 @@
+package clive
+
+import (
+	"encoding"
+	"reflect"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+)
+
+// The parts of clive that don't depend on urfave/cli live in
+// internal/clive, they are shared with the builders for other command line
+// libraries (see the clivecore package).
+
+type (
+	CommandSpec      = core.CommandSpec
+	FieldSpec        = core.FieldSpec
+	Tag              = core.Tag
+	HelpRenderer     = core.HelpRenderer
+	TextHelpRenderer = core.TextHelpRenderer
+	JSONHelpRenderer = core.JSONHelpRenderer
+	Schema           = core.Schema
+
+	GeneratedCommand = core.GeneratedCommand
+
+	FlagValues        = core.FlagValues
+	OrderedFlagValues = core.OrderedFlagValues
+	RawValue          = core.RawValue
+	RawValues         = core.RawValues
+
+	WithVersion                = core.WithVersion
+	WithDescription            = core.WithDescription
+	HasVariants                = core.HasVariants
+	HasVariantAliases          = core.HasVariantAliases
+	HasCaseInsensitiveVariants = core.HasCaseInsensitiveVariants
+	HasVariantDescriptions     = core.HasVariantDescriptions
+	Opener                     = core.Opener
+
+	TypePredicate  = core.TypePredicate
+	TypeFunctions  = core.TypeFunctions
+	TypeInterface  = core.TypeInterface
+	StandardType   = core.StandardType
+	InterfaceType  = core.InterfaceType
+	UnderlyingType = core.UnderlyingType
+	ParsedType     = core.ParsedType
+	PointerTo      = core.PointerTo
+	LayoutType     = core.LayoutType
+	UnitType       = core.UnitType
+
+	Counter      = core.Counter
+	ByteSize     = core.ByteSize
+	Duration     = core.Duration
+	Percent      = core.Percent
+	Path         = core.Path
+	ExistingFile = core.ExistingFile
+	ExistingDir  = core.ExistingDir
+	InputFile    = core.InputFile
+	OutputFile   = core.OutputFile
+
+	ByValueError                 = core.ByValueError
+	WrongFirstFieldError         = core.WrongFirstFieldError
+	HiddenPositionalError        = core.HiddenPositionalError
+	PositionalAfterVariadicError = core.PositionalAfterVariadicError
+	RequiredFlagsError           = core.RequiredFlagsError
+	NegatedFlagError             = core.NegatedFlagError
+	TooFewArgumentsError         = core.TooFewArgumentsError
+	TooManyArgumentsError        = core.TooManyArgumentsError
+	FieldBindError               = core.FieldBindError
+	InvalidVariantError          = core.InvalidVariantError
+	UnselectedVariantError       = core.UnselectedVariantError
+	UnknownFlagError             = core.UnknownFlagError
+	UnknownCommandError          = core.UnknownCommandError
+	UnknownEnvError              = core.UnknownEnvError
+	UnsupportedError             = core.UnsupportedError
+)
+
+// DefaultSep separates the items of slice values unless a `sep:` tag says
+// otherwise.
+const DefaultSep = core.DefaultSep
+
+// Formats of slice environment variables, set with the `envformat:` tag.
+const (
+	EnvFormatList  = core.EnvFormatList
+	EnvFormatLines = core.EnvFormatLines
+	EnvFormatJSON  = core.EnvFormatJSON
+)
+
+var ErrNil = core.ErrNil
+
+// DefaultHelpRenderer is used when BuildOptions.HelpRenderer is not set.
+var DefaultHelpRenderer HelpRenderer = &TextHelpRenderer{}
+
+func Reflected[T any]() reflect.Type {
+	return core.Reflected[T]()
+}
+
+func NewStandardType[T any]() *StandardType {
+	return core.NewStandardType[T]()
+}
+
+func NewUnderlyingType[T any]() *UnderlyingType {
+	return core.NewUnderlyingType[T]()
+}
+
+func NewParsedType[T any](parse func(s, option string) (T, error)) *ParsedType {
+	return core.NewParsedType(parse)
+}
+
+// ParseTag parses the value of a `cli` struct tag.
+func ParseTag(s string) (Tag, error) {
+	return core.ParseTag(s)
+}
+
+// ParseValue parses s into dst the same way clive parses positional
+// arguments and default values. It is used by generated code.
+func ParseValue[T any](dst *T, s string) error {
+	return core.ParseValue(dst, s)
+}
+
+// ParseValues parses a list of positional arguments into the slice dst. It
+// is used by generated code.
+func ParseValues[T any](dst *T, s []string) error {
+	return core.ParseValues(dst, s)
+}
+
+// ParseByteSize parses a byte size like 10MiB or 1.5GB, plain numbers are
+// in unit.
+func ParseByteSize(s, unit string) (ByteSize, error) {
+	return core.ParseByteSize(s, unit)
+}
+
+// ParseDuration parses a duration the way time.ParseDuration does, with d and
+// w units on top. Plain numbers are in unit, they are an error if unit is
+// empty, except for 0.
+func ParseDuration(s, unit string) (Duration, error) {
+	return core.ParseDuration(s, unit)
+}
+
+// ParsePercent parses a percentage with an optional % sign.
+func ParsePercent(s string) (Percent, error) {
+	return core.ParsePercent(s)
+}
+
+// SplitList splits a slice value into its items at sep. A backslash escapes
+// sep, a double quote or another backslash, other backslashes are kept. An
+// item starting with a double quote runs to the closing quote, seps inside
+// it are kept.
+func SplitList(s, sep string) ([]string, error) {
+	return core.SplitList(s, sep)
+}
+
+// Suggest returns the candidates closest to name by edit distance, if they
+// are close enough for name to be a typo of them. It is used for unknown
+// flags, subcommands, variants and environment variables, and by generated
+// code.
+func Suggest(name string, candidates []string) []string {
+	return core.Suggest(name, candidates)
+}
+
+// UsageWithVariants appends the list of possible values to a flag usage text.
+func UsageWithVariants(usage string, variants []string) string {
+	return core.UsageWithVariants(usage, variants)
+}
+
+// UnmarshalVariant sets v from the variant of its type named by s, the way
+// fields of HasVariants types are set. It is used by generated code.
+func UnmarshalVariant(v encoding.TextUnmarshaler, s string) error {
+	return core.UnmarshalVariant(v, s)
+}
+
+// NewRawValues returns RawValues for the flags of spec.
+func NewRawValues(spec *CommandSpec) *RawValues {
+	return core.NewRawValues(spec)
+}
+
+// JSONSchema parses obj the same way Describe does and returns a JSON Schema
+// for every command, keyed by the command path (see CommandSpec.Path).
+func JSONSchema(obj interface{}) (map[string]*Schema, error) {
+	return core.JSONSchema(obj)
+}
+
+// BindJSON parses obj the same way Describe does and populates the command
+// found at commandPath (see CommandSpec.Path) from a JSON document matching
+// the schema returned by JSONSchema.
+func BindJSON(obj interface{}, commandPath string, data []byte) error {
+	return core.BindJSON(obj, commandPath, data)
+}
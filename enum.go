@@ -0,0 +1,83 @@
+package clive
+
+import (
+	"cmp"
+	"fmt"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+)
+
+// RegisterVariant registers the struct impl points to as the implementation
+// of the interface I selected by name. A flag or field of type I becomes a
+// flag taking one of the names registered for I, in the order they were
+// registered. The fields of every implementation become flags prefixed with
+// its name, like an inline group, but only the flags of the selected one are
+// bound and checked for being required: the field is set to a new instance
+// of it. Flags of the other implementations given on the command line are an
+// error.
+//
+// The name is usually a string. It can also be a value of a string type
+// implementing HasVariants, the way the values of flags are checked, which
+// keeps the names registered for I among the ones that type lists.
+//
+// Like Build, RegisterVariant panics on misuse: when I is not an interface,
+// impl doesn't point to a struct, name is already taken or isn't one of the
+// variants of its type.
+func RegisterVariant[I any, K ~string](name K, impl I) {
+	core.RegisterVariant[I](name, impl)
+}
+
+// RegisterEnum registers the names of the values of T. Fields of type T, and
+// of Enum[T], then take the names of its values: they are listed in help,
+// checked like the variants of HasVariants types and parsed into the values
+// they name. The variants are listed in the order of their values.
+//
+// Like RegisterVariant, RegisterEnum panics on misuse: when T is already
+// registered or two values have the same name.
+//
+//	clive.RegisterEnum(map[Color]string{Red: "red", Green: "green", Blue: "blue"})
+func RegisterEnum[T cmp.Ordered](names map[T]string) {
+	core.RegisterEnum(names)
+}
+
+// RegisterEnumDescriptions sets the descriptions of the values of T shown in
+// help, T must be registered with RegisterEnum first. It panics when T isn't
+// registered or a value has no name.
+func RegisterEnumDescriptions[T cmp.Ordered](descriptions map[T]string) {
+	core.RegisterEnumDescriptions(descriptions)
+}
+
+// Enum holds a value of T, an enum registered with RegisterEnum, and gives it
+// the String, MarshalText, UnmarshalText and Variants methods of the names of
+// its values.
+type Enum[T cmp.Ordered] struct {
+	Value T
+}
+
+func (e Enum[T]) String() string {
+	if name, ok := core.EnumName(e.Value); ok {
+		return name
+	}
+	return fmt.Sprint(e.Value)
+}
+
+func (e Enum[T]) MarshalText() ([]byte, error) {
+	name, ok := core.EnumName(e.Value)
+	if !ok {
+		return nil, fmt.Errorf("value %v of enum %s has no name", e.Value, Reflected[T]())
+	}
+	return []byte(name), nil
+}
+
+func (e *Enum[T]) UnmarshalText(text []byte) (err error) {
+	e.Value, err = core.ParseEnum[T](string(text))
+	return
+}
+
+func (*Enum[T]) Variants() []string {
+	return core.EnumVariants[T]()
+}
+
+func (*Enum[T]) VariantDescriptions() map[string]string {
+	return core.EnumDescriptions[T]()
+}
@@ -52,32 +52,12 @@ const (
 	Client
 )
 
-var roleStrings = []string{
-	Server: "server",
-	Client: "client",
-}
-
-func (c Role) String() string {
-	return roleStrings[c]
-}
-
-func (c *Role) UnmarshalText(text []byte) error {
-	for i, variant := range roleStrings {
-		if string(text) == variant {
-			*c = Role(i)
-			return nil
-		}
-	}
-	return fmt.Errorf("invalid color: %s", text)
-}
-
-func (c Role) MarshalText() ([]byte, error) {
-	return []byte(c.String()), nil
-}
-
-var AllRoles = []Role{
-	Server,
-	Client,
+func init() {
+	clive.RegisterEnum(map[Role]string{Server: "server", Client: "client"})
+	clive.RegisterEnumDescriptions(map[Role]string{
+		Server: "accept connections",
+		Client: "connect to a server",
+	})
 }
 
 type SetOption struct {
@@ -109,6 +89,7 @@ type App struct {
 		}
 	}
 
+	Role                  Role   `cli:"default:server"`
 	PostgresDsn           string `cli:"default:hello"`
 	ProcessSchedule       string `cli:"hidden:true"`
 	ApplicationAPIAddress string `cli:"name:api_address"`
@@ -0,0 +1,141 @@
+package clive
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ExitCoder is implemented by errors choosing the exit code of the program,
+// like those of cli.Exit.
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// ErrorClass groups errors by who has to fix them, see
+// BuildOptions.ExitCodes.
+type ErrorClass int
+
+const (
+	// RuntimeErrors are failures of the commands themselves.
+	RuntimeErrors ErrorClass = iota
+	// UsageErrors are mistakes on the command line: unknown or malformed
+	// flags, bad values, missing required flags and arguments, extra
+	// arguments.
+	UsageErrors
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case RuntimeErrors:
+		return "runtime"
+	case UsageErrors:
+		return "usage"
+	}
+	return fmt.Sprintf("ErrorClass(%d)", int(c))
+}
+
+// DefaultExitCodes are the exit codes of the classes of errors used when
+// BuildOptions.ExitCodes doesn't set them, following the convention of 2
+// for usage errors.
+var DefaultExitCodes = map[ErrorClass]int{
+	RuntimeErrors: 1,
+	UsageErrors:   2,
+}
+
+// UsageError wraps the errors urfave/cli returns parsing the command line.
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// ClassOf returns the class of err.
+func ClassOf(err error) ErrorClass {
+	var (
+		usage      *UsageError
+		tooFew     *TooFewArgumentsError
+		tooMany    *TooManyArgumentsError
+		bind       *FieldBindError
+		required   *RequiredFlagsError
+		negated    *NegatedFlagError
+		unselected *UnselectedVariantError
+		flag       *UnknownFlagError
+		command    *UnknownCommandError
+		env        *UnknownEnvError
+	)
+	switch {
+	case errors.As(err, &usage), errors.As(err, &tooFew), errors.As(err, &tooMany), errors.As(err, &bind),
+		errors.As(err, &required), errors.As(err, &unselected), errors.As(err, &flag), errors.As(err, &command),
+		errors.As(err, &env), errors.As(err, &negated):
+		return UsageErrors
+	}
+	return RuntimeErrors
+}
+
+// ExitCode returns the exit code for err: 0 for nil, the code of an
+// ExitCoder and the code of the class of err otherwise.
+func (bo *BuildOptions) ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	class := ClassOf(err)
+	if code, ok := bo.ExitCodes[class]; ok {
+		return code
+	}
+	return DefaultExitCodes[class]
+}
+
+// ExitCode returns the exit code for the error err returned by running app,
+// an App built by clive, with the ExitCodes of its BuildOptions.
+//
+//	err := app.Run(os.Args)
+//	if err != nil {
+//		fmt.Fprintln(os.Stderr, err)
+//	}
+//	os.Exit(clive.ExitCode(app, err))
+func ExitCode(app *cli.App, err error) int {
+	if record, ok := app.Metadata[cliveRecordKey].(*commandRecord); ok {
+		return record.bo.ExitCode(err)
+	}
+	return DefaultBuildOptions.ExitCode(err)
+}
+
+// exitErrHandler is the ExitErrHandler of Apps built with ExitOnError whose
+// root command struct has none: it prints err and exits with its exit code, 2
+// for usage errors by default. urfave/cli only exits for ExitCoders.
+func exitErrHandler(bo *BuildOptions) cli.ExitErrHandlerFunc {
+	return func(ctx *cli.Context, err error) {
+		if err == nil {
+			return
+		}
+		_, _ = fmt.Fprintln(ctx.App.ErrWriter, err)
+		cli.OsExiter(bo.ExitCode(err))
+	}
+}
+
+// onUsageError marks the errors urfave/cli returns parsing the command line
+// of commands not handling them themselves as UsageErrors, showing help the
+// way urfave/cli does. Unknown flags become UnknownFlagErrors.
+func onUsageError(ctx *cli.Context, err error, isSubcommand bool) error {
+	err = unknownFlag(ctx, err)
+	_, _ = fmt.Fprintf(ctx.App.Writer, "%s %s\n\n", "Incorrect Usage:", err.Error())
+	if isSubcommand {
+		_ = showSubcommandHelp(ctx)
+	} else {
+		_ = cli.ShowAppHelp(ctx)
+	}
+	return &UsageError{err}
+}
@@ -0,0 +1,349 @@
+package clive
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+	"github.com/urfave/cli/v2"
+)
+
+// cliType constructs the urfave/cli flags of fields holding their values in
+// one type, see core.TypeFunctions.ValueType, and reads the values back.
+type cliType struct {
+	newFlag func(cmdMeta core.CommandMetadata) (cli.Flag, error)
+	value   func(ctx *cli.Context, name string) (reflect.Value, error)
+}
+
+func newCliType[T, Flag any]() cliType {
+	return cliType{newFlag: newFlag[T, Flag], value: valueFromContext[T]}
+}
+
+var cliTypes = map[reflect.Type]cliType{
+	core.Reflected[int]():           newCliType[int, cli.IntFlag](),
+	core.Reflected[int64]():         newCliType[int64, cli.Int64Flag](),
+	core.Reflected[int8]():          newCliType[int8, cli.Int64Flag](),
+	core.Reflected[int16]():         newCliType[int16, cli.Int64Flag](),
+	core.Reflected[int32]():         newCliType[int32, cli.Int64Flag](),
+	core.Reflected[uint]():          newCliType[uint, cli.UintFlag](),
+	core.Reflected[uint64]():        newCliType[uint64, cli.Uint64Flag](),
+	core.Reflected[uint8]():         newCliType[uint8, cli.Uint64Flag](),
+	core.Reflected[uint16]():        newCliType[uint16, cli.Uint64Flag](),
+	core.Reflected[uint32]():        newCliType[uint32, cli.Uint64Flag](),
+	core.Reflected[float32]():       newCliType[float32, cli.Float64Flag](),
+	core.Reflected[float64]():       newCliType[float64, cli.Float64Flag](),
+	core.Reflected[string]():        newCliType[string, cli.StringFlag](),
+	core.Reflected[time.Duration](): newCliType[time.Duration, cli.DurationFlag](),
+	core.Reflected[bool]():          newCliType[bool, BoolFlag](),
+	core.Reflected[Counter]():       newCliType[Counter, cli.BoolFlag](),
+
+	core.Reflected[[]int]():           newCliType[[]int, cli.IntSliceFlag](),
+	core.Reflected[[]int64]():         newCliType[[]int64, cli.Int64SliceFlag](),
+	core.Reflected[[]int8]():          newCliType[[]int8, cli.Int64SliceFlag](),
+	core.Reflected[[]int16]():         newCliType[[]int16, cli.Int64SliceFlag](),
+	core.Reflected[[]int32]():         newCliType[[]int32, cli.Int64SliceFlag](),
+	core.Reflected[[]uint]():          newCliType[[]uint, cli.UintSliceFlag](),
+	core.Reflected[[]uint64]():        newCliType[[]uint64, cli.Uint64SliceFlag](),
+	core.Reflected[[]uint8]():         newCliType[[]uint8, cli.Uint64SliceFlag](),
+	core.Reflected[[]uint16]():        newCliType[[]uint16, cli.Uint64SliceFlag](),
+	core.Reflected[[]uint32]():        newCliType[[]uint32, cli.Uint64SliceFlag](),
+	core.Reflected[[]float32]():       newCliType[[]float32, cli.Float64SliceFlag](),
+	core.Reflected[[]float64]():       newCliType[[]float64, cli.Float64SliceFlag](),
+	core.Reflected[[]string]():        newCliType[[]string, cli.StringSliceFlag](),
+	core.Reflected[[]time.Duration](): newCliType[[]time.Duration, cli.StringSliceFlag](),
+	core.Reflected[[]bool]():          newCliType[[]bool, cli.StringSliceFlag](),
+}
+
+// newCliFlag constructs the urfave/cli flag of a field.
+func newCliFlag(cmdMeta core.CommandMetadata) (cli.Flag, error) {
+	t, ok := cliTypes[cmdMeta.ValueType()]
+	if !ok {
+		return nil, fmt.Errorf("flag %s: urfave/cli can't hold values of type %s", cmdMeta.Name, cmdMeta.ValueType())
+	}
+	// flags keeping values as strings only parse them when the command runs
+	if err := core.CheckDefault(&cmdMeta); err != nil {
+		return nil, err
+	}
+	return t.newFlag(cmdMeta)
+}
+
+// setFromContext sets the field of the flag cmdMeta from its value in c.
+func setFromContext(cmdMeta *core.CommandMetadata, field reflect.Value, c *cli.Context) error {
+	if listFlag(cmdMeta) {
+		items, _ := c.Value(cmdMeta.Name).([]string)
+		return cmdMeta.SetValueFromStrings(field, items)
+	}
+	v, err := cliTypes[cmdMeta.ValueType()].value(c, cmdMeta.Name)
+	if err != nil {
+		return err
+	}
+	return cmdMeta.SetValue(field, v)
+}
+
+func valueFromContext[T any](ctx *cli.Context, name string) (reflect.Value, error) {
+	var v T
+	err := contextFunction[T]()(&v, ctx, name)
+	return reflect.ValueOf(v), err
+}
+
+//nolint:unparam
+func contextFunction[T any]() (ret func(ret *T, ctx *cli.Context, s string) error) {
+	switch rv := interface{}(&ret).(type) {
+	// scalars
+	case *func(*int, *cli.Context, string) error:
+		*rv = func(ret *int, ctx *cli.Context, s string) error { *ret = ctx.Int(s); return nil }
+	case *func(*int64, *cli.Context, string) error:
+		*rv = func(ret *int64, ctx *cli.Context, s string) error { *ret = ctx.Int64(s); return nil }
+	case *func(*uint, *cli.Context, string) error:
+		*rv = func(ret *uint, ctx *cli.Context, s string) error { *ret = ctx.Uint(s); return nil }
+	case *func(*uint64, *cli.Context, string) error:
+		*rv = func(ret *uint64, ctx *cli.Context, s string) error { *ret = ctx.Uint64(s); return nil }
+	case *func(*int8, *cli.Context, string) error:
+		*rv = narrowInt[int8]
+	case *func(*int16, *cli.Context, string) error:
+		*rv = narrowInt[int16]
+	case *func(*int32, *cli.Context, string) error:
+		*rv = narrowInt[int32]
+	case *func(*uint8, *cli.Context, string) error:
+		*rv = narrowUint[uint8]
+	case *func(*uint16, *cli.Context, string) error:
+		*rv = narrowUint[uint16]
+	case *func(*uint32, *cli.Context, string) error:
+		*rv = narrowUint[uint32]
+	case *func(*float32, *cli.Context, string) error:
+		*rv = func(ret *float32, ctx *cli.Context, s string) error { *ret = float32(ctx.Float64(s)); return nil }
+	case *func(*float64, *cli.Context, string) error:
+		*rv = func(ret *float64, ctx *cli.Context, s string) error { *ret = ctx.Float64(s); return nil }
+	case *func(*string, *cli.Context, string) error:
+		*rv = func(ret *string, ctx *cli.Context, s string) error { *ret = ctx.String(s); return nil }
+	case *func(*time.Duration, *cli.Context, string) error:
+		*rv = func(ret *time.Duration, ctx *cli.Context, s string) error { *ret = ctx.Duration(s); return nil }
+	case *func(*bool, *cli.Context, string) error:
+		*rv = func(ret *bool, ctx *cli.Context, s string) error { *ret = ctx.Bool(s); return nil }
+	case *func(*Counter, *cli.Context, string) error:
+		*rv = func(ret *Counter, ctx *cli.Context, s string) error { ret.Value = ctx.Count(s); return nil }
+	// slices
+	case *func(*[]int, *cli.Context, string) error:
+		*rv = func(ret *[]int, ctx *cli.Context, s string) error { *ret = ctx.IntSlice(s); return nil }
+	case *func(*[]int64, *cli.Context, string) error:
+		*rv = func(ret *[]int64, ctx *cli.Context, s string) error { *ret = ctx.Int64Slice(s); return nil }
+	case *func(*[]uint, *cli.Context, string) error:
+		*rv = func(ret *[]uint, ctx *cli.Context, s string) error { *ret = ctx.UintSlice(s); return nil }
+	case *func(*[]uint64, *cli.Context, string) error:
+		*rv = func(ret *[]uint64, ctx *cli.Context, s string) error { *ret = ctx.Uint64Slice(s); return nil }
+	case *func(*[]int8, *cli.Context, string) error:
+		*rv = narrowInts[int8]
+	case *func(*[]int16, *cli.Context, string) error:
+		*rv = narrowInts[int16]
+	case *func(*[]int32, *cli.Context, string) error:
+		*rv = narrowInts[int32]
+	case *func(*[]uint8, *cli.Context, string) error:
+		*rv = narrowUints[uint8]
+	case *func(*[]uint16, *cli.Context, string) error:
+		*rv = narrowUints[uint16]
+	case *func(*[]uint32, *cli.Context, string) error:
+		*rv = narrowUints[uint32]
+	case *func(*[]float32, *cli.Context, string) error:
+		*rv = func(ret *[]float32, ctx *cli.Context, s string) error {
+			return core.ConvertSlice[float32, float64](
+				ret, ctx.Float64Slice(s),
+				func(f1 *float32, f2 float64) error { *f1 = float32(f2); return nil })
+		}
+	case *func(*[]float64, *cli.Context, string) error:
+		*rv = func(ret *[]float64, ctx *cli.Context, s string) error { *ret = ctx.Float64Slice(s); return nil }
+	case *func(*[]string, *cli.Context, string) error:
+		*rv = func(ret *[]string, ctx *cli.Context, s string) error { *ret = ctx.StringSlice(s); return nil }
+	case *func(*[]time.Duration, *cli.Context, string) error:
+		*rv = func(ret *[]time.Duration, ctx *cli.Context, s string) error {
+			return core.ConvertSlice[time.Duration, string](ret, ctx.StringSlice(s), core.ParseValue[time.Duration])
+		}
+	case *func(*[]bool, *cli.Context, string) error:
+		*rv = func(ret *[]bool, ctx *cli.Context, s string) error {
+			return core.ConvertSlice[bool, string](ret, ctx.StringSlice(s), core.ParseValue[bool])
+		}
+	default:
+		panic("unexpected type " + reflect.TypeOf((*T)(nil)).Elem().String())
+	}
+	return
+}
+
+// narrowInt reads a flag held by a cli.Int64Flag into a narrower integer,
+// checking for overflow.
+func narrowInt[T any](ret *T, ctx *cli.Context, s string) error {
+	return core.ParseValue[T](ret, strconv.FormatInt(ctx.Int64(s), 10))
+}
+
+func narrowUint[T any](ret *T, ctx *cli.Context, s string) error {
+	return core.ParseValue[T](ret, strconv.FormatUint(ctx.Uint64(s), 10))
+}
+
+func narrowInts[T any](ret *[]T, ctx *cli.Context, s string) error {
+	return core.ConvertSlice[T, int64](ret, ctx.Int64Slice(s), func(r *T, v int64) error {
+		return core.ParseValue[T](r, strconv.FormatInt(v, 10))
+	})
+}
+
+func narrowUints[T any](ret *[]T, ctx *cli.Context, s string) error {
+	return core.ConvertSlice[T, uint64](ret, ctx.Uint64Slice(s), func(r *T, v uint64) error {
+		return core.ParseValue[T](r, strconv.FormatUint(v, 10))
+	})
+}
+
+// BoolFlag is a cli.BoolFlag reading the spellings ParseBool accepts from its
+// environment variables, urfave/cli only understands strconv.ParseBool. Every
+// bool flag reads an environment variable, so all of them are BoolFlags rather
+// than *cli.BoolFlags: code looking for the latter in App.Flags has to look for
+// *BoolFlag and use its embedded cli.BoolFlag. Counters stay cli.BoolFlags.
+type BoolFlag struct {
+	cli.BoolFlag
+}
+
+func (f *BoolFlag) Apply(set *flag.FlagSet) error {
+	inner := f.BoolFlag
+	inner.EnvVars = nil
+	inner.HasBeenSet = false
+	for _, env := range f.EnvVars {
+		value, ok := os.LookupEnv(strings.TrimSpace(env))
+		if !ok {
+			continue
+		}
+		// an empty value is false, like urfave/cli has it
+		inner.Value = false
+		if value != "" {
+			var err error
+			inner.Value, err = core.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("could not parse %q as bool value from environment variable %q for flag %s: %s", value, env, f.Name, err)
+			}
+		}
+		inner.HasBeenSet = true
+		break
+	}
+	err := inner.Apply(set)
+	f.HasBeenSet = inner.HasBeenSet
+	return err
+}
+
+func cliSliceFromStandartSliceTypes[T any](val *T) (ret reflect.Value, err error) {
+	switch rv := interface{}(val).(type) {
+	case *[]int:
+		ret = reflect.ValueOf(cli.NewIntSlice((*rv)...))
+	case *[]int64:
+		ret = reflect.ValueOf(cli.NewInt64Slice((*rv)...))
+	case *[]uint:
+		ret = reflect.ValueOf(cli.NewUintSlice((*rv)...))
+	case *[]uint64:
+		ret = reflect.ValueOf(cli.NewUint64Slice((*rv)...))
+	case *[]int8:
+		ret = widenSlice[int8, int64](*rv, cli.NewInt64Slice)
+	case *[]int16:
+		ret = widenSlice[int16, int64](*rv, cli.NewInt64Slice)
+	case *[]int32:
+		ret = widenSlice[int32, int64](*rv, cli.NewInt64Slice)
+	case *[]uint8:
+		ret = widenSlice[uint8, uint64](*rv, cli.NewUint64Slice)
+	case *[]uint16:
+		ret = widenSlice[uint16, uint64](*rv, cli.NewUint64Slice)
+	case *[]uint32:
+		ret = widenSlice[uint32, uint64](*rv, cli.NewUint64Slice)
+	case *[]float32:
+		var realSlice []float64
+		err = core.ConvertSlice[float64, float32](&realSlice, *rv, func(f1 *float64, f2 float32) error { *f1 = float64(f2); return nil })
+		ret = reflect.ValueOf(cli.NewFloat64Slice(realSlice...))
+	case *[]float64:
+		ret = reflect.ValueOf(cli.NewFloat64Slice((*rv)...))
+	case *[]string:
+		ret = reflect.ValueOf(cli.NewStringSlice((*rv)...))
+	case *[]time.Duration:
+		var realSlice []string
+		err = core.ConvertSlice[string, time.Duration](&realSlice, *rv, func(s *string, d time.Duration) error { *s = d.String(); return nil })
+		ret = reflect.ValueOf(cli.NewStringSlice(realSlice...))
+	case *[]bool:
+		var realSlice []string
+		err = core.ConvertSlice[string, bool](&realSlice, *rv, func(s *string, b bool) error { *s = strconv.FormatBool(b); return nil })
+		ret = reflect.ValueOf(cli.NewStringSlice(realSlice...))
+	default:
+		err = fmt.Errorf("unexpected type in  parseStandartTypes %s", reflect.TypeOf((*T)(nil)).Elem().String())
+	}
+	return
+}
+
+// widenSlice converts a default value of a slice of narrow integers into
+// the cli slice holding it.
+func widenSlice[U int8 | int16 | int32 | uint8 | uint16 | uint32, W int64 | uint64, S any](values []U, newSlice func(...W) S) reflect.Value {
+	wide := make([]W, len(values))
+	for i, v := range values {
+		wide[i] = W(v)
+	}
+	return reflect.ValueOf(newSlice(wide...))
+}
+
+func newFlag[T, Flag any](cmdMeta core.CommandMetadata) (flag cli.Flag, err error) {
+	variadic := core.Reflected[T]().Kind() == reflect.Slice
+	var def T
+	var defRefPtr reflect.Value
+	if cmdMeta.Default != nil {
+		defRefPtr = reflect.ValueOf(&def)
+		if variadic {
+			var items []string
+			items, err = cmdMeta.Split(*cmdMeta.Default)
+			if err == nil {
+				err = core.ParseValues(&def, items)
+			}
+		} else {
+			err = core.ParseValue(&def, *cmdMeta.Default)
+		}
+		if err != nil {
+			return
+		}
+		if core.Reflected[T]() == core.Reflected[float32]() {
+			def64 := float64(*defRefPtr.Interface().(*float32))
+			defRefPtr = reflect.ValueOf(&def64)
+		}
+		if variadic {
+			defRefPtr, err = cliSliceFromStandartSliceTypes[T](&def)
+			if err != nil {
+				return
+			}
+		} else {
+			defRefPtr = defRefPtr.Elem()
+		}
+	}
+	typedFlag := new(Flag)
+	refTypedFlag := reflect.ValueOf(typedFlag)
+	refTypedFlag.Elem().FieldByName("Name").SetString(cmdMeta.Name)
+	refTypedFlag.Elem().FieldByName("EnvVars").Set(reflect.ValueOf(cmdMeta.Envs))
+	refTypedFlag.Elem().FieldByName("Aliases").Set(reflect.ValueOf(cmdMeta.Aliases))
+	if core.Reflected[T]() == core.Reflected[Counter]() {
+		refTypedFlag.Elem().FieldByName("Count").Set(reflect.New(core.Reflected[int]()))
+	}
+	if defRefPtr.IsValid() {
+		if core.Reflected[T]() != core.Reflected[Counter]() {
+			value := refTypedFlag.Elem().FieldByName("Value")
+			// narrow integers are held by 64 bit flags
+			if defRefPtr.Type() != value.Type() && defRefPtr.CanConvert(value.Type()) {
+				defRefPtr = defRefPtr.Convert(value.Type())
+			}
+			value.Set(defRefPtr)
+		} else {
+			refTypedFlag.Elem().FieldByName("Count").Elem().Set(reflect.ValueOf(defRefPtr.Interface().(Counter).Value))
+		}
+	}
+	refTypedFlag.Elem().FieldByName("Hidden").SetBool(cmdMeta.Hidden)
+	refTypedFlag.Elem().FieldByName("Usage").SetString(cmdMeta.FlagUsage())
+	req := refTypedFlag.Elem().FieldByName("Required")
+	if req.IsValid() {
+		req.SetBool(cmdMeta.Required)
+	}
+	if takesFile := refTypedFlag.Elem().FieldByName("TakesFile"); takesFile.IsValid() {
+		takesFile.SetBool(core.TakesFile(cmdMeta.TypeInterface))
+	}
+
+	flag = refTypedFlag.Interface().(cli.Flag)
+	return
+}
@@ -0,0 +1,202 @@
+package clive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+	"github.com/urfave/cli/v2"
+)
+
+// Generated is implemented by command structs with code generated by
+// clive-gen. Build takes the command from CliveCommand and its flags from
+// CliveFlags instead of parsing the struct through reflection, and the
+// command binds flag values and positional arguments with CliveBind.
+// CliveHashes lists the FieldHash of its flags, then of its positional
+// arguments, as they were when the code was generated, see CheckGenerated.
+type Generated interface {
+	CliveCommand() GeneratedCommand
+	CliveFlags(bo *BuildOptions) []cli.Flag
+	CliveBind(ctx *cli.Context) error
+	CliveHashes() []string
+}
+
+// generatedFlags is implemented by command structs with generated code,
+// including code generated before CliveHashes and CliveCommand.
+type generatedFlags interface {
+	CliveFlags(bo *BuildOptions) []cli.Flag
+}
+
+// generated returns the GeneratedCommand of obj for core.Options.Generated,
+// unless bo ignores generated code. Code generated by older versions of
+// clive-gen is reported as stale.
+func (bo *BuildOptions) generated(obj interface{}) (*GeneratedCommand, bool, error) {
+	if bo.IgnoreGenerated {
+		return nil, false, nil
+	}
+	gen, ok := obj.(Generated)
+	if !ok {
+		if _, ok := obj.(generatedFlags); ok {
+			return nil, false, &StaleGeneratedError{Type: reflect.TypeOf(obj).Elem().Name()}
+		}
+		return nil, false, nil
+	}
+	command := gen.CliveCommand()
+	if bo.EnvPrefix != "" {
+		// only needed to look for unknown environment variables
+		for _, flag := range gen.CliveFlags(bo) {
+			if df, ok := flag.(cli.DocGenerationFlag); ok {
+				command.Envs = append(command.Envs, df.GetEnvVars()...)
+			}
+		}
+	}
+	return &command, true, nil
+}
+
+type StaleGeneratedError struct {
+	Type string
+	// Field is the flag or positional argument that changed, if known.
+	Field string
+}
+
+func (e *StaleGeneratedError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("generated code for command struct %s is out of date (%s changed), re-run clive-gen", e.Type, e.Field)
+	}
+	return fmt.Sprintf("generated code for command struct %s is out of date, re-run clive-gen", e.Type)
+}
+
+// FieldHash returns the hash clive-gen records for the flag or positional
+// argument name. types and tags hold the type and the cli tag of each struct
+// field leading to it from the command struct, types formatted the way
+// reflect.Type.String does.
+func FieldHash(name string, types, tags []string) string {
+	h := sha256.New()
+	io.WriteString(h, name)
+	for i := range types {
+		fmt.Fprintf(h, "\x00%s\x00%s", types[i], tags[i])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// fieldHash returns the FieldHash of a flag or positional argument of a
+// command struct of type objType.
+func fieldHash(objType reflect.Type, cmdMeta *core.CommandMetadata) string {
+	var types, tags []string
+	for _, field := range core.FieldsAlong(objType, cmdMeta.Accesses) {
+		types = append(types, field.Type.String())
+		tags = append(tags, field.Tag.Get("cli"))
+	}
+	return FieldHash(cmdMeta.Name, types, tags)
+}
+
+// CheckGenerated parses the command struct obj and its subcommands through
+// reflection and makes sure the code generated for them was generated from
+// the flags and positional arguments they declare now: their names, types
+// and tags. Build trusts generated code, call CheckGenerated in a test of
+// the package declaring the command structs:
+//
+//	func TestGenerated(t *testing.T) {
+//		if err := clive.CheckGenerated(&App{}); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+func CheckGenerated(obj interface{}) error {
+	model, err := core.ParseCommand(obj, "", DefaultBuildOptions.options())
+	if err != nil {
+		return err
+	}
+	return checkGeneratedModels(model)
+}
+
+// checkGeneratedModels runs checkGenerated for the command structs with
+// generated code among model and its subcommands.
+func checkGeneratedModels(model *core.CommandModel) error {
+	if model.Foreign {
+		return nil
+	}
+	if _, ok := model.Obj.(generatedFlags); ok {
+		err := checkGenerated(model.ObjType, model.Obj, model.Flags, model.Positionals)
+		if err != nil {
+			return err
+		}
+	}
+	for _, sub := range model.Subcommands {
+		if err := checkGeneratedModels(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGenerated compares the hashes of the generated code of a command
+// struct with those of the flags and positional arguments it declares.
+func checkGenerated(objType reflect.Type, obj interface{}, flags, positionals []core.CommandMetadata) error {
+	gen, ok := obj.(Generated)
+	if !ok {
+		// generated before CliveHashes and CliveCommand
+		return &StaleGeneratedError{Type: objType.Name()}
+	}
+	hashes := gen.CliveHashes()
+	fields := append(append([]core.CommandMetadata{}, flags...), positionals...)
+	for i := range fields {
+		if i >= len(hashes) || hashes[i] != fieldHash(objType, &fields[i]) {
+			return &StaleGeneratedError{Type: objType.Name(), Field: fields[i].Name}
+		}
+	}
+	if len(hashes) != len(fields) {
+		return &StaleGeneratedError{Type: objType.Name()}
+	}
+	return nil
+}
+
+// generatedMetas returns the flags of a command struct with generated code
+// clive handles itself: the required ones and, with NegatableBools, the bool
+// flags defaulting to true, which get a --no-<name> flag.
+func generatedMetas(gen *GeneratedCommand, flags []cli.Flag, bo *BuildOptions) (metas []core.CommandMetadata) {
+	for _, name := range gen.Required {
+		metas = append(metas, core.CommandMetadata{Tag: core.Tag{Name: name, Required: true}})
+	}
+	if !bo.NegatableBools {
+		return
+	}
+	for _, flag := range flags {
+		if f, ok := flag.(*BoolFlag); ok && f.Value {
+			metas = append(metas, core.CommandMetadata{Tag: core.Tag{Name: f.Name, Hidden: f.Hidden, Negatable: true}})
+		}
+	}
+	return
+}
+
+// generatedFlag reports whether flags, the flags of a command struct with
+// generated code, define name.
+func generatedFlag(flags []cli.Flag, name string) bool {
+	for _, flag := range flags {
+		for _, n := range flag.Names() {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bindGenerated binds the command struct gen with generated code, after
+// setting the flags of metas given as --no-<name> to false.
+func bindGenerated(ctx *cli.Context, gen Generated, metas []core.CommandMetadata) error {
+	// urfave/cli counts only the flags given on the command line
+	err := core.CheckNegated(metas, func(name string) bool { return ctx.Count(name) != 0 })
+	for i := 0; err == nil && i < len(metas); i++ {
+		if negated := core.NegatedName(metas[i].Name); metas[i].Negatable && ctx.IsSet(negated) {
+			err = ctx.Set(metas[i].Name, strconv.FormatBool(!ctx.Bool(negated)))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return gen.CliveBind(ctx)
+}
@@ -0,0 +1,55 @@
+package clive
+
+import (
+	"reflect"
+	"strconv"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+	"github.com/urfave/cli/v2"
+)
+
+// bindGlobals sets the fields of the global flags of the ancestors of the
+// command of ctx, and the fields of its flags of the same names, from the
+// innermost context the flags were set in.
+func bindGlobals(ctx *cli.Context, model *core.CommandModel, obj interface{}) error {
+	for i := range model.Globals {
+		global := &model.Globals[i]
+		for _, c := range ctx.Lineage() {
+			negated := global.Negatable && c.IsSet(core.NegatedName(global.Name))
+			if !c.IsSet(global.Name) && !negated {
+				continue
+			}
+			err := bindGlobal(ctx.App.Metadata[global.Path], &global.CommandMetadata, c, negated)
+			if own := model.Flag(global.Name); err == nil && own != nil {
+				err = bindGlobal(obj, own, c, negated)
+			}
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func bindGlobal(obj interface{}, cmdMeta *core.CommandMetadata, c *cli.Context, negated bool) error {
+	objValue := reflect.ValueOf(obj).Elem()
+	field := core.FieldByAccesses(objValue, cmdMeta.Accesses)
+	var (
+		source, value string
+		err           error
+	)
+	if negated {
+		name := core.NegatedName(cmdMeta.Name)
+		source, value = "flag "+name, strconv.FormatBool(c.Bool(name))
+		err = cmdMeta.SetValueFromString(field, strconv.FormatBool(!c.Bool(name)))
+	} else {
+		source, value = FlagSource(c, cmdMeta.Name), RawFlagValue(c, cmdMeta.Name)
+		err = setFromContext(cmdMeta, field, c)
+	}
+	if err != nil {
+		top := objValue.Type().Field(cmdMeta.Accesses[0])
+		return &FieldBindError{Field: top.Name, Type: top.Type.String(), Source: source, Value: value, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package clive
+
+import (
+	"fmt"
+	"strings"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+	"github.com/urfave/cli/v2"
+)
+
+// commandRecord keeps what Build needs to render the help of a command it
+// has built.
+type commandRecord struct {
+	// describe returns the spec of the command, it is only called once its
+	// help is rendered, see Spec.
+	describe func() *CommandSpec
+	spec     *CommandSpec
+	renderer HelpRenderer
+	bo       *BuildOptions
+	// app is set for the record of the root command, template to the
+	// CustomHelpTemplate last rendered for the command
+	app      *cli.App
+	template string
+}
+
+const (
+	cliveRecordKey  = "cliveRecord"
+	cliveRecordsKey = "cliveRecords"
+)
+
+// commandRecords returns the records of the commands clive built for app.
+func commandRecords(app *cli.App) map[*cli.Command]*commandRecord {
+	records, ok := app.Metadata[cliveRecordsKey].(map[*cli.Command]*commandRecord)
+	if !ok {
+		records = map[*cli.Command]*commandRecord{}
+		app.Metadata[cliveRecordsKey] = records
+	}
+	return records
+}
+
+// commandSpecFor returns the spec of a command built by clive for app, or a
+// minimal one for commands constructed by hand (see HasSubcommand).
+func commandSpecFor(app *cli.App, command *cli.Command) *CommandSpec {
+	if record, ok := commandRecords(app)[command]; ok {
+		return record.Spec()
+	}
+	spec := &CommandSpec{
+		Name:        command.Name,
+		Aliases:     command.Aliases,
+		Usage:       command.Usage,
+		Description: command.Description,
+	}
+	for _, flag := range command.Flags {
+		names := flag.Names()
+		if len(names) == 0 {
+			continue
+		}
+		fieldSpec := &FieldSpec{Name: names[0], Aliases: names[1:]}
+		if df, ok := flag.(cli.DocGenerationFlag); ok {
+			fieldSpec.Usage = df.GetUsage()
+			fieldSpec.Envs = df.GetEnvVars()
+			fieldSpec.Type = strings.TrimPrefix(fmt.Sprintf("%T", flag), "*cli.")
+		}
+		spec.Flags = append(spec.Flags, fieldSpec)
+	}
+	for _, sub := range command.Subcommands {
+		spec.Subcommands = append(spec.Subcommands, commandSpecFor(app, sub))
+	}
+	return spec
+}
+
+// Spec returns the spec of the command, describing it on first use.
+func (record *commandRecord) Spec() *CommandSpec {
+	if record.spec == nil {
+		record.spec = record.describe()
+	}
+	return record.spec
+}
+
+// render renders the help of the command through its HelpRenderer.
+func (record *commandRecord) render(app *cli.App, helpName string) (string, error) {
+	spec := *record.Spec()
+	spec.HelpName = helpName
+	b := &core.HelpBuffer{Out: app.Writer}
+	if err := record.renderer.RenderHelp(b, &spec); err != nil {
+		return "", fmt.Errorf("failed to render help of %s: %w", helpName, err)
+	}
+	return b.String(), nil
+}
+
+// Help renders the help of the App, its CustomAppHelpTemplate calls it. When
+// the HelpRenderer fails the error is printed to ErrWriter, followed by the
+// help of urfave/cli.
+func (record *commandRecord) Help() string {
+	help, err := record.render(record.app, record.app.HelpName)
+	if err != nil {
+		fmt.Fprintln(record.app.ErrWriter, err)
+		b := &strings.Builder{}
+		cli.HelpPrinterCustom(b, cli.AppHelpTemplate, record.app, nil)
+		return b.String()
+	}
+	return help
+}
+
+// appHelpTemplate renders the help of the App through the Help method of its
+// record.
+const appHelpTemplate = "{{.Metadata." + cliveRecordKey + ".Help}}"
+
+// renderSubcommandHelp sets the CustomHelpTemplate of the subcommand the
+// command of ctx runs next to its rendered help when its help flag is given.
+// It runs in Before: the help of a subcommand is shown before its own Before
+// runs.
+func renderSubcommandHelp(ctx *cli.Context) {
+	sub := ctx.Command.Command(ctx.Args().First())
+	if sub == nil || !helpRequested(sub, ctx.Args().Tail()) {
+		return
+	}
+	helpName := sub.HelpName
+	if helpName == "" {
+		helpName = strings.TrimSpace(ctx.Command.HelpName + " " + sub.Name)
+	}
+	renderHelp(ctx.App, sub, helpName)
+}
+
+// showSubcommandHelp is cli.ShowSubcommandHelp rendering the help of the
+// command of ctx first.
+func showSubcommandHelp(ctx *cli.Context) error {
+	if ctx.Command != nil {
+		renderHelp(ctx.App, ctx.Command, ctx.Command.HelpName)
+	}
+	return cli.ShowSubcommandHelp(ctx)
+}
+
+// renderHelp sets the CustomHelpTemplate of command to its rendered help, if
+// clive built it. Templates set by hand are left alone. When the HelpRenderer
+// fails the error is printed to ErrWriter and urfave/cli renders the help
+// instead.
+func renderHelp(app *cli.App, command *cli.Command, helpName string) {
+	record, ok := commandRecords(app)[command]
+	if !ok || command.CustomHelpTemplate != record.template {
+		return
+	}
+	help, err := record.render(app, helpName)
+	if err != nil {
+		fmt.Fprintln(app.ErrWriter, err)
+		record.template = ""
+	} else {
+		// a template printing the help as is
+		record.template = fmt.Sprintf("{{%q}}", help)
+	}
+	command.CustomHelpTemplate = record.template
+}
+
+// helpRequested reports whether args, the arguments of command, contain a
+// help flag for it rather than for one of its subcommands.
+func helpRequested(command *cli.Command, args []string) bool {
+	if cli.HelpFlag == nil {
+		return false
+	}
+	for _, arg := range args {
+		if arg == "--" || command.Command(arg) != nil {
+			return false
+		}
+		if name, ok := strings.CutPrefix(arg, "-"); ok {
+			name = strings.TrimPrefix(name, "-")
+			for _, help := range cli.HelpFlag.Names() {
+				if name == help {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package clive
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+	"github.com/hashicorp/go-multierror"
+	"github.com/urfave/cli/v2"
+)
+
+// Provider constructs the values of fields tagged inject, see Provide.
+type Provider struct {
+	fn  reflect.Value
+	out reflect.Type
+	in  []reflect.Type
+}
+
+var errorType = Reflected[error]()
+
+// Provide returns the Provider of the values constructed by fn, a function
+// returning a value or a value and an error. The arguments of fn are other
+// provided values or command structs of the command being run, like the
+// root. It panics if fn is not such a function.
+//
+//	clive.Provide(func(root *App) (*sql.DB, error) { return sql.Open("pgx", root.DSN) })
+func Provide(fn interface{}) Provider {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || (t.NumOut() != 1 && t.NumOut() != 2) || (t.NumOut() == 2 && t.Out(1) != errorType) {
+		panic(fmt.Errorf("provider %s must be a function returning a value, or a value and an error", t))
+	}
+	p := Provider{fn: v, out: t.Out(0)}
+	for i := 0; i < t.NumIn(); i++ {
+		p.in = append(p.in, t.In(i))
+	}
+	return p
+}
+
+// MissingProviderError is returned when no provider constructs the value of
+// a field tagged inject or an argument of a provider.
+type MissingProviderError struct {
+	Type string
+	For  string
+}
+
+func (e *MissingProviderError) Error() string {
+	return fmt.Sprintf("no provider for %s needed by %s", e.Type, e.For)
+}
+
+// DependencyCycleError is returned when providers depend on each other.
+type DependencyCycleError struct {
+	Types []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf("providers depend on each other: %s", strings.Join(e.Types, " -> "))
+}
+
+// providers returns the providers of bo by the type they construct, later
+// ones replace earlier ones.
+func (bo *BuildOptions) providers() map[reflect.Type]Provider {
+	providers := map[reflect.Type]Provider{}
+	for _, p := range bo.Providers {
+		providers[p.out] = p
+	}
+	return providers
+}
+
+// checkInjected checks that the values of the fields tagged inject of model
+// and its subcommands can be constructed. commands are the types of the
+// command structs of the ancestors of model.
+func checkInjected(model *core.CommandModel, providers map[reflect.Type]Provider, commands []reflect.Type) error {
+	if model.Foreign {
+		return nil
+	}
+	commands = append(commands[:len(commands):len(commands)], reflect.PointerTo(model.ObjType))
+	var check func(t reflect.Type, needer string, path []reflect.Type) error
+	check = func(t reflect.Type, needer string, path []reflect.Type) error {
+		for i, seen := range path {
+			if seen == t {
+				var types []string
+				for _, p := range append(path[i:], t) {
+					types = append(types, p.String())
+				}
+				return &DependencyCycleError{types}
+			}
+		}
+		p, ok := providers[t]
+		if !ok {
+			for _, command := range commands {
+				if command == t {
+					return nil
+				}
+			}
+			return &MissingProviderError{t.String(), needer}
+		}
+		for _, in := range p.in {
+			err := check(in, "provider of "+t.String(), append(path, t))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, i := range model.Injected {
+		field := model.ObjType.Field(i)
+		err := check(field.Type, "field "+model.ObjType.Name()+"."+field.Name, nil)
+		if err != nil {
+			return err
+		}
+	}
+	for _, sub := range model.Subcommands {
+		if err := checkInjected(sub, providers, commands); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injector constructs provided values for a run of an App, each at most
+// once.
+type injector struct {
+	providers map[reflect.Type]Provider
+	values    map[reflect.Type]reflect.Value
+	// created lists the values in the order they were constructed in, with
+	// the path of the command whose After closes them.
+	created []injected
+}
+
+type injected struct {
+	path  string
+	typ   reflect.Type
+	value reflect.Value
+}
+
+const cliveInjectorKey = "cliveInjector"
+
+// inject sets the fields tagged inject of the command struct obj of model,
+// constructing the values they need.
+func inject(ctx *cli.Context, model *core.CommandModel, obj interface{}, bo *BuildOptions) error {
+	inj, ok := ctx.App.Metadata[cliveInjectorKey].(*injector)
+	if !ok || model.ParentPath == "" {
+		// the Before of the root starts every run
+		inj = &injector{providers: bo.providers(), values: map[reflect.Type]reflect.Value{}}
+		ctx.App.Metadata[cliveInjectorKey] = inj
+	}
+	objValue := reflect.ValueOf(obj).Elem()
+	for _, i := range model.Injected {
+		value, err := inj.value(ctx, objValue.Type().Field(i).Type, model.Path)
+		if err != nil {
+			return fmt.Errorf("failed to inject field %s: %w", objValue.Type().Field(i).Name, err)
+		}
+		objValue.Field(i).Set(value)
+	}
+	return nil
+}
+
+// value returns the value of type t, constructing it and what it depends on
+// if needed.
+func (inj *injector) value(ctx *cli.Context, t reflect.Type, path string) (reflect.Value, error) {
+	if v, ok := inj.values[t]; ok {
+		return v, nil
+	}
+	p, ok := inj.providers[t]
+	if !ok {
+		// command structs, checkInjected made sure they are ancestors
+		for _, v := range ctx.App.Metadata {
+			if reflect.TypeOf(v) == t {
+				return reflect.ValueOf(v), nil
+			}
+		}
+		return reflect.Value{}, &MissingProviderError{t.String(), path}
+	}
+	args := make([]reflect.Value, len(p.in))
+	for i, in := range p.in {
+		arg, err := inj.value(ctx, in, path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = arg
+	}
+	out := p.fn.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, out[1].Interface().(error)
+	}
+	inj.values[t] = out[0]
+	inj.created = append(inj.created, injected{path, t, out[0]})
+	return out[0], nil
+}
+
+// close closes the values constructed for the command path implementing
+// io.Closer, in reverse order.
+func (inj *injector) close(path string) (err error) {
+	for i := len(inj.created) - 1; i >= 0; i-- {
+		created := inj.created[i]
+		if created.path != path {
+			continue
+		}
+		inj.created = append(inj.created[:i], inj.created[i+1:]...)
+		delete(inj.values, created.typ)
+		if closer, ok := created.value.Interface().(io.Closer); ok {
+			if cerr := closer.Close(); cerr != nil {
+				err = multierror.Append(err, cerr)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,31 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package clive
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// access checks that the file at path can be accessed with mode by opening
+// it. Directories are only checked on unix.
+func access(path string, mode uint32) error {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return err
+	}
+	flag := os.O_RDONLY
+	if mode&accessWrite != 0 {
+		flag = os.O_WRONLY
+	}
+	f, err := os.OpenFile(path, flag, 0)
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
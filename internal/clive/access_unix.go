@@ -0,0 +1,11 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package clive
+
+import "syscall"
+
+// access checks that the file at path can be accessed with mode, see
+// access(2).
+func access(path string, mode uint32) error {
+	return syscall.Access(path, mode)
+}
@@ -0,0 +1,624 @@
+package clive
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindValue sets the fields of obj from positional arguments, flags are set
+// with setFlag, which returns where the value of the flag came from and the
+// raw value it set the flag from.
+func BindValue(obj reflect.Value, objType reflect.Type, args []string, bo *Options, setFlag func(cmdMeta *CommandMetadata, field reflect.Value) (string, string, error)) error {
+	hadPositionals := false
+	for i := 1; i < objType.NumField(); i++ {
+		fieldType := objType.Field(i)
+		if fieldType.Name == "Subcommands" || (isRunField(fieldType)) {
+			continue
+		}
+		var flieldMetadata []CommandMetadata
+		err := parseFieldOrPositional(nil, []int{i}, fieldType, &flieldMetadata, &flieldMetadata, bo)
+		if err != nil {
+			return err
+		}
+		for _, cmdMeta := range flieldMetadata {
+			if cmdMeta.Skipped {
+				continue
+			}
+			currentField := FieldByAccesses(obj, cmdMeta.Accesses)
+			if !currentField.IsValid() {
+				// the field of a variant that isn't selected
+				continue
+			}
+			var source, value string
+			if cmdMeta.Positional {
+				hadPositionals = true
+				source = "positional argument " + toScreamingSnake(cmdMeta.Name)
+				if len(args) == 0 {
+					if cmdMeta.Required {
+						return &TooFewArgumentsError{Name: toScreamingSnake(cmdMeta.Name)}
+					}
+					if cmdMeta.Default != nil {
+						source, value = "default value of "+source, *cmdMeta.Default
+						err = cmdMeta.SetFromString(currentField, *cmdMeta.Default)
+					}
+				} else {
+					if cmdMeta.IsVariadic() {
+						value = strings.Join(args, " ")
+						err = cmdMeta.SetValueFromStrings(currentField, args)
+						args = []string{}
+					} else {
+						value = args[0]
+						err = cmdMeta.SetValueFromString(currentField, args[0])
+						args = args[1:]
+					}
+				}
+			} else {
+				source, value, err = setFlag(&cmdMeta, currentField)
+			}
+			if err != nil {
+				bindErr := &FieldBindError{Field: fieldType.Name, Type: fieldType.Type.String(), Source: source, Value: value, Err: err}
+				if _, ok := cmdMeta.TypeInterface.(*VariantType); ok {
+					bindErr.Suggestions = Suggest(value, cmdMeta.Variants)
+				}
+				return bindErr
+			}
+		}
+	}
+	if hadPositionals && len(args) > 0 {
+		return &TooManyArgumentsError{Args: args}
+	}
+	return nil
+}
+
+// FlagValues gives clive access to flags parsed by another command line
+// library.
+type FlagValues interface {
+	// FlagValues returns the values given for the flag name and whether it
+	// was set at all. Values of variadic flags may be repeated or comma
+	// separated.
+	FlagValues(name string) ([]string, bool)
+}
+
+// Object returns the command struct the command was built from.
+func (spec *CommandSpec) Object() interface{} {
+	if spec.record == nil {
+		return nil
+	}
+	return spec.record.obj
+}
+
+// ValueType returns the Go type of the flag or positional argument field with
+// pointers removed, nil if field is not one of the command.
+func (spec *CommandSpec) ValueType(field *FieldSpec) reflect.Type {
+	if spec.record == nil {
+		return nil
+	}
+	for _, group := range [][]CommandMetadata{spec.record.flags, spec.record.positionals} {
+		for i := range group {
+			if group[i].Name != field.Name || group[i].Positional != field.Positional {
+				continue
+			}
+			t := group[i].FieldType
+			for t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+			return t
+		}
+	}
+	return nil
+}
+
+// UnsupportedError is returned by CheckPortable for features of a command
+// only Build implements.
+type UnsupportedError struct {
+	Command string
+	Feature string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("command %q uses %s, which only clive.Build supports", e.Command, e.Feature)
+}
+
+// CheckPortable makes sure the command and its subcommands don't use
+// features only clive.Build implements: global flags and fields tagged
+// inject. Adapters for other command line libraries call it before building
+// their commands.
+func (spec *CommandSpec) CheckPortable() error {
+	if spec.record == nil {
+		return fmt.Errorf("command %q was not built by clive", spec.Path)
+	}
+	unsupported := func(feature string) error {
+		return &UnsupportedError{Command: spec.DisplayName(), Feature: feature}
+	}
+	if spec.record.injected {
+		return unsupported("fields tagged inject")
+	}
+	for _, flag := range spec.Flags {
+		if flag.Global {
+			return unsupported("global flag " + flag.Name)
+		}
+	}
+	for _, sub := range spec.Subcommands {
+		if err := sub.CheckPortable(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DisplayName returns the path of the command, or its name for the root
+// command.
+func (spec *CommandSpec) DisplayName() string {
+	if spec.Path == "" {
+		return spec.Name
+	}
+	return spec.Path
+}
+
+// Bind sets the fields of the command struct from flags and positional
+// arguments parsed by another command line library, the same way commands
+// built by Build do. Flags that weren't given are looked up in their
+// environment variables, then fall back to their defaults.
+func (spec *CommandSpec) Bind(values FlagValues, args []string) error {
+	if spec.record == nil {
+		return fmt.Errorf("command %q was not built by clive", spec.Path)
+	}
+	// lookup returns the values given for a flag and the environment
+	// variable they were read from, if any
+	lookup := func(cmdMeta *CommandMetadata) ([]string, string, bool) {
+		if cmdMeta.Negatable {
+			if given, ok := values.FlagValues(NegatedName(cmdMeta.Name)); ok && len(given) != 0 {
+				negated, err := ParseBool(given[len(given)-1])
+				if err != nil {
+					return given, "", true
+				}
+				return []string{strconv.FormatBool(!negated)}, "", true
+			}
+		}
+		if given, ok := values.FlagValues(cmdMeta.Name); ok {
+			return given, "", true
+		}
+		for _, env := range cmdMeta.Envs {
+			if value, ok := os.LookupEnv(env); ok {
+				// an empty value is false, like urfave/cli has it
+				if value == "" && cmdMeta.ValueType().Kind() == reflect.Bool {
+					value = "false"
+				}
+				return []string{value}, env, true
+			}
+		}
+		return nil, "", false
+	}
+
+	err := CheckNegated(spec.record.flags, func(name string) bool {
+		given, ok := values.FlagValues(name)
+		return ok && len(given) != 0
+	})
+	if err != nil {
+		return err
+	}
+
+	err = CheckRequired(spec.record.flags, func(cmdMeta *CommandMetadata) bool {
+		_, _, ok := lookup(cmdMeta)
+		return ok
+	})
+	if err != nil {
+		return err
+	}
+
+	groups := NilGroups(spec.record.obj, spec.record.flags)
+	objValue := reflect.ValueOf(spec.record.obj).Elem()
+	err = BindValue(objValue, objValue.Type(), args, spec.record.bo, func(cmdMeta *CommandMetadata, field reflect.Value) (string, string, error) {
+		given, env, ok := lookup(cmdMeta)
+		source := "flag " + cmdMeta.Name
+		switch {
+		case ok && cmdMeta.IsVariadic() && env != "":
+			source = "environment variable " + env
+			items, err := SplitEnv(given[0], cmdMeta.Sep, cmdMeta.EnvFormat)
+			if err != nil {
+				return source, given[0], err
+			}
+			return source, given[0], cmdMeta.SetValueFromStrings(field, items)
+		case ok && cmdMeta.IsVariadic():
+			var items []string
+			for _, value := range given {
+				split, err := cmdMeta.Split(value)
+				if err != nil {
+					return source, value, err
+				}
+				items = append(items, split...)
+			}
+			return source, strings.Join(given, ","), cmdMeta.SetValueFromStrings(field, items)
+		case ok && len(given) != 0:
+			if env != "" {
+				source = "environment variable " + env
+			}
+			return source, given[len(given)-1], cmdMeta.SetValueFromString(field, given[len(given)-1])
+		case cmdMeta.Default != nil:
+			return "default value of " + source, *cmdMeta.Default, cmdMeta.SetFromString(field, *cmdMeta.Default)
+		}
+		return source, "", nil
+	})
+	if err != nil {
+		return err
+	}
+	err = BindRepeated(spec.record.obj, spec.record.flags, func(group []*CommandMetadata) []Occurrence {
+		return givenInOrder(values, group)
+	})
+	if err != nil {
+		return err
+	}
+	err = CheckVariants(spec.record.obj, spec.record.flags, func(cmdMeta *CommandMetadata) (bool, bool) {
+		_, env, ok := lookup(cmdMeta)
+		return ok, env != ""
+	})
+	if err != nil {
+		return err
+	}
+	ResetGroups(spec.record.obj, groups, spec.record.flags, func(cmdMeta *CommandMetadata) bool {
+		_, _, ok := lookup(cmdMeta)
+		return ok
+	})
+	return nil
+}
+
+// NegatedFlagError is returned when a negatable flag is given on the command
+// line together with its --no-<name> counterpart.
+type NegatedFlagError struct {
+	Name string
+}
+
+func (e *NegatedFlagError) Error() string {
+	return fmt.Sprintf("flags --%s and --%s can't be given together", e.Name, NegatedName(e.Name))
+}
+
+// CheckNegated returns a NegatedFlagError for the first negatable flag given
+// together with its --no-<name> flag, given reports whether a flag was given
+// on the command line.
+func CheckNegated(flags []CommandMetadata, given func(name string) bool) error {
+	for i := range flags {
+		cmdMeta := &flags[i]
+		if cmdMeta.Negatable && given(cmdMeta.Name) && given(NegatedName(cmdMeta.Name)) {
+			return &NegatedFlagError{Name: cmdMeta.Name}
+		}
+	}
+	return nil
+}
+
+// BindNegated sets negatable flags given as --no-<name> to false, negated
+// returns the value of a flag and whether it was given.
+func BindNegated(obj interface{}, flags []CommandMetadata, negated func(name string) (bool, bool)) error {
+	objValue := reflect.ValueOf(obj).Elem()
+	for i := range flags {
+		cmdMeta := &flags[i]
+		if !cmdMeta.Negatable {
+			continue
+		}
+		value, ok := negated(NegatedName(cmdMeta.Name))
+		if !ok {
+			continue
+		}
+		field := FieldByAccesses(objValue, cmdMeta.Accesses)
+		if !field.IsValid() {
+			continue
+		}
+		err := cmdMeta.SetValueFromString(field, strconv.FormatBool(!value))
+		if err != nil {
+			return fmt.Errorf("failed to set field %s from flag %s: %s", cmdMeta.Name, NegatedName(cmdMeta.Name), err.Error())
+		}
+	}
+	return nil
+}
+
+// CheckRequired returns a RequiredFlagsError listing the required flags
+// among flags that set reports as not given. Required flags of variants are
+// checked once one is selected, see CheckVariants, those of repeated groups
+// for every element.
+func CheckRequired(flags []CommandMetadata, set func(cmdMeta *CommandMetadata) bool) error {
+	var missing []string
+	for i := range flags {
+		cmdMeta := &flags[i]
+		if cmdMeta.Required && cmdMeta.Variant == nil && cmdMeta.Repeated == nil && !set(cmdMeta) {
+			missing = append(missing, cmdMeta.Name)
+		}
+	}
+	if len(missing) != 0 {
+		return &RequiredFlagsError{missing}
+	}
+	return nil
+}
+
+type RequiredFlagsError struct {
+	Names []string
+}
+
+func (e *RequiredFlagsError) Error() string {
+	if len(e.Names) == 1 {
+		return fmt.Sprintf("Required flag %q not set", e.Names[0])
+	}
+	return fmt.Sprintf("Required flags %q not set", strings.Join(e.Names, ", "))
+}
+
+// TooFewArgumentsError is returned when a required positional argument is
+// missing.
+type TooFewArgumentsError struct {
+	Name string
+}
+
+func (e *TooFewArgumentsError) Error() string {
+	return fmt.Sprintf("too few arguments: %s is required", e.Name)
+}
+
+// TooManyArgumentsError is returned when arguments are left after the
+// positional arguments of a command.
+type TooManyArgumentsError struct {
+	Args []string
+}
+
+func (e *TooManyArgumentsError) Error() string {
+	return fmt.Sprintf("too many arguments: %d left unparsed: %s", len(e.Args), strings.Join(e.Args, " "))
+}
+
+// FieldBindError is returned when a field of a command struct can't be set
+// from the value given for it.
+type FieldBindError struct {
+	// Field and Type are the name and the type of the field of the command
+	// struct, the inline group for fields of inline groups.
+	Field string
+	Type  string
+	// Source tells where Value came from, like "flag port", "environment
+	// variable PORT", "default value of flag port" or "positional argument
+	// NAME".
+	Source string
+	Value  string
+	Err    error
+	// Suggestions are the variants closest to Value, for fields selecting a
+	// variant registered with RegisterVariant.
+	Suggestions []string
+}
+
+func (e *FieldBindError) Error() string {
+	return fmt.Sprintf("failed to set field %s (type %s) from %s: %s%s", e.Field, e.Type, e.Source, e.Err.Error(), didYouMean(e.Suggestions))
+}
+
+func (e *FieldBindError) Unwrap() error {
+	return e.Err
+}
+
+// RawValues collects the values of the flags of a command as they are given
+// on the command line, for command line libraries that clive has no flag
+// types for. Register the Value of every flag with the library, then pass
+// RawValues to CommandSpec.Bind.
+type RawValues struct {
+	values map[string]*RawValue
+	order  []string
+}
+
+// NewRawValues returns RawValues for the flags of spec.
+func NewRawValues(spec *CommandSpec) *RawValues {
+	rv := &RawValues{values: map[string]*RawValue{}}
+	if spec.record == nil {
+		return rv
+	}
+	for i := range spec.record.flags {
+		cmdMeta := &spec.record.flags[i]
+		value := &RawValue{name: cmdMeta.Name, order: &rv.order, typeName: "string", defaultValue: cmdMeta.Default}
+		t := cmdMeta.FieldType
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch {
+		case t == Reflected[Counter]():
+			value.typeName, value.counter = "count", true
+		case t.Kind() == reflect.Bool:
+			value.typeName = "bool"
+		case cmdMeta.IsVariadic() && t.Kind() == reflect.Slice:
+			value.typeName = rawTypeName(t.Elem()) + "s"
+		case cmdMeta.IsVariadic():
+			value.typeName = "strings"
+		default:
+			value.typeName = rawTypeName(t)
+		}
+		rv.values[cmdMeta.Name] = value
+		if cmdMeta.Negatable {
+			rv.values[NegatedName(cmdMeta.Name)] = &RawValue{name: NegatedName(cmdMeta.Name), order: &rv.order, typeName: "bool"}
+		}
+	}
+	return rv
+}
+
+// rawTypeName returns the name of the type t for the help of command line
+// libraries, the way the pflag package names its types. Types parsing text
+// themselves are strings.
+func rawTypeName(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return "duration"
+	}
+	if t.PkgPath() != "" {
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+// Value returns the value of the flag name, nil if there is no such flag.
+func (rv *RawValues) Value(name string) *RawValue {
+	return rv.values[name]
+}
+
+func (rv *RawValues) FlagValues(name string) ([]string, bool) {
+	value, ok := rv.values[name]
+	if !ok || len(value.values) == 0 {
+		return nil, false
+	}
+	if value.counter {
+		return []string{strconv.Itoa(len(value.values))}, true
+	}
+	return value.values, true
+}
+
+// FlagOrder returns the names of the flags in the order they were given.
+func (rv *RawValues) FlagOrder() []string {
+	return append([]string{}, rv.order...)
+}
+
+// Reset forgets the values collected so far.
+func (rv *RawValues) Reset() {
+	for _, value := range rv.values {
+		value.values = nil
+	}
+	rv.order = nil
+}
+
+// RawValue implements flag.Value and pflag.Value, it keeps every value it is
+// set to.
+type RawValue struct {
+	name         string
+	order        *[]string
+	typeName     string
+	counter      bool
+	defaultValue *string
+	values       []string
+}
+
+func (v *RawValue) Set(s string) error {
+	v.values = append(v.values, s)
+	if v.order != nil {
+		*v.order = append(*v.order, v.name)
+	}
+	return nil
+}
+
+func (v *RawValue) String() string {
+	if v == nil {
+		return ""
+	}
+	if len(v.values) == 0 && v.defaultValue != nil {
+		return *v.defaultValue
+	}
+	return strings.Join(v.values, ",")
+}
+
+func (v *RawValue) Get() any {
+	return v.values
+}
+
+// Type returns the name of the type of the flag: bool, count, duration, int,
+// uint, float or string, with an s appended for lists.
+func (v *RawValue) Type() string {
+	return v.typeName
+}
+
+// IsBoolFlag reports whether the flag is set without a value, like bool
+// flags and counters.
+func (v *RawValue) IsBoolFlag() bool {
+	return v.counter || v.typeName == "bool"
+}
+
+// FieldByAccesses returns a pointer to the field of the addressable struct obj
+// found by following accesses through inline groups and variants, nil pointer
+// inline groups on the way are allocated. It returns the zero Value for fields
+// of variants that aren't selected and for fields of repeated groups, which
+// are set by BindRepeated.
+func FieldByAccesses(obj reflect.Value, accesses []int) reflect.Value {
+	return followAccesses(obj, accesses, true)
+}
+
+// existingField is FieldByAccesses without allocating, it returns the zero
+// Value for fields of nil pointer inline groups too.
+func existingField(obj reflect.Value, accesses []int) reflect.Value {
+	return followAccesses(obj, accesses, false)
+}
+
+func followAccesses(obj reflect.Value, accesses []int, allocate bool) reflect.Value {
+	currentObj := obj.Addr()
+	var currentField reflect.Value
+	for accessIndex, fieldIndex := range accesses {
+		if accessIndex > 0 {
+			currentObj = currentField
+		}
+		if group := currentObj.Elem(); group.Kind() == reflect.Pointer {
+			// a pointer inline group
+			if group.IsNil() {
+				if !allocate {
+					return reflect.Value{}
+				}
+				group.Set(reflect.New(group.Type().Elem()))
+			}
+			currentObj = group
+		}
+		if fieldIndex < 0 {
+			// currentObj points to an interface field holding the variant
+			iface := currentObj.Elem()
+			v := variantTypeOf(iface.Type()).variants[variantIndex(fieldIndex)]
+			if iface.IsNil() || iface.Elem().Type() != reflect.PointerTo(v.typ) {
+				return reflect.Value{}
+			}
+			currentField = iface.Elem()
+			continue
+		}
+		if currentObj.Elem().Kind() == reflect.Slice {
+			return reflect.Value{}
+		}
+		currentField = currentObj.Elem().Field(fieldIndex).Addr()
+	}
+	return currentField
+}
+
+// NilGroups returns the accesses of the pointer inline groups of flags that
+// are nil in obj.
+func NilGroups(obj interface{}, flags []CommandMetadata) (groups [][]int) {
+	objValue := reflect.ValueOf(obj).Elem()
+	seen := map[string]bool{}
+	for i := range flags {
+		for _, accesses := range flags[i].optional {
+			key := fmt.Sprint(accesses)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if field := existingField(objValue, accesses); !field.IsValid() || field.Elem().IsNil() {
+				groups = append(groups, accesses)
+			}
+		}
+	}
+	return
+}
+
+// ResetGroups sets the pointer inline groups that were nil before binding
+// back to nil unless one of their flags was set, set reports whether it was.
+func ResetGroups(obj interface{}, groups [][]int, flags []CommandMetadata, set func(cmdMeta *CommandMetadata) bool) {
+	objValue := reflect.ValueOf(obj).Elem()
+	used := map[string]bool{}
+	for i := range flags {
+		if len(flags[i].optional) == 0 || !set(&flags[i]) {
+			continue
+		}
+		for _, accesses := range flags[i].optional {
+			used[fmt.Sprint(accesses)] = true
+		}
+	}
+	// outer groups first, inner ones are gone with them
+	for _, accesses := range groups {
+		if used[fmt.Sprint(accesses)] {
+			continue
+		}
+		if field := existingField(objValue, accesses); field.IsValid() {
+			field.Elem().Set(reflect.Zero(field.Elem().Type()))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package clive
+
+import "strings"
+
+// toScreamingSnake converts a field name to SCREAMING_SNAKE_CASE, the names
+// of positional arguments and environment variables.
+func toScreamingSnake(s string) string {
+	return toDelimited(s, '_', true)
+}
+
+// toKebab converts a field name to kebab-case, the names of flags.
+func toKebab(s string) string {
+	return toDelimited(s, '-', false)
+}
+
+// toDelimited splits s into words at changes of case and between letters and
+// digits, keeping acronyms together: JSONData becomes JSON and Data. Spaces,
+// underscores, hyphens and dots become delimiter. It converts names the way
+// github.com/iancoleman/strcase does.
+func toDelimited(s string, delimiter byte, screaming bool) string {
+	s = strings.TrimSpace(s)
+	n := strings.Builder{}
+	n.Grow(len(s) + 2)
+	for i, v := range []byte(s) {
+		vIsCap := v >= 'A' && v <= 'Z'
+		vIsLow := v >= 'a' && v <= 'z'
+		if vIsLow && screaming {
+			v -= 'a' - 'A'
+		} else if vIsCap && !screaming {
+			v += 'a' - 'A'
+		}
+		if i+1 < len(s) {
+			next := s[i+1]
+			vIsNum := v >= '0' && v <= '9'
+			nextIsCap := next >= 'A' && next <= 'Z'
+			nextIsLow := next >= 'a' && next <= 'z'
+			nextIsNum := next >= '0' && next <= '9'
+			if (vIsCap && (nextIsLow || nextIsNum)) || (vIsLow && (nextIsCap || nextIsNum)) || (vIsNum && (nextIsCap || nextIsLow)) {
+				if prevIsCap := i > 0 && s[i-1] >= 'A' && s[i-1] <= 'Z'; vIsCap && nextIsLow && prevIsCap {
+					n.WriteByte(delimiter)
+				}
+				n.WriteByte(v)
+				if vIsLow || vIsNum || nextIsNum {
+					n.WriteByte(delimiter)
+				}
+				continue
+			}
+		}
+		if v == ' ' || v == '_' || v == '-' || v == '.' {
+			n.WriteByte(delimiter)
+		} else {
+			n.WriteByte(v)
+		}
+	}
+	return n.String()
+}
@@ -0,0 +1,1007 @@
+package clive
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Counter struct {
+	Value int
+}
+
+type TypePredicate interface {
+	Predicate(reflect.Type) bool
+}
+
+// TypeFunctions set fields from strings, and from values parsed by a command
+// line library: ValueType is the type the library holds values of the field
+// in, SetValue sets the field from one of them. The libraries map value types
+// to their flags, see the adapters of clive.
+type TypeFunctions interface {
+	SetValueFromString(val reflect.Value, s string) (err error)
+	IsVariadic() bool
+	SetValueFromStrings(val reflect.Value, s []string) (err error)
+	ValueType() reflect.Type
+	SetValue(val, v reflect.Value) (err error)
+}
+
+type TypeInterface interface {
+	TypePredicate
+	TypeFunctions
+}
+
+type (
+	predicateHandler           func(fType reflect.Type) bool
+	setValueFromStringHandler  func(val reflect.Value, s string) (err error)
+	setValueFromStringsHandler func(val reflect.Value, s []string) (err error)
+)
+
+func Reflected[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func typePredicate[T any](fType reflect.Type) bool {
+	return fType == Reflected[T]()
+}
+
+func checkType[T any](fType reflect.Type) {
+	expected := Reflected[T]()
+	if fType != expected {
+		panic(fmt.Errorf("wrong type: %s, expected: %s", fType.String(), expected.String()))
+	}
+}
+
+func bits[T any]() int {
+	rv := reflect.TypeOf((*T)(nil)).Elem()
+	// if rv.Kind() == reflect.Int || rv.Kind() == reflect.Uint {
+	// 	return 32
+	// }
+	return rv.Bits()
+}
+
+func ConvertSlice[U, T any](ret *[]U, ts []T, conv func(*U, T) error) (err error) {
+	*ret = make([]U, len(ts))
+	for i, v := range ts {
+		err = conv(&(*ret)[i], v)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func parseInt[T int8 | int16 | int32](ret *T, s string) error {
+	v, err := strconv.ParseInt(s, 0, bits[T]())
+	if err != nil {
+		return err
+	}
+	*ret = T(v)
+	return nil
+}
+
+func parseUint[T uint8 | uint16 | uint32](ret *T, s string) error {
+	v, err := strconv.ParseUint(s, 0, bits[T]())
+	if err != nil {
+		return err
+	}
+	*ret = T(v)
+	return nil
+}
+
+func parseStandartTypes[T any](ret *T, s string) (err error) {
+	if isVariadic[T]() {
+		var items []string
+		items, err = SplitList(s, DefaultSep)
+		if err == nil {
+			err = parseStandartSliceTypes[T](ret, items)
+		}
+		return
+	}
+	switch rv := interface{}(ret).(type) {
+	case *int:
+		var def int64
+		def, err = strconv.ParseInt(s, 0, bits[T]())
+		if err != nil {
+			return
+		}
+		*rv = int(def)
+	case *int64:
+		*rv, err = strconv.ParseInt(s, 0, bits[T]())
+	case *uint:
+		var def uint64
+		def, err = strconv.ParseUint(s, 0, bits[T]())
+		if err != nil {
+			return
+		}
+		*rv = uint(def)
+	case *uint64:
+		*rv, err = strconv.ParseUint(s, 0, bits[T]())
+	case *int8:
+		err = parseInt(rv, s)
+	case *int16:
+		err = parseInt(rv, s)
+	case *int32:
+		err = parseInt(rv, s)
+	case *uint8:
+		err = parseUint(rv, s)
+	case *uint16:
+		err = parseUint(rv, s)
+	case *uint32:
+		err = parseUint(rv, s)
+	case *float32:
+		var def float64
+		def, err = strconv.ParseFloat(s, bits[T]())
+		if err != nil {
+			return
+		}
+		*rv = float32(def)
+	case *float64:
+		*rv, err = strconv.ParseFloat(s, bits[T]())
+	case *string:
+		*rv = s
+	case *time.Duration:
+		*rv, err = time.ParseDuration(s)
+	case *bool:
+		*rv, err = ParseBool(s)
+	case *Counter:
+		var def int64
+		def, err = strconv.ParseInt(s, 0, bits[int]())
+		if err != nil {
+			return
+		}
+		rv.Value = int(def)
+	// case encoding.TextUnmarshaler:
+	// 	err = rv.UnmarshalText([]byte(s))
+
+	// case *[]encoding.TextUnmarshaler:
+	// 	*rv, err = parseSlice[[]encoding.TextUnmarshaler](strings.Split(s, ","))
+	default:
+		err = fmt.Errorf("unexpected type in  parseStandartTypes %s", reflect.TypeOf((*T)(nil)).Elem().String())
+	}
+	return
+}
+
+// ParseBool accepts yes/no, y/n and on/off in any case on top of what
+// strconv.ParseBool does.
+func ParseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+func parseSlice[T []U, U any](ret *[]U, s []string) (err error) {
+	return ConvertSlice[U, string](ret, s, parseStandartTypes[U])
+}
+
+func parseStandartSliceTypes[T any](ret *T, s []string) (err error) {
+	switch rv := interface{}(ret).(type) {
+	case *[]int:
+		err = parseSlice[[]int](rv, s)
+	case *[]int64:
+		err = parseSlice[[]int64](rv, s)
+	case *[]uint:
+		err = parseSlice[[]uint](rv, s)
+	case *[]uint64:
+		err = parseSlice[[]uint64](rv, s)
+	case *[]int8:
+		err = parseSlice[[]int8](rv, s)
+	case *[]int16:
+		err = parseSlice[[]int16](rv, s)
+	case *[]int32:
+		err = parseSlice[[]int32](rv, s)
+	case *[]uint8:
+		err = parseSlice[[]uint8](rv, s)
+	case *[]uint16:
+		err = parseSlice[[]uint16](rv, s)
+	case *[]uint32:
+		err = parseSlice[[]uint32](rv, s)
+	case *[]float32:
+		err = parseSlice[[]float32](rv, s)
+	case *[]float64:
+		err = parseSlice[[]float64](rv, s)
+	case *[]string:
+		err = parseSlice[[]string](rv, s)
+	case *[]time.Duration:
+		err = parseSlice[[]time.Duration](rv, s)
+	case *[]bool:
+		err = parseSlice[[]bool](rv, s)
+	default:
+		err = fmt.Errorf("unexpected type in parseStandartSliceTypes %s", reflect.TypeOf((*T)(nil)).Elem().String())
+	}
+	return
+}
+
+func isVariadic[T any]() bool {
+	return Reflected[T]().Kind() == reflect.Slice
+}
+
+func setValueFromString[T any](val reflect.Value, s string) (err error) {
+	checkType[*T](val.Type())
+	err = parseStandartTypes[T](val.Interface().(*T), s)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func setValueFromStrings[T any](val reflect.Value, s []string) (err error) {
+	checkType[*T](val.Type())
+	err = parseStandartSliceTypes[T](val.Interface().(*T), s)
+	if err != nil {
+		return
+	}
+	return
+}
+
+type StandardType struct {
+	predicate           predicateHandler
+	setValueFromString  setValueFromStringHandler
+	setValueFromStrings setValueFromStringsHandler
+	valueType           reflect.Type
+}
+
+func NewStandardType[T any]() *StandardType {
+	var variadic setValueFromStringsHandler
+	if isVariadic[T]() {
+		variadic = setValueFromStrings[T]
+	}
+	return &StandardType{
+		predicate:           typePredicate[T],
+		setValueFromString:  setValueFromString[T],
+		setValueFromStrings: variadic,
+		valueType:           Reflected[T](),
+	}
+}
+
+func (nt *StandardType) Predicate(fType reflect.Type) bool {
+	return nt.predicate(fType)
+}
+
+func (nt *StandardType) SetValueFromString(val reflect.Value, s string) (err error) {
+	return nt.setValueFromString(val, s)
+}
+
+func (nt *StandardType) ValueType() reflect.Type {
+	return nt.valueType
+}
+
+func (nt *StandardType) SetValue(val, v reflect.Value) error {
+	if val.Type() != reflect.PointerTo(nt.valueType) || v.Type() != nt.valueType {
+		panic(fmt.Errorf("wrong type: %s from %s, expected: %s", val.Type(), v.Type(), nt.valueType))
+	}
+	val.Elem().Set(v)
+	return nil
+}
+
+func (nt *StandardType) IsVariadic() bool {
+	return nt.setValueFromStrings != nil
+}
+
+func (nt *StandardType) SetValueFromStrings(val reflect.Value, s []string) (err error) {
+	return nt.setValueFromStrings(val, s)
+}
+
+type InterfaceType struct {
+	interfaceType reflect.Type
+	under         TypeInterface
+	underType     reflect.Type
+
+	convert func(convertInto, fromUnderType reflect.Value) error
+}
+
+func (ifaceType *InterfaceType) Predicate(fType reflect.Type) bool {
+	if ifaceType.IsVariadic() {
+		if fType.Kind() != reflect.Slice {
+			return false
+		}
+		fType = fType.Elem()
+	}
+	return reflect.PointerTo(fType).Implements(ifaceType.interfaceType)
+}
+
+func (ifaceType *InterfaceType) ValueType() reflect.Type {
+	return ifaceType.under.ValueType()
+}
+
+func (ifaceType *InterfaceType) SetValueFromString(value reflect.Value, s string) (err error) {
+	if value.Kind() != reflect.Ptr {
+		err = fmt.Errorf("expected pointer, got %s", value.Type().String())
+		return
+	}
+	underVal := reflect.New(ifaceType.underType)
+	err = ifaceType.under.SetValueFromString(underVal, s)
+	if err != nil {
+		return
+	}
+	return ifaceType.convert(value, underVal)
+}
+
+func (ifaceType *InterfaceType) SetValue(value, v reflect.Value) (err error) {
+	if value.Kind() != reflect.Ptr {
+		err = fmt.Errorf("expected pointer, got %s", value.Type().String())
+		return
+	}
+	underVal := reflect.New(ifaceType.underType)
+	err = ifaceType.under.SetValue(underVal, v)
+	if err != nil {
+		return
+	}
+	return ifaceType.convert(value, underVal)
+}
+func (ifaceType *InterfaceType) IsVariadic() bool { return ifaceType.under.IsVariadic() }
+func (ifaceType *InterfaceType) SetValueFromStrings(value reflect.Value, s []string) (err error) {
+	if value.Kind() != reflect.Ptr {
+		err = fmt.Errorf("expected pointer, got %s", value.Type().String())
+		return
+	}
+	underVal := reflect.New(ifaceType.underType)
+	err = ifaceType.under.SetValueFromStrings(underVal, s)
+	if err != nil {
+		return
+	}
+	return ifaceType.convert(value, underVal)
+}
+
+// UnderlyingType handles named types with a basic underlying kind, like
+// `type Port uint16`, and slices of them through the standard type of the
+// same kind. Values keep their named type.
+type UnderlyingType struct {
+	under     TypeInterface
+	underType reflect.Type
+}
+
+func NewUnderlyingType[T any]() *UnderlyingType {
+	return &UnderlyingType{
+		under:     NewStandardType[T](),
+		underType: Reflected[T](),
+	}
+}
+
+func (ut *UnderlyingType) Predicate(fType reflect.Type) bool {
+	underType := ut.underType
+	if ut.IsVariadic() {
+		if fType.Kind() != reflect.Slice {
+			return false
+		}
+		fType, underType = fType.Elem(), underType.Elem()
+	}
+	return fType.Kind() == underType.Kind()
+}
+
+func (ut *UnderlyingType) ValueType() reflect.Type {
+	return ut.under.ValueType()
+}
+
+func (ut *UnderlyingType) SetValueFromString(value reflect.Value, s string) (err error) {
+	underVal := reflect.New(ut.underType)
+	err = ut.under.SetValueFromString(underVal, s)
+	if err != nil {
+		return
+	}
+	return convertUnderlying(value, underVal)
+}
+
+func (ut *UnderlyingType) SetValue(value, v reflect.Value) (err error) {
+	underVal := reflect.New(ut.underType)
+	err = ut.under.SetValue(underVal, v)
+	if err != nil {
+		return
+	}
+	return convertUnderlying(value, underVal)
+}
+func (ut *UnderlyingType) IsVariadic() bool { return ut.under.IsVariadic() }
+func (ut *UnderlyingType) SetValueFromStrings(value reflect.Value, s []string) (err error) {
+	underVal := reflect.New(ut.underType)
+	err = ut.under.SetValueFromStrings(underVal, s)
+	if err != nil {
+		return
+	}
+	return convertUnderlying(value, underVal)
+}
+
+// convertUnderlying stores a value of a standard type into a named type of
+// the same kind, element by element for slices of named types.
+func convertUnderlying(convertInto, fromUnderType reflect.Value) error {
+	into, from := convertInto.Elem(), fromUnderType.Elem()
+	if from.Type().ConvertibleTo(into.Type()) {
+		into.Set(from.Convert(into.Type()))
+		return nil
+	}
+	return genericSliceConvert(convertInto, fromUnderType, convertUnderlying)
+}
+
+// ParsedType handles value types clive parses itself, like net.IP or
+// url.URL. The flag holds the values as strings.
+type ParsedType struct {
+	typ      reflect.Type
+	variadic bool
+	// option is given by the tag named optionTag (`layout:` or `unit:`),
+	// types that take none have no optionTag.
+	optionTag     string
+	option        string
+	defaultOption string
+	// takesFile marks values as file paths for shell completion.
+	takesFile bool
+	parse     func(s, option string) (reflect.Value, error)
+}
+
+func NewParsedType[T any](parse func(s, option string) (T, error)) *ParsedType {
+	return &ParsedType{
+		typ: Reflected[T](),
+		parse: func(s, option string) (reflect.Value, error) {
+			v, err := parse(s, option)
+			return reflect.ValueOf(&v).Elem(), err
+		},
+	}
+}
+
+// Slice returns the type handling slices of pt, the elements may be
+// pointers.
+func (pt *ParsedType) Slice() *ParsedType {
+	slice := *pt
+	slice.variadic = true
+	return &slice
+}
+
+// Layout makes pt accept a `layout:` tag, layout is used when it is not
+// given.
+func (pt *ParsedType) Layout(layout string) *ParsedType {
+	pt.optionTag, pt.defaultOption = "layout", layout
+	return pt
+}
+
+// Unit makes pt accept a `unit:` tag, unit is used when it is not given.
+func (pt *ParsedType) Unit(unit string) *ParsedType {
+	pt.optionTag, pt.defaultOption = "unit", unit
+	return pt
+}
+
+// Files marks values of pt as file paths, their flags complete file names.
+func (pt *ParsedType) Files() *ParsedType {
+	pt.takesFile = true
+	return pt
+}
+
+// LayoutType is implemented by types accepting a `layout:` tag.
+type LayoutType interface {
+	WithLayout(layout string) (TypeInterface, error)
+}
+
+// UnitType is implemented by types accepting a `unit:` tag.
+type UnitType interface {
+	WithUnit(unit string) (TypeInterface, error)
+}
+
+func (pt *ParsedType) WithLayout(layout string) (TypeInterface, error) {
+	if pt.optionTag != "layout" {
+		return nil, errors.New("type takes no layout")
+	}
+	withLayout := *pt
+	withLayout.option = layout
+	return &withLayout, nil
+}
+
+func (pt *ParsedType) WithUnit(unit string) (TypeInterface, error) {
+	if pt.optionTag != "unit" {
+		return nil, errors.New("type takes no unit")
+	}
+	// plain numbers are parsed in the unit, check that it is one
+	_, err := pt.parse("1", unit)
+	if err != nil {
+		return nil, fmt.Errorf("unknown unit %q", unit)
+	}
+	withUnit := *pt
+	withUnit.option = unit
+	return &withUnit, nil
+}
+
+func (pt *ParsedType) Predicate(fType reflect.Type) bool {
+	if !pt.variadic {
+		return fType == pt.typ
+	}
+	return fType.Kind() == reflect.Slice && (fType.Elem() == pt.typ || fType.Elem() == reflect.PointerTo(pt.typ))
+}
+
+// ValueType is string, or []string for slices: values are parsed by pt.
+func (pt *ParsedType) ValueType() reflect.Type {
+	if pt.variadic {
+		return Reflected[[]string]()
+	}
+	return Reflected[string]()
+}
+
+func (pt *ParsedType) checkDefault(cmdMeta *CommandMetadata) error {
+	if !pt.variadic {
+		_, err := pt.parseOne(*cmdMeta.Default)
+		return err
+	}
+	items, err := cmdMeta.Split(*cmdMeta.Default)
+	if err == nil {
+		_, err = pt.parseAll(items)
+	}
+	return err
+}
+
+func (pt *ParsedType) parseOne(s string) (reflect.Value, error) {
+	option := pt.option
+	if option == "" {
+		option = pt.defaultOption
+	}
+	return pt.parse(s, option)
+}
+
+func (pt *ParsedType) parseAll(s []string) ([]reflect.Value, error) {
+	values := make([]reflect.Value, len(s))
+	for i, item := range s {
+		v, err := pt.parseOne(item)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (pt *ParsedType) SetValueFromString(value reflect.Value, s string) error {
+	if pt.variadic {
+		items, err := SplitList(s, DefaultSep)
+		if err != nil {
+			return err
+		}
+		return pt.SetValueFromStrings(value, items)
+	}
+	v, err := pt.parseOne(s)
+	if err != nil {
+		return err
+	}
+	value.Elem().Set(v)
+	return nil
+}
+
+func (pt *ParsedType) SetValue(value, v reflect.Value) error {
+	if pt.variadic {
+		return pt.SetValueFromStrings(value, v.Interface().([]string))
+	}
+	return pt.SetValueFromString(value, v.String())
+}
+
+func (pt *ParsedType) IsVariadic() bool { return pt.variadic }
+
+func (pt *ParsedType) SetValueFromStrings(value reflect.Value, s []string) error {
+	values, err := pt.parseAll(s)
+	if err != nil {
+		return err
+	}
+	sliceType := value.Type().Elem()
+	slice := reflect.MakeSlice(sliceType, len(values), len(values))
+	for i, v := range values {
+		if sliceType.Elem() != pt.typ {
+			ptr := reflect.New(pt.typ)
+			ptr.Elem().Set(v)
+			v = ptr
+		}
+		slice.Index(i).Set(v)
+	}
+	value.Elem().Set(slice)
+	return nil
+}
+
+// TakesFile reports whether the values of ti are file paths.
+func TakesFile(ti TypeInterface) bool {
+	switch ti := ti.(type) {
+	case *ParsedType:
+		return ti.takesFile
+	case *PointerTo:
+		return TakesFile(ti.ti)
+	}
+	return false
+}
+
+// CheckDefault checks the default value of a flag its command line library
+// keeps as a string, which is only parsed when the command runs: the flags of
+// ParsedTypes and of variant selectors.
+func CheckDefault(cmdMeta *CommandMetadata) error {
+	if cmdMeta.Default == nil {
+		return nil
+	}
+	ti := cmdMeta.TypeInterface
+	for {
+		switch t := ti.(type) {
+		case *ParsedType:
+			return t.checkDefault(cmdMeta)
+		case *VariantType:
+			return t.SetValueFromString(reflect.New(t.iface), *cmdMeta.Default)
+		case *PointerTo:
+			ti = t.ti
+		case *strictVariants:
+			ti = t.TypeInterface
+		default:
+			return nil
+		}
+	}
+}
+
+// isParsedType reports whether values of t are parsed by a ParsedType.
+func isParsedType(t reflect.Type) bool {
+	for _, ti := range types {
+		if pt, ok := ti.(*ParsedType); ok && !pt.variadic && pt.Predicate(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseIP(s, _ string) (net.IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", s)
+	}
+	return ip, nil
+}
+
+func parseIPNet(s, _ string) (net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return net.IPNet{}, err
+	}
+	return *ipNet, nil
+}
+
+func parseURL(s, _ string) (*url.URL, error) {
+	return url.Parse(s)
+}
+
+func parseRegexp(s, _ string) (*regexp.Regexp, error) {
+	return regexp.Compile(s)
+}
+
+func parseLocation(s, _ string) (*time.Location, error) {
+	return time.LoadLocation(s)
+}
+
+func parseFileMode(s, _ string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid file mode %q, expected octal permissions like 0644", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+func parseBigInt(s, _ string) (*big.Int, error) {
+	i, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer %q", s)
+	}
+	return i, nil
+}
+
+func parseBigFloat(s, _ string) (*big.Float, error) {
+	f, ok := new(big.Float).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	return f, nil
+}
+
+// deref turns a parser of pointers into a parser of the values they point
+// to, for fields holding the values themselves.
+func deref[T any](parse func(s, option string) (*T, error)) func(s, option string) (T, error) {
+	return func(s, option string) (T, error) {
+		v, err := parse(s, option)
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		return *v, nil
+	}
+}
+
+func parseTime(s, layout string) (time.Time, error) {
+	return time.Parse(layout, s)
+}
+
+func ignoreOption[T any](parse func(string) (T, error)) func(s, option string) (T, error) {
+	return func(s, _ string) (T, error) { return parse(s) }
+}
+
+var (
+	ipType       = NewParsedType(parseIP)
+	ipNetType    = NewParsedType(parseIPNet)
+	addrType     = NewParsedType(ignoreOption(netip.ParseAddr))
+	addrPortType = NewParsedType(ignoreOption(netip.ParseAddrPort))
+	prefixType   = NewParsedType(ignoreOption(netip.ParsePrefix))
+	urlType      = NewParsedType(deref(parseURL))
+	regexpType   = NewParsedType(deref(parseRegexp))
+	timeType     = NewParsedType(parseTime).Layout(time.RFC3339)
+	locationType = NewParsedType(deref(parseLocation))
+	fileModeType = NewParsedType(parseFileMode)
+	bigIntType   = NewParsedType(deref(parseBigInt))
+	bigFloatType = NewParsedType(deref(parseBigFloat))
+	byteSizeType = NewParsedType(ParseByteSize).Unit("B")
+	percentType  = NewParsedType(ignoreOption(ParsePercent))
+	durationType = NewParsedType(ParseDuration).Unit("")
+)
+
+// pointerTypes are parsed into pointers the parser returns rather than copied
+// into new values: a copy of time.Local isn't the local time zone, and
+// copies of big.Int share their digits.
+var (
+	urlPtrType      = NewParsedType(parseURL)
+	regexpPtrType   = NewParsedType(parseRegexp)
+	locationPtrType = NewParsedType(parseLocation)
+	bigIntPtrType   = NewParsedType(parseBigInt)
+	bigFloatPtrType = NewParsedType(parseBigFloat)
+
+	pointerTypes = []TypeInterface{urlPtrType, regexpPtrType, locationPtrType, bigIntPtrType, bigFloatPtrType}
+)
+
+type PointerTo struct {
+	ti TypeInterface
+}
+
+func (ptrTo *PointerTo) maybeInitializeDereference(value reflect.Value) reflect.Value {
+	elem := value.Elem()
+	if value.Kind() != reflect.Ptr || elem.Kind() != reflect.Ptr {
+		panic("Wrong kind in pointer to: " + elem.Type().String())
+	}
+	if elem.IsNil() {
+		elem.Set(reflect.New(elem.Type().Elem()))
+	}
+	return elem
+}
+
+func (ptrTo *PointerTo) Predicate(fType reflect.Type) bool {
+	return fType.Kind() == reflect.Ptr
+}
+
+func (ptrTo *PointerTo) SetValueFromString(value reflect.Value, s string) (err error) {
+	return ptrTo.ti.SetValueFromString(ptrTo.maybeInitializeDereference(value), s)
+}
+
+func (ptrTo *PointerTo) ValueType() reflect.Type {
+	return ptrTo.ti.ValueType()
+}
+
+func (ptrTo *PointerTo) SetValue(value, v reflect.Value) error {
+	return ptrTo.ti.SetValue(ptrTo.maybeInitializeDereference(value), v)
+}
+
+func (ptrTo *PointerTo) IsVariadic() bool { return ptrTo.ti.IsVariadic() }
+
+func (ptrTo *PointerTo) WithLayout(layout string) (TypeInterface, error) {
+	lt, ok := ptrTo.ti.(LayoutType)
+	if !ok {
+		return nil, errors.New("type takes no layout")
+	}
+	ti, err := lt.WithLayout(layout)
+	if err != nil {
+		return nil, err
+	}
+	return &PointerTo{ti: ti}, nil
+}
+
+func (ptrTo *PointerTo) WithUnit(unit string) (TypeInterface, error) {
+	ut, ok := ptrTo.ti.(UnitType)
+	if !ok {
+		return nil, errors.New("type takes no unit")
+	}
+	ti, err := ut.WithUnit(unit)
+	if err != nil {
+		return nil, err
+	}
+	return &PointerTo{ti: ti}, nil
+}
+
+func (ptrTo *PointerTo) SetValueFromStrings(value reflect.Value, s []string) (err error) {
+	return ptrTo.ti.SetValueFromStrings(ptrTo.maybeInitializeDereference(value), s)
+}
+
+func flagType(fieldType reflect.StructField) (TypeInterface, error) {
+	fieldValueType := fieldType.Type
+	var ptrTo *PointerTo
+	var ptrCurrent *PointerTo
+	for ptrCurrent.Predicate(fieldValueType) {
+		if t := pointerTypeOf(fieldValueType); t != nil {
+			if ptrCurrent == nil {
+				return t, nil
+			}
+			ptrCurrent.ti = t
+			return ptrTo, nil
+		}
+		if ptrTo == nil {
+			ptrTo = &PointerTo{}
+			ptrCurrent = ptrTo
+		} else {
+			newPtrTo := &PointerTo{}
+			ptrCurrent.ti = newPtrTo
+			ptrCurrent = newPtrTo
+		}
+		fieldValueType = fieldValueType.Elem()
+	}
+	if vt := variantTypeOf(fieldValueType); vt != nil && ptrCurrent == nil {
+		return vt, nil
+	}
+	candidates := types
+	if et := enumTypeOf(fieldValueType); et != nil {
+		candidates = append([]TypeInterface{et}, types...)
+	}
+	for _, t := range candidates {
+		if t.Predicate(fieldValueType) {
+			if ptrCurrent == nil {
+				return t, nil
+			}
+			ptrCurrent.ti = t
+			return ptrTo, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported flag generator type: %s", fieldType.Type.String())
+}
+
+// pointerTypeOf returns the type parsing values of fType, a pointer, if it is
+// one of pointerTypes.
+func pointerTypeOf(fType reflect.Type) TypeInterface {
+	for _, t := range pointerTypes {
+		if t.Predicate(fType) {
+			return t
+		}
+	}
+	return nil
+}
+
+func genericSliceConvert(convertInto, from reflect.Value, convertOne func(reflect.Value, reflect.Value) error) (err error) {
+	if convertInto.Kind() != reflect.Pointer || convertInto.Elem().Kind() != reflect.Slice {
+		err = fmt.Errorf("unsupported type for convertInto in genericSliceConvert: %s", convertInto.Type().String())
+		return
+	}
+	if from.Kind() != reflect.Pointer || from.Elem().Kind() != reflect.Slice {
+		err = fmt.Errorf("unsupported type for from in genericSliceConvert: %s", from.Type().String())
+		return
+	}
+	length := from.Elem().Len()
+	slice := reflect.MakeSlice(
+		convertInto.Type().Elem(),
+		length,
+		length,
+	)
+	for i := 0; i < length; i++ {
+		err = convertOne(slice.Index(i).Addr(), from.Elem().Index(i).Addr())
+		if err != nil {
+			return
+		}
+	}
+	convertInto.Elem().Set(slice)
+	return
+}
+
+func genericConvertTextUnmarshal(convertInto, fromUnderType reflect.Value) error {
+	return convertInto.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(fromUnderType.Elem().String()))
+}
+
+var types = []TypeInterface{
+	NewStandardType[int](),
+	NewStandardType[int64](),
+	NewStandardType[uint](),
+	NewStandardType[uint64](),
+	NewStandardType[float32](),
+	NewStandardType[float64](),
+	NewStandardType[string](),
+	NewStandardType[time.Duration](),
+	NewStandardType[int8](),
+	NewStandardType[int16](),
+	NewStandardType[int32](),
+	NewStandardType[uint8](),
+	NewStandardType[uint16](),
+	NewStandardType[uint32](),
+	NewStandardType[bool](),
+	NewStandardType[[]int](),
+	NewStandardType[[]int64](),
+	NewStandardType[[]uint](),
+	NewStandardType[[]uint64](),
+	NewStandardType[[]int8](),
+	NewStandardType[[]int16](),
+	NewStandardType[[]int32](),
+	NewStandardType[[]uint8](),
+	NewStandardType[[]uint16](),
+	NewStandardType[[]uint32](),
+	NewStandardType[[]float32](),
+	NewStandardType[[]float64](),
+	NewStandardType[[]string](),
+	NewStandardType[[]time.Duration](),
+	NewStandardType[[]bool](),
+	NewStandardType[Counter](),
+	// stdlib value types go before text unmarshalers, many of them are
+	ipType, ipType.Slice(),
+	ipNetType, ipNetType.Slice(),
+	addrType, addrType.Slice(),
+	addrPortType, addrPortType.Slice(),
+	prefixType, prefixType.Slice(),
+	// slices of pointers come before slices of values, which take them too
+	urlPtrType.Slice(), urlType, urlType.Slice(),
+	regexpPtrType.Slice(), regexpType, regexpType.Slice(),
+	timeType, timeType.Slice(),
+	locationPtrType.Slice(), locationType, locationType.Slice(),
+	fileModeType, fileModeType.Slice(),
+	bigIntPtrType.Slice(), bigIntType, bigIntType.Slice(),
+	bigFloatPtrType.Slice(), bigFloatType, bigFloatType.Slice(),
+	byteSizeType, byteSizeType.Slice(),
+	percentType, percentType.Slice(),
+	durationType, durationType.Slice(),
+	pathType, pathType.Slice(),
+	existingFileType, existingFileType.Slice(),
+	existingDirType, existingDirType.Slice(),
+	inputFileType, inputFileType.Slice(),
+	outputFileType, outputFileType.Slice(),
+	&InterfaceType{
+		interfaceType: Reflected[encoding.TextUnmarshaler](),
+
+		under:     NewStandardType[string](),
+		underType: Reflected[string](),
+
+		convert: genericConvertTextUnmarshal,
+	},
+	&InterfaceType{
+		interfaceType: Reflected[encoding.TextUnmarshaler](),
+
+		under:     NewStandardType[[]string](),
+		underType: Reflected[[]string](),
+
+		convert: func(convertInto, fromUnderType reflect.Value) error {
+			return genericSliceConvert(convertInto, fromUnderType, genericConvertTextUnmarshal)
+		},
+	},
+	// named types are matched by kind only when nothing above matched them
+	NewUnderlyingType[int](),
+	NewUnderlyingType[int8](),
+	NewUnderlyingType[int16](),
+	NewUnderlyingType[int32](),
+	NewUnderlyingType[int64](),
+	NewUnderlyingType[uint](),
+	NewUnderlyingType[uint8](),
+	NewUnderlyingType[uint16](),
+	NewUnderlyingType[uint32](),
+	NewUnderlyingType[uint64](),
+	NewUnderlyingType[float32](),
+	NewUnderlyingType[float64](),
+	NewUnderlyingType[string](),
+	NewUnderlyingType[bool](),
+	NewUnderlyingType[[]int](),
+	NewUnderlyingType[[]int8](),
+	NewUnderlyingType[[]int16](),
+	NewUnderlyingType[[]int32](),
+	NewUnderlyingType[[]int64](),
+	NewUnderlyingType[[]uint](),
+	NewUnderlyingType[[]uint8](),
+	NewUnderlyingType[[]uint16](),
+	NewUnderlyingType[[]uint32](),
+	NewUnderlyingType[[]uint64](),
+	NewUnderlyingType[[]float32](),
+	NewUnderlyingType[[]float64](),
+	NewUnderlyingType[[]string](),
+	NewUnderlyingType[[]bool](),
+}
+
+// ParseValue parses s into dst the same way clive parses positional
+// arguments and default values.
+func ParseValue[T any](dst *T, s string) error {
+	return parseStandartTypes[T](dst, s)
+}
+
+// ParseValues parses a list of positional arguments into the slice dst.
+func ParseValues[T any](dst *T, s []string) error {
+	return parseStandartSliceTypes[T](dst, s)
+}
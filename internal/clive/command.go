@@ -0,0 +1,192 @@
+package clive
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+type (
+	WithVersion interface {
+		Version() string
+	}
+	WithDescription interface {
+		Description() string
+	}
+	HasVariants interface {
+		Variants() []string
+	}
+)
+
+// Command is embedded by command structs built only for the command line
+// libraries with a builder on top of CommandSpec, see Describe. Its tag
+// sets the name, aliases and usage of the command like the one of
+// clive.Command.
+type Command struct{}
+
+// Library is a command line library command structs can be built for.
+type Library struct {
+	// Command is the pointer type command structs built for the library
+	// embed as their first field.
+	Command reflect.Type
+	// Run is the type of the Run field of command structs running it instead
+	// of their Action, nil if the library has none.
+	Run reflect.Type
+	// CommandName returns the name set on the embedded command by hand, if
+	// any.
+	CommandName func(command interface{}) string
+	// Foreign reports whether obj is a subcommand constructed by hand.
+	Foreign func(obj interface{}) bool
+}
+
+var (
+	librariesMu sync.RWMutex
+	libraries   = []Library{{Command: reflect.TypeOf((*Command)(nil))}}
+)
+
+// RegisterLibrary registers the command type of a command line library.
+func RegisterLibrary(l Library) {
+	librariesMu.Lock()
+	defer librariesMu.Unlock()
+	libraries = append(libraries, l)
+}
+
+// libraryOf returns the library the command type t belongs to.
+func libraryOf(t reflect.Type) (Library, bool) {
+	librariesMu.RLock()
+	defer librariesMu.RUnlock()
+	for _, l := range libraries {
+		if l.Command == t {
+			return l, true
+		}
+	}
+	return Library{}, false
+}
+
+// foreign reports whether obj is a subcommand constructed by hand for one
+// of the libraries.
+func foreign(obj interface{}) bool {
+	librariesMu.RLock()
+	defer librariesMu.RUnlock()
+	for _, l := range libraries {
+		if l.Foreign != nil && l.Foreign(obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// isRunField reports whether field is the Run field of a command struct.
+func isRunField(field reflect.StructField) bool {
+	if field.Name != "Run" {
+		return false
+	}
+	librariesMu.RLock()
+	defer librariesMu.RUnlock()
+	for _, l := range libraries {
+		if l.Run != nil && field.Type == l.Run {
+			return true
+		}
+	}
+	return false
+}
+
+// Options are the options of parsing command structs the builders for
+// every library share.
+type Options struct {
+	EnvPrefix string
+	// HelpRenderer renders help for every command, if nil
+	// DefaultHelpRenderer is used.
+	HelpRenderer HelpRenderer
+	// NegatableBools adds a --no-<name> flag to every bool flag defaulting to
+	// true, unless it is tagged with negatable:false.
+	NegatableBools bool
+	// Generated returns the GeneratedCommand of command structs with code
+	// generated for the library building them, their flags and positional
+	// arguments aren't parsed.
+	Generated func(obj interface{}) (*GeneratedCommand, bool, error)
+}
+
+// EnvVar returns the name of the environment variable for a flag with
+// EnvPrefix applied.
+func (bo *Options) EnvVar(name string) string {
+	if bo.EnvPrefix == "" {
+		return name
+	}
+	return bo.EnvPrefix + "_" + name
+}
+
+// Renderer returns the HelpRenderer, or DefaultHelpRenderer.
+func (bo *Options) Renderer() HelpRenderer {
+	if bo.HelpRenderer == nil {
+		return DefaultHelpRenderer
+	}
+	return bo.HelpRenderer
+}
+
+// ErrCommandNotImplemented is returned by commands that have neither an
+// action nor a subcommand to run, after printing their help.
+func ErrCommandNotImplemented() error {
+	return errors.New("command not implemented")
+}
+
+// Describe parses obj and returns the tree of commands, flags and positional
+// arguments it found. Subcommands constructed by hand are left out.
+func Describe(obj interface{}, o Options) (*CommandSpec, error) {
+	model, err := ParseCommand(obj, "", &o)
+	if err != nil {
+		return nil, err
+	}
+	if model.Foreign {
+		return nil, fmt.Errorf("command %T is constructed by hand and can't be described", obj)
+	}
+	spec := model.Spec(&o)
+	if versioned, ok := obj.(WithVersion); ok {
+		spec.Version = versioned.Version()
+	}
+	spec.SetPaths("")
+	return spec, nil
+}
+
+var ErrNil = errors.New("obj is n ull")
+
+type ByValueError struct {
+	Type string
+}
+
+func (e *ByValueError) Error() string {
+	return fmt.Sprintf("command struct %s is passed by value, pass by reference", e.Type)
+}
+
+type WrongFirstFieldError struct {
+	NumFields int
+
+	FieldName string
+	Type      string
+}
+
+func (e *WrongFirstFieldError) Error() string {
+	return fmt.Sprintf(`
+	command struct:
+	* should have at least one field (have %d)
+	* its first field must be an embedded *clive.Command (name: %s, type: %s)
+	`, e.NumFields, e.FieldName, e.Type)
+}
+
+type HiddenPositionalError struct {
+	Name string
+}
+
+func (e *HiddenPositionalError) Error() string {
+	return fmt.Sprintf("positional argument %s cannot be Hidden", e.Name)
+}
+
+type PositionalAfterVariadicError struct {
+	CurrentName string
+	FirstName   string
+}
+
+func (e *PositionalAfterVariadicError) Error() string {
+	return fmt.Sprintf("cant add positional argument %s after variadic (slice of x) argument %s", e.CurrentName, e.FirstName)
+}
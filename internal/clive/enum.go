@@ -0,0 +1,331 @@
+package clive
+
+import (
+	"cmp"
+	"encoding"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Interfaces HasVariants types can implement to accept more than the exact
+// names of their variants. Values are passed on to UnmarshalText as the
+// variant they name.
+type (
+	// HasVariantAliases is implemented by HasVariants types accepting other
+	// names for their variants, VariantAliases maps each alias to a variant.
+	HasVariantAliases interface {
+		VariantAliases() map[string]string
+	}
+	// HasCaseInsensitiveVariants is implemented by HasVariants types matching
+	// their variants and aliases regardless of case.
+	HasCaseInsensitiveVariants interface {
+		CaseInsensitiveVariants() bool
+	}
+	// HasVariantDescriptions is implemented by HasVariants types describing
+	// their variants in help, VariantDescriptions maps variants to their
+	// descriptions.
+	HasVariantDescriptions interface {
+		VariantDescriptions() map[string]string
+	}
+)
+
+// InvalidVariantError is returned for values of HasVariants types naming
+// none of their variants.
+type InvalidVariantError struct {
+	Value    string
+	Variants []string
+	// Suggestions are the variants and aliases closest to Value.
+	Suggestions []string
+}
+
+func (e *InvalidVariantError) Error() string {
+	return fmt.Sprintf("invalid value %q, expected one of [%s]%s", e.Value, strings.Join(e.Variants, ", "), didYouMean(e.Suggestions))
+}
+
+// variantSet holds what values of a HasVariants type are checked against.
+type variantSet struct {
+	names        []string
+	aliases      map[string]string
+	fold         bool
+	descriptions map[string]string
+}
+
+// newVariantSet returns the variantSet of v, implementing HasVariants.
+func newVariantSet(v interface{}) (*variantSet, error) {
+	set := &variantSet{names: v.(HasVariants).Variants()}
+	if a, ok := v.(HasVariantAliases); ok {
+		set.aliases = a.VariantAliases()
+	}
+	if c, ok := v.(HasCaseInsensitiveVariants); ok {
+		set.fold = c.CaseInsensitiveVariants()
+	}
+	if d, ok := v.(HasVariantDescriptions); ok {
+		set.descriptions = d.VariantDescriptions()
+	}
+	for alias, name := range set.aliases {
+		if !contains(set.names, name) {
+			return nil, fmt.Errorf("alias %s of %T is for %s, which is not a variant", alias, v, name)
+		}
+	}
+	return set, nil
+}
+
+// variantSetOf returns the variantSet of t, a HasVariants type, an enum
+// registered with RegisterEnum or a pointer or a slice of one, nil for other
+// types.
+func variantSetOf(t reflect.Type) (*variantSet, error) {
+	for {
+		if e := enumOf(t); e != nil {
+			return e.variantSet(), nil
+		}
+		if ptr := reflect.PointerTo(t); ptr.Implements(Reflected[HasVariants]()) {
+			return newVariantSet(reflect.Zero(ptr).Interface())
+		}
+		if t.Kind() != reflect.Pointer && t.Kind() != reflect.Slice {
+			return nil, nil
+		}
+		t = t.Elem()
+	}
+}
+
+func (set *variantSet) match(name, s string) bool {
+	if set.fold {
+		return strings.EqualFold(name, s)
+	}
+	return name == s
+}
+
+// variant returns the variant named by s.
+func (set *variantSet) variant(s string) (string, error) {
+	for _, name := range set.names {
+		if set.match(name, s) {
+			return name, nil
+		}
+	}
+	aliases := make([]string, 0, len(set.aliases))
+	for alias := range set.aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		if set.match(alias, s) {
+			return set.aliases[alias], nil
+		}
+	}
+	candidates := append(append([]string{}, set.names...), aliases...)
+	return "", &InvalidVariantError{Value: s, Variants: set.names, Suggestions: Suggest(s, candidates)}
+}
+
+// strictVariants checks the values of fields of HasVariants types against
+// their variants before passing them on to the type of the field.
+type strictVariants struct {
+	TypeInterface
+	set *variantSet
+}
+
+func (sv *strictVariants) SetValueFromString(value reflect.Value, s string) error {
+	name, err := sv.set.variant(s)
+	if err != nil {
+		return err
+	}
+	return sv.TypeInterface.SetValueFromString(value, name)
+}
+
+func (sv *strictVariants) SetValueFromStrings(value reflect.Value, s []string) error {
+	names := make([]string, len(s))
+	for i, item := range s {
+		var err error
+		names[i], err = sv.set.variant(item)
+		if err != nil {
+			return err
+		}
+	}
+	return sv.TypeInterface.SetValueFromStrings(value, names)
+}
+
+func (sv *strictVariants) SetValue(value, v reflect.Value) error {
+	if sv.IsVariadic() {
+		return sv.SetValueFromStrings(value, v.Interface().([]string))
+	}
+	return sv.SetValueFromString(value, v.String())
+}
+
+// checkDefault checks the default value of a field of a HasVariants type.
+func (sv *strictVariants) checkDefault(cmdMeta *CommandMetadata) error {
+	if cmdMeta.Default == nil {
+		return nil
+	}
+	items := []string{*cmdMeta.Default}
+	if sv.IsVariadic() {
+		var err error
+		items, err = cmdMeta.Split(*cmdMeta.Default)
+		if err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		if _, err := sv.set.variant(item); err != nil {
+			return fmt.Errorf("bad default of %s: %w", cmdMeta.Name, err)
+		}
+	}
+	return nil
+}
+
+// UnmarshalVariant sets v from the variant of its type named by s, the way
+// fields of HasVariants types are set. It is used by generated code.
+func UnmarshalVariant(v encoding.TextUnmarshaler, s string) error {
+	set, err := newVariantSet(v)
+	if err != nil {
+		return err
+	}
+	name, err := set.variant(s)
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalText([]byte(name))
+}
+
+// enum is an enum registered with RegisterEnum.
+type enum interface {
+	variantSet() *variantSet
+	typeInterface(slice bool) TypeInterface
+}
+
+var (
+	enumsMu sync.RWMutex
+	enums   = map[reflect.Type]enum{}
+)
+
+// enumOf returns the enum registered for t, or nil.
+func enumOf(t reflect.Type) enum {
+	enumsMu.RLock()
+	defer enumsMu.RUnlock()
+	return enums[t]
+}
+
+// enumTypeOf returns the type handling t, an enum registered with
+// RegisterEnum or a slice of one, or nil.
+func enumTypeOf(t reflect.Type) TypeInterface {
+	if e := enumOf(t); e != nil {
+		return e.typeInterface(false)
+	}
+	if t.Kind() != reflect.Slice {
+		return nil
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if e := enumOf(elem); e != nil {
+		return e.typeInterface(true)
+	}
+	return nil
+}
+
+// enumNames holds the names of the values of an enum.
+type enumNames[T cmp.Ordered] struct {
+	// values are sorted, they list the variants in help in that order
+	values       []T
+	names        map[T]string
+	byName       map[string]T
+	descriptions map[string]string
+}
+
+func (e *enumNames[T]) variantSet() *variantSet {
+	set := &variantSet{descriptions: e.descriptions}
+	for _, v := range e.values {
+		set.names = append(set.names, e.names[v])
+	}
+	return set
+}
+
+func (e *enumNames[T]) typeInterface(slice bool) TypeInterface {
+	pt := NewParsedType(func(s, _ string) (T, error) {
+		return e.parse(s)
+	})
+	if slice {
+		return pt.Slice()
+	}
+	return pt
+}
+
+func (e *enumNames[T]) parse(s string) (T, error) {
+	v, ok := e.byName[s]
+	if !ok {
+		return v, &InvalidVariantError{Value: s, Variants: e.variantSet().names}
+	}
+	return v, nil
+}
+
+// RegisterEnum is clive.RegisterEnum.
+func RegisterEnum[T cmp.Ordered](names map[T]string) {
+	t := Reflected[T]()
+	e := &enumNames[T]{names: map[T]string{}, byName: map[string]T{}}
+	for v, name := range names {
+		if _, ok := e.byName[name]; ok {
+			panic(fmt.Errorf("enum %s has more than one value named %s", t, name))
+		}
+		e.values = append(e.values, v)
+		e.names[v], e.byName[name] = name, v
+	}
+	slices.Sort(e.values)
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+	if _, ok := enums[t]; ok {
+		panic(fmt.Errorf("enum %s is already registered", t))
+	}
+	enums[t] = e
+}
+
+// RegisterEnumDescriptions is clive.RegisterEnumDescriptions.
+func RegisterEnumDescriptions[T cmp.Ordered](descriptions map[T]string) {
+	e := registeredEnum[T]()
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+	e.descriptions = map[string]string{}
+	for v, description := range descriptions {
+		name, ok := e.names[v]
+		if !ok {
+			panic(fmt.Errorf("value %v of enum %s has no name", v, Reflected[T]()))
+		}
+		e.descriptions[name] = description
+	}
+}
+
+// registeredEnum returns the names of the values of T, it panics if T is not
+// registered with RegisterEnum.
+func registeredEnum[T cmp.Ordered]() *enumNames[T] {
+	e, ok := enumOf(Reflected[T]()).(*enumNames[T])
+	if !ok {
+		panic(fmt.Errorf("enum %s is not registered, see RegisterEnum", Reflected[T]()))
+	}
+	return e
+}
+
+// EnumName returns the name of the value v of T, an enum registered with
+// RegisterEnum. It panics if T is not registered.
+func EnumName[T cmp.Ordered](v T) (string, bool) {
+	name, ok := registeredEnum[T]().names[v]
+	return name, ok
+}
+
+// ParseEnum returns the value of T named s.
+func ParseEnum[T cmp.Ordered](s string) (T, error) {
+	return registeredEnum[T]().parse(s)
+}
+
+// EnumVariants returns the names of the values of T in the order of the
+// values.
+func EnumVariants[T cmp.Ordered]() []string {
+	return registeredEnum[T]().variantSet().names
+}
+
+// EnumDescriptions returns the descriptions set with
+// RegisterEnumDescriptions.
+func EnumDescriptions[T cmp.Ordered]() map[string]string {
+	return registeredEnum[T]().descriptions
+}
@@ -0,0 +1,277 @@
+package clive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Path is a file system path with a leading ~ expanded to the home directory.
+type Path string
+
+// ExistingFile is a Path that must name an existing file when it is bound.
+type ExistingFile string
+
+// ExistingDir is a Path that must name an existing directory when it is
+// bound.
+type ExistingDir string
+
+// InputFile is a file opened for reading after the command struct is bound
+// and closed after its After ran. "-" stands for stdin, which is not closed.
+type InputFile struct {
+	// Path is the path given, with ~ expanded.
+	Path string
+	*os.File
+}
+
+// OutputFile is a file created or truncated after the command struct is
+// bound and closed after its After ran. "-" stands for stdout, which is not
+// closed.
+type OutputFile struct {
+	// Path is the path given, with ~ expanded.
+	Path string
+	*os.File
+}
+
+// Opener is implemented by field types holding resources, like InputFile.
+// clive opens them after binding the command struct, before its Before, and
+// closes them after its After, whether the command failed or not.
+type Opener interface {
+	Open() error
+	io.Closer
+}
+
+// expandHome replaces a leading ~ in path with the home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// stat returns the file info of path, not existing is reported with what
+// was expected to be there.
+func stat(path, what string) (fs.FileInfo, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s %q does not exist", what, path)
+	}
+	return info, err
+}
+
+func parsePath(s, _ string) (Path, error) {
+	path, err := expandHome(s)
+	return Path(path), err
+}
+
+func parseExistingFile(s, _ string) (ExistingFile, error) {
+	path, err := expandHome(s)
+	if err != nil {
+		return "", err
+	}
+	info, err := stat(path, "file")
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%q is a directory, not a file", path)
+	}
+	return ExistingFile(path), nil
+}
+
+func parseExistingDir(s, _ string) (ExistingDir, error) {
+	path, err := expandHome(s)
+	if err != nil {
+		return "", err
+	}
+	info, err := stat(path, "directory")
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%q is not a directory", path)
+	}
+	return ExistingDir(path), nil
+}
+
+// modes of access checks, the ones of access(2)
+const (
+	accessRead   = 4
+	accessWrite  = 2
+	accessSearch = 1
+)
+
+func parseInputFile(s, _ string) (InputFile, error) {
+	if s == "-" {
+		return InputFile{Path: s}, nil
+	}
+	path, err := parseExistingFile(s, "")
+	if err != nil {
+		return InputFile{}, err
+	}
+	if err = access(string(path), accessRead); err != nil {
+		return InputFile{}, fmt.Errorf("file %q can't be read: %w", path, err)
+	}
+	return InputFile{Path: string(path)}, nil
+}
+
+func parseOutputFile(s, _ string) (OutputFile, error) {
+	if s == "-" {
+		return OutputFile{Path: s}, nil
+	}
+	path, err := expandHome(s)
+	if err != nil {
+		return OutputFile{}, err
+	}
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		return OutputFile{}, fmt.Errorf("%q is a directory, not a file", path)
+	case err == nil:
+		if err = access(path, accessWrite); err != nil {
+			return OutputFile{}, fmt.Errorf("file %q can't be written: %w", path, err)
+		}
+	default:
+		// the file is created in its directory
+		dir, err := parseExistingDir(filepath.Dir(path), "")
+		if err != nil {
+			return OutputFile{}, err
+		}
+		if err = access(string(dir), accessWrite|accessSearch); err != nil {
+			return OutputFile{}, fmt.Errorf("directory %q can't be written: %w", dir, err)
+		}
+	}
+	return OutputFile{Path: path}, nil
+}
+
+// Open opens the file at Path, stdin for "-".
+func (f *InputFile) Open() (err error) {
+	if f.Path == "-" {
+		f.File = os.Stdin
+		return nil
+	}
+	f.File, err = os.Open(f.Path)
+	return
+}
+
+// Close closes the file unless it is stdin, closing a file that isn't open
+// does nothing.
+func (f *InputFile) Close() error {
+	file := f.File
+	f.File = nil
+	if file == nil || f.Path == "-" {
+		return nil
+	}
+	return file.Close()
+}
+
+// Open creates or truncates the file at Path, stdout for "-".
+func (f *OutputFile) Open() (err error) {
+	if f.Path == "-" {
+		f.File = os.Stdout
+		return nil
+	}
+	f.File, err = os.Create(f.Path)
+	return
+}
+
+// Close closes the file unless it is stdout, closing a file that isn't open
+// does nothing.
+func (f *OutputFile) Close() error {
+	file := f.File
+	f.File = nil
+	if file == nil || f.Path == "-" {
+		return nil
+	}
+	return file.Close()
+}
+
+var (
+	pathType         = NewParsedType(parsePath).Files()
+	existingFileType = NewParsedType(parseExistingFile).Files()
+	existingDirType  = NewParsedType(parseExistingDir).Files()
+	inputFileType    = NewParsedType(parseInputFile).Files()
+	outputFileType   = NewParsedType(parseOutputFile).Files()
+)
+
+// openers returns the Openers held by the flags and positional arguments of
+// obj, in the order they are declared.
+func openers(obj interface{}, fields ...[]CommandMetadata) (found []Opener) {
+	objValue := reflect.ValueOf(obj).Elem()
+	var collect func(v reflect.Value)
+	collect = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.Pointer:
+			if !v.IsNil() {
+				collect(v.Elem())
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				collect(v.Index(i))
+			}
+		default:
+			if !v.Addr().CanInterface() {
+				return
+			}
+			if opener, ok := v.Addr().Interface().(Opener); ok {
+				found = append(found, opener)
+			}
+		}
+	}
+	for _, group := range fields {
+		for i := range group {
+			if field := existingField(objValue, group[i].Accesses); field.IsValid() {
+				collect(field.Elem())
+			}
+		}
+	}
+	return
+}
+
+// OpenFields opens the Openers of the command struct, see Opener.
+func OpenFields(obj interface{}, positionals, flags []CommandMetadata) error {
+	for _, opener := range openers(obj, positionals, flags) {
+		err := opener.Open()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloseFields closes the Openers of the command struct, see Opener.
+func CloseFields(obj interface{}, positionals, flags []CommandMetadata) error {
+	var err error
+	for _, opener := range openers(obj, positionals, flags) {
+		if cerr := opener.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+	}
+	return err
+}
+
+// Open opens the files and other Openers of the bound command struct, see
+// Opener. Command line libraries call it after Bind and before Before.
+func (spec *CommandSpec) Open() error {
+	if spec.record == nil {
+		return fmt.Errorf("command %q was not built by clive", spec.Path)
+	}
+	return OpenFields(spec.record.obj, spec.record.positionals, spec.record.flags)
+}
+
+// Close closes what Open opened, command line libraries call it after After.
+func (spec *CommandSpec) Close() error {
+	if spec.record == nil {
+		return nil
+	}
+	return CloseFields(spec.record.obj, spec.record.positionals, spec.record.flags)
+}
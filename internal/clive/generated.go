@@ -0,0 +1,64 @@
+package clive
+
+import (
+	"reflect"
+	"strings"
+)
+
+// GeneratedCommand describes a command struct with code generated for a
+// library, which constructs its flags and binds them itself. Parsing takes
+// the command from it instead of going through the fields of the struct.
+type GeneratedCommand struct {
+	// Command is the embedded Command of the library, Run its Run field.
+	Command interface{}
+	Run     interface{}
+	// Name, Usage, Aliases and UseShortOptions are read from the tag of the
+	// embedded command. Without a name the command is named the way
+	// ParseCommand names it.
+	Name            string
+	Usage           string
+	Aliases         []string
+	UseShortOptions bool
+	ArgsUsage       string
+	// Subcommands are the pointers held by the Subcommands field.
+	Subcommands []interface{}
+	// Required are the names of the required flags, Envs the environment
+	// variables of every flag. Envs are set by the library, see
+	// Options.Generated.
+	Required []string
+	Envs     []string
+}
+
+// parseGenerated returns the model of the command struct obj with generated
+// code. Its Flags and Positionals are left empty.
+func parseGenerated(obj interface{}, gen *GeneratedCommand, parentCommandPath string, bo *Options) (*CommandModel, error) {
+	model := &CommandModel{
+		Obj:        obj,
+		ObjType:    reflect.TypeOf(obj).Elem(),
+		Command:    gen.Command,
+		Meta:       CommandMetadata{Tag: Tag{Usage: gen.Usage, Aliases: gen.Aliases, UseShortOptions: gen.UseShortOptions}},
+		Name:       gen.Name,
+		ParentPath: parentCommandPath,
+		Run:        gen.Run,
+		ArgsUsage:  gen.ArgsUsage,
+		Generated:  gen,
+	}
+	if library, ok := libraryOf(reflect.TypeOf(gen.Command)); model.Name == "" && ok && library.CommandName != nil {
+		model.Name = library.CommandName(gen.Command)
+	}
+	if model.Name == "" {
+		model.Name = strings.ToLower(model.ObjType.Name())
+	}
+	model.Path = commandPath(parentCommandPath, model.Name)
+	for _, subcommand := range gen.Subcommands {
+		sub, err := ParseCommand(subcommand, model.Path, bo)
+		if err != nil {
+			return nil, err
+		}
+		model.Subcommands = append(model.Subcommands, sub)
+	}
+	if parentCommandPath == "" {
+		model.inheritGlobals(nil)
+	}
+	return model, nil
+}
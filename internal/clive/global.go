@@ -0,0 +1,84 @@
+package clive
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// GlobalFlag is a flag tagged global by an ancestor of a command. Global
+// flags are accepted anywhere after the command declaring them and are bound
+// into its struct, and into a field of a descendant with a flag of the same
+// name.
+type GlobalFlag struct {
+	CommandMetadata
+	// Path is the Path of the declaring command, objType its type.
+	Path    string
+	ObjType reflect.Type
+}
+
+// checkGlobal reports fields that can't be global flags.
+func checkGlobal(cmdMeta *CommandMetadata, fieldType reflect.StructField) error {
+	switch {
+	case cmdMeta.Positional:
+		return fmt.Errorf("global field %s can't be positional", fieldType.Name)
+	case cmdMeta.Variant != nil || cmdMeta.Repeated != nil || len(cmdMeta.optional) != 0:
+		return fmt.Errorf("global field %s can't be in a variant, a repeated or a pointer inline group", fieldType.Name)
+	case cmdMeta.Required:
+		// the flag may be given after the command declaring it has run its
+		// checks
+		return fmt.Errorf("global field %s can't be required", fieldType.Name)
+	}
+	if _, ok := cmdMeta.TypeInterface.(*VariantType); ok {
+		return fmt.Errorf("global field %s can't be a variant", fieldType.Name)
+	}
+	return nil
+}
+
+// inheritGlobals sets the global flags of the ancestors of model and of its
+// subcommands, a global flag of a command shadows those of the same name of
+// its ancestors.
+func (model *CommandModel) inheritGlobals(inherited []GlobalFlag) {
+	model.Globals = inherited
+	visible := append([]GlobalFlag{}, inherited...)
+	for _, cmdMeta := range model.Flags {
+		if !cmdMeta.Global {
+			continue
+		}
+		global := GlobalFlag{CommandMetadata: cmdMeta, Path: model.Path, ObjType: model.ObjType}
+		shadowed := false
+		for i := range visible {
+			if visible[i].Name == cmdMeta.Name {
+				visible[i], shadowed = global, true
+			}
+		}
+		if !shadowed {
+			visible = append(visible, global)
+		}
+	}
+	for _, sub := range model.Subcommands {
+		if !sub.Foreign {
+			sub.inheritGlobals(visible)
+		}
+	}
+}
+
+// Flag returns the flag of the command named name, or nil.
+func (model *CommandModel) Flag(name string) *CommandMetadata {
+	for i := range model.Flags {
+		if model.Flags[i].Name == name {
+			return &model.Flags[i]
+		}
+	}
+	return nil
+}
+
+// InheritedGlobals returns the global flags of the ancestors of model its
+// own flags don't replace.
+func (model *CommandModel) InheritedGlobals() (globals []*GlobalFlag) {
+	for i := range model.Globals {
+		if model.Flag(model.Globals[i].Name) == nil {
+			globals = append(globals, &model.Globals[i])
+		}
+	}
+	return
+}
@@ -0,0 +1,557 @@
+package clive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CommandSpec is a structured description of a command built by clive. It is
+// the model help renderers work with.
+type CommandSpec struct {
+	Name string `json:"name"`
+	// Path is the space separated list of subcommand names leading to this
+	// command from the root, it is empty for the root command.
+	Path        string       `json:"path,omitempty"`
+	Type        string       `json:"type,omitempty"`
+	HelpName    string       `json:"helpName,omitempty"`
+	Aliases     []string     `json:"aliases,omitempty"`
+	Usage       string       `json:"usage,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Version     string       `json:"version,omitempty"`
+	Flags       []*FieldSpec `json:"flags,omitempty"`
+	// GlobalFlags are the global flags of the ancestors of the command it
+	// accepts too.
+	GlobalFlags []*FieldSpec   `json:"globalFlags,omitempty"`
+	Positionals []*FieldSpec   `json:"positionals,omitempty"`
+	Subcommands []*CommandSpec `json:"subcommands,omitempty"`
+
+	record *commandRecord
+}
+
+// FieldSpec describes a single flag or positional argument of a command.
+type FieldSpec struct {
+	Name string `json:"name"`
+	// FieldPath is the path to the Go field holding the value, starting from
+	// the command struct and going through inline groups.
+	FieldPath  []string `json:"fieldPath,omitempty"`
+	Type       string   `json:"type"`
+	Tag        string   `json:"tag,omitempty"`
+	Aliases    []string `json:"aliases,omitempty"`
+	Usage      string   `json:"usage,omitempty"`
+	Envs       []string `json:"envs,omitempty"`
+	Default    *string  `json:"default,omitempty"`
+	Variants   []string `json:"variants,omitempty"`
+	Group      string   `json:"group,omitempty"`
+	Required   bool     `json:"required,omitempty"`
+	Hidden     bool     `json:"hidden,omitempty"`
+	Variadic   bool     `json:"variadic,omitempty"`
+	Positional bool     `json:"positional,omitempty"`
+	// Negatable flags can be set to false with --no-<name>.
+	Negatable bool `json:"negatable,omitempty"`
+	// TakesFile is set for file paths, for shell completion.
+	TakesFile bool `json:"takesFile,omitempty"`
+	// VariantDescriptions describe Variants, see HasVariantDescriptions.
+	VariantDescriptions map[string]string `json:"variantDescriptions,omitempty"`
+	// Sep and EnvFormat are set for slices with `sep:` and `envformat:`
+	// tags.
+	Sep       string `json:"sep,omitempty"`
+	EnvFormat string `json:"envFormat,omitempty"`
+	// Selector and Variant are set for flags of variant implementations (see
+	// RegisterVariant), they are only bound when the flag Selector is set to
+	// Variant. Required applies then too.
+	Selector string `json:"selector,omitempty"`
+	Variant  string `json:"variant,omitempty"`
+	// Repeated is set for flags of repeated inline groups (slices of
+	// structs) to the flag starting a new element. Their environment
+	// variables are indexed, IndexedEnv has <N> in place of the index.
+	Repeated   string `json:"repeated,omitempty"`
+	IndexedEnv string `json:"indexedEnv,omitempty"`
+	// Global flags are accepted by the subcommands of the command too.
+	Global bool `json:"global,omitempty"`
+}
+
+// NegatedName returns the name of the flag setting a negatable flag to false.
+func (field *FieldSpec) NegatedName() string {
+	return NegatedName(field.Name)
+}
+
+// HelpRenderer turns a CommandSpec into help text.
+type HelpRenderer interface {
+	RenderHelp(w io.Writer, spec *CommandSpec) error
+}
+
+// TextHelpRenderer renders human-readable help. Lines are wrapped at Width
+// columns; when Width is zero the width of the terminal w is attached to is
+// used, falling back to $COLUMNS and then to 80 columns.
+type TextHelpRenderer struct {
+	Width int
+}
+
+// JSONHelpRenderer renders the CommandSpec as a JSON document, for use by IDE
+// and tool integrations.
+type JSONHelpRenderer struct {
+	Indent string
+}
+
+// DefaultHelpRenderer is used when BuildOptions.HelpRenderer is not set.
+var DefaultHelpRenderer HelpRenderer = &TextHelpRenderer{}
+
+const defaultHelpWidth = 80
+
+func (r *JSONHelpRenderer) RenderHelp(w io.Writer, spec *CommandSpec) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", r.Indent)
+	return enc.Encode(spec)
+}
+
+func (r *TextHelpRenderer) width(w io.Writer) int {
+	if r.Width > 0 {
+		return r.Width
+	}
+	if b, ok := w.(*HelpBuffer); ok {
+		w = b.Out
+	}
+	if f, ok := w.(*os.File); ok {
+		if width, ok := terminalWidth(f); ok && width > 0 {
+			return width
+		}
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return defaultHelpWidth
+}
+
+func (r *TextHelpRenderer) RenderHelp(w io.Writer, spec *CommandSpec) error {
+	width := r.width(w)
+	b := &strings.Builder{}
+
+	name := spec.HelpName
+	if name == "" {
+		name = spec.Name
+	}
+
+	b.WriteString("NAME:\n")
+	title := name
+	if spec.Usage != "" {
+		title = fmt.Sprintf("%s - %s", name, spec.Usage)
+	}
+	writeWrapped(b, title, 3, width)
+
+	b.WriteString("\nUSAGE:\n")
+	usage := []string{name}
+	if len(spec.Flags) != 0 {
+		usage = append(usage, "[options]")
+	}
+	if len(spec.Subcommands) != 0 {
+		usage = append(usage, "command [command options]")
+	}
+	if args := positionalsUsage(spec.Positionals); args != "" {
+		usage = append(usage, args)
+	}
+	writeWrapped(b, strings.Join(usage, " "), 3, width)
+
+	if spec.Version != "" {
+		b.WriteString("\nVERSION:\n")
+		writeWrapped(b, spec.Version, 3, width)
+	}
+
+	if description := strings.TrimSpace(spec.Description); description != "" {
+		b.WriteString("\nDESCRIPTION:\n")
+		writeWrapped(b, description, 3, width)
+	}
+
+	if len(spec.Subcommands) != 0 {
+		var rows [][2]string
+		for _, sub := range spec.Subcommands {
+			rows = append(rows, [2]string{strings.Join(append([]string{sub.Name}, sub.Aliases...), ", "), sub.Usage})
+		}
+		b.WriteString("\nCOMMANDS:\n")
+		writeTable(b, rows, width)
+	}
+
+	if len(spec.Positionals) != 0 {
+		var rows [][2]string
+		for _, positional := range spec.Positionals {
+			rows = append(rows, fieldRows(toScreamingSnake(positional.Name), positional)...)
+		}
+		b.WriteString("\nARGUMENTS:\n")
+		writeTable(b, rows, width)
+	}
+
+	var groups []string
+	grouped := map[string][][2]string{}
+	var globals [][2]string
+	for _, flag := range append(append([]*FieldSpec{}, spec.Flags...), spec.GlobalFlags...) {
+		if flag.Hidden {
+			continue
+		}
+		if flag.Global {
+			globals = append(globals, fieldRows(flagLabel(flag), flag)...)
+			continue
+		}
+		if _, ok := grouped[flag.Group]; !ok {
+			groups = append(groups, flag.Group)
+		}
+		grouped[flag.Group] = append(grouped[flag.Group], fieldRows(flagLabel(flag), flag)...)
+	}
+	builtins := [][2]string{{"--help, -h", "show help"}}
+	if spec.Version != "" {
+		builtins = append(builtins, [2]string{"--version, -v", "print the version"})
+	}
+	if _, ok := grouped[""]; !ok {
+		groups = append([]string{""}, groups...)
+	}
+	grouped[""] = append(grouped[""], builtins...)
+	for _, group := range groups {
+		if group == "" {
+			b.WriteString("\nOPTIONS:\n")
+		} else {
+			fmt.Fprintf(b, "\n%s OPTIONS:\n", strings.ToUpper(group))
+		}
+		writeTable(b, grouped[group], width)
+	}
+	if len(globals) != 0 {
+		b.WriteString("\nGLOBAL OPTIONS:\n")
+		writeTable(b, globals, width)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func positionalsUsage(positionals []*FieldSpec) string {
+	var usage []string
+	for _, positional := range positionals {
+		name := toScreamingSnake(positional.Name)
+		if positional.Variadic {
+			name = fmt.Sprintf("%s [%[1]s...]", name)
+		}
+		if !positional.Required {
+			name = fmt.Sprintf("[%s]", name)
+		}
+		usage = append(usage, name)
+	}
+	return strings.Join(usage, " ")
+}
+
+func flagLabel(flag *FieldSpec) string {
+	var names []string
+	for i, name := range append([]string{flag.Name}, flag.Aliases...) {
+		switch {
+		case i == 0 && flag.Negatable:
+			names = append(names, "--[no-]"+name)
+		case utf8.RuneCountInString(name) == 1:
+			names = append(names, "-"+name)
+		default:
+			names = append(names, "--"+name)
+		}
+	}
+	label := strings.Join(names, ", ")
+	if flag.Type != "bool" && flag.Type != "*bool" && flag.Type != "clive.Counter" {
+		label += " value"
+	}
+	return label
+}
+
+// fieldRows returns the rows of the table of the field, the described
+// variants go under it.
+func fieldRows(label string, field *FieldSpec) [][2]string {
+	rows := [][2]string{{label, fieldDetails(field)}}
+	for _, variant := range field.Variants {
+		if description, ok := field.VariantDescriptions[variant]; ok {
+			rows = append(rows, [2]string{"", variant + ": " + description})
+		}
+	}
+	return rows
+}
+
+func fieldDetails(field *FieldSpec) string {
+	var details []string
+	if field.Usage != "" {
+		details = append(details, field.Usage)
+	}
+	if len(field.Variants) != 0 {
+		details = append(details, fmt.Sprintf("(one of: %s)", strings.Join(field.Variants, ", ")))
+	}
+	if field.Default != nil {
+		details = append(details, fmt.Sprintf("(default: %q)", *field.Default))
+	}
+	if field.Required && !field.Positional {
+		details = append(details, "(required)")
+	}
+	if field.Selector != "" {
+		details = append(details, fmt.Sprintf("(with --%s=%s)", field.Selector, field.Variant))
+	}
+	switch field.Repeated {
+	case "":
+	case field.Name:
+		details = append(details, "(repeatable)")
+	default:
+		details = append(details, fmt.Sprintf("(per --%s)", field.Repeated))
+	}
+	if field.IndexedEnv != "" {
+		details = append(details, fmt.Sprintf("[$%s]", field.IndexedEnv))
+	}
+	if len(field.Envs) != 0 && !field.Positional {
+		envs := make([]string, len(field.Envs))
+		for i, env := range field.Envs {
+			envs[i] = "$" + env
+		}
+		details = append(details, fmt.Sprintf("[%s]", strings.Join(envs, ", ")))
+	}
+	return strings.Join(details, " ")
+}
+
+const (
+	maxHelpLabelWidth   = 32
+	minHelpDetailsWidth = 20
+)
+
+func writeTable(b *strings.Builder, rows [][2]string, width int) {
+	labelWidth := 0
+	for _, row := range rows {
+		if l := utf8.RuneCountInString(row[0]); l > labelWidth && l <= maxHelpLabelWidth {
+			labelWidth = l
+		}
+	}
+	indent := 3 + labelWidth + 2
+	stacked := width-indent < minHelpDetailsWidth
+	if stacked {
+		// not enough room for two columns, print details under the label
+		indent = 7
+	}
+	for _, row := range rows {
+		label := row[0]
+		l := utf8.RuneCountInString(label)
+		b.WriteString("   ")
+		b.WriteString(label)
+		if row[1] == "" {
+			b.WriteString("\n")
+			continue
+		}
+		if stacked || l > labelWidth {
+			b.WriteString("\n")
+			b.WriteString(strings.Repeat(" ", indent))
+		} else {
+			b.WriteString(strings.Repeat(" ", indent-3-l))
+		}
+		lines := wrapWords(row[1], width-indent)
+		b.WriteString(strings.Join(lines, "\n"+strings.Repeat(" ", indent)))
+		b.WriteString("\n")
+	}
+}
+
+func writeWrapped(b *strings.Builder, text string, indent, width int) {
+	pad := strings.Repeat(" ", indent)
+	for _, paragraph := range strings.Split(text, "\n") {
+		lines := wrapWords(paragraph, width-indent)
+		if len(lines) == 0 {
+			b.WriteString("\n")
+			continue
+		}
+		for _, line := range lines {
+			b.WriteString(pad)
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+}
+
+// wrapWords splits text into lines no longer than width runes; words longer
+// than width are kept on their own line.
+func wrapWords(text string, width int) (lines []string) {
+	if width < 10 {
+		width = 10
+	}
+	var line strings.Builder
+	lineLen := 0
+	for _, word := range strings.Fields(text) {
+		wordLen := utf8.RuneCountInString(word)
+		if lineLen > 0 && lineLen+1+wordLen > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			line.WriteByte(' ')
+			lineLen++
+		}
+		line.WriteString(word)
+		lineLen += wordLen
+	}
+	if lineLen > 0 {
+		lines = append(lines, line.String())
+	}
+	return
+}
+
+func newFieldSpec(objType reflect.Type, cmdMeta *CommandMetadata) *FieldSpec {
+	spec := &FieldSpec{
+		Name:       cmdMeta.Name,
+		FieldPath:  FieldPath(objType, cmdMeta.Accesses),
+		Tag:        cmdMeta.RawTag,
+		Aliases:    cmdMeta.Aliases,
+		Usage:      cmdMeta.Usage,
+		Default:    cmdMeta.Default,
+		Variants:   cmdMeta.Variants,
+		Group:      cmdMeta.Group,
+		Required:   cmdMeta.Required,
+		Hidden:     cmdMeta.Hidden,
+		Positional: cmdMeta.Positional,
+		Negatable:  cmdMeta.Negatable,
+		Sep:        cmdMeta.Sep,
+		EnvFormat:  cmdMeta.EnvFormat,
+		Global:     cmdMeta.Global,
+	}
+	if cmdMeta.FieldType != nil {
+		spec.Type = cmdMeta.FieldType.String()
+	}
+	if len(cmdMeta.VariantDescriptions) != 0 {
+		spec.VariantDescriptions = cmdMeta.VariantDescriptions
+	}
+	if cmdMeta.Variant != nil {
+		spec.Selector, spec.Variant = cmdMeta.Variant.Selector, cmdMeta.Variant.Name
+	}
+	if cmdMeta.Repeated != nil {
+		spec.Repeated, spec.IndexedEnv = cmdMeta.Repeated.Start, cmdMeta.Repeated.indexedEnv(cmdMeta, "<N>")
+	}
+	if cmdMeta.TypeInterface != nil {
+		spec.Variadic = cmdMeta.IsVariadic()
+		spec.TakesFile = TakesFile(cmdMeta.TypeInterface)
+	}
+	if !cmdMeta.Positional {
+		spec.Envs = cmdMeta.Envs
+	}
+	return spec
+}
+
+func FieldPath(objType reflect.Type, accesses []int) (path []string) {
+	for _, field := range FieldsAlong(objType, accesses) {
+		path = append(path, field.Name)
+	}
+	return
+}
+
+// FieldsAlong returns the struct fields accesses go through, starting from
+// objType.
+func FieldsAlong(objType reflect.Type, accesses []int) (fields []reflect.StructField) {
+	for _, i := range accesses {
+		for objType.Kind() == reflect.Pointer {
+			objType = objType.Elem()
+		}
+		if i < 0 {
+			objType = variantTypeOf(objType).variants[variantIndex(i)].typ
+			continue
+		}
+		if objType.Kind() == reflect.Slice {
+			// fields of the elements of repeated groups
+			objType = objType.Elem()
+			for objType.Kind() == reflect.Pointer {
+				objType = objType.Elem()
+			}
+		}
+		field := objType.Field(i)
+		fields = append(fields, field)
+		objType = field.Type
+	}
+	return
+}
+
+func newCommandSpec(model *CommandModel) *CommandSpec {
+	spec := &CommandSpec{
+		Name:    model.Name,
+		Type:    model.ObjType.String(),
+		Aliases: model.Meta.Aliases,
+		Usage:   model.Meta.Usage,
+	}
+	if desc, ok := model.Obj.(WithDescription); ok {
+		spec.Description = desc.Description()
+	}
+	for i := range model.Flags {
+		spec.Flags = append(spec.Flags, newFieldSpec(model.ObjType, &model.Flags[i]))
+	}
+	for i := range model.Positionals {
+		spec.Positionals = append(spec.Positionals, newFieldSpec(model.ObjType, &model.Positionals[i]))
+	}
+	for _, global := range model.InheritedGlobals() {
+		spec.GlobalFlags = append(spec.GlobalFlags, newFieldSpec(global.ObjType, &global.CommandMetadata))
+	}
+	return spec
+}
+
+// NewCommandSpec returns the CommandSpec of a parsed command, without its
+// subcommands.
+func NewCommandSpec(model *CommandModel, bo *Options) *CommandSpec {
+	record := &commandRecord{
+		spec:        newCommandSpec(model),
+		obj:         model.Obj,
+		positionals: model.Positionals,
+		flags:       model.Flags,
+		bo:          bo,
+		injected:    len(model.Injected) != 0,
+	}
+	record.spec.record = record
+	return record.spec
+}
+
+// Spec returns the CommandSpec tree of a parsed command, subcommands
+// constructed by hand are left out.
+func (model *CommandModel) Spec(bo *Options) *CommandSpec {
+	spec := NewCommandSpec(model, bo)
+	for _, sub := range model.Subcommands {
+		if !sub.Foreign {
+			spec.Subcommands = append(spec.Subcommands, sub.Spec(bo))
+		}
+	}
+	return spec
+}
+
+func (spec *CommandSpec) SetPaths(path string) {
+	spec.Path = path
+	for _, sub := range spec.Subcommands {
+		sub.SetPaths(strings.TrimSpace(path + " " + sub.Name))
+	}
+}
+
+// ArgsUsage returns the usage line of the positional arguments of the command.
+func (spec *CommandSpec) ArgsUsage() string {
+	return positionalsUsage(spec.Positionals)
+}
+
+// Subcommand looks up a command in the tree by its Path.
+func (spec *CommandSpec) Subcommand(path string) *CommandSpec {
+	if spec.Path == path {
+		return spec
+	}
+	for _, sub := range spec.Subcommands {
+		if found := sub.Subcommand(path); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// commandRecord keeps everything clive knows about a command it has parsed.
+type commandRecord struct {
+	spec        *CommandSpec
+	obj         interface{}
+	positionals []CommandMetadata
+	flags       []CommandMetadata
+	bo          *Options
+	// injected reports whether the command struct has fields tagged inject
+	injected bool
+}
+
+// HelpBuffer holds rendered help until the command line library prints it
+// to Out, text help is wrapped to the width of Out.
+type HelpBuffer struct {
+	strings.Builder
+	Out io.Writer
+}
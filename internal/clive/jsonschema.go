@@ -0,0 +1,425 @@
+package clive
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema used to describe commands.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+}
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema parses obj the same way Describe does and returns a JSON Schema for
+// every command, keyed by the command path (see CommandSpec.Path). Flags and
+// positional arguments become properties named after the flag, inline groups
+// become nested objects.
+func JSONSchema(obj interface{}) (map[string]*Schema, error) {
+	spec, err := Describe(obj, Options{})
+	if err != nil {
+		return nil, err
+	}
+	schemas := map[string]*Schema{}
+	err = collectSchemas(spec, schemas)
+	return schemas, err
+}
+
+func collectSchemas(spec *CommandSpec, schemas map[string]*Schema) error {
+	schema, err := commandSchema(spec.record)
+	if err != nil {
+		return err
+	}
+	schemas[spec.Path] = schema
+	for _, sub := range spec.Subcommands {
+		err = collectSchemas(sub, schemas)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newObjectSchema() *Schema {
+	return &Schema{
+		Type:                 "object",
+		Properties:           map[string]*Schema{},
+		AdditionalProperties: new(bool),
+	}
+}
+
+func commandSchema(record *commandRecord) (*Schema, error) {
+	schema := newObjectSchema()
+	schema.Schema = jsonSchemaDialect
+	schema.Title = record.spec.Name
+	schema.Description = record.spec.Usage
+	for _, cmdMeta := range append(append([]CommandMetadata{}, record.positionals...), record.flags...) {
+		property := typeSchema(cmdMeta.FieldType)
+		property.Description = cmdMeta.Usage
+		if len(cmdMeta.Variants) != 0 {
+			if property.Items != nil {
+				property.Items.Enum = cmdMeta.Variants
+			} else {
+				property.Enum = cmdMeta.Variants
+			}
+		}
+		if cmdMeta.Default != nil {
+			def, err := schemaValue(property, *cmdMeta.Default, cmdMeta.Sep)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert default value of %s for JSON schema: %w", cmdMeta.Name, err)
+			}
+			property.Default = def
+		}
+		parents := []*Schema{schema}
+		for i, group := range cmdMeta.GroupPath {
+			parent := parents[len(parents)-1]
+			groupSchema, ok := parent.Properties[group]
+			if !ok {
+				groupSchema = newObjectSchema()
+				if cmdMeta.Repeated != nil && i == cmdMeta.Repeated.depth {
+					groupSchema = &Schema{Type: "array", Items: newObjectSchema()}
+				}
+				parent.Properties[group] = groupSchema
+			}
+			if groupSchema.Type == "array" {
+				// properties of repeated groups are those of their elements
+				groupSchema = groupSchema.Items
+			}
+			parents = append(parents, groupSchema)
+		}
+		parents[len(parents)-1].Properties[cmdMeta.LocalName] = property
+		names := append(append([]string{}, cmdMeta.GroupPath...), cmdMeta.LocalName)
+		if cmdMeta.Required {
+			// fields of variants are required within the object of their
+			// variant only, which is there when it is selected, fields of
+			// repeated groups within every element
+			first := 0
+			switch {
+			case cmdMeta.Variant != nil:
+				first = cmdMeta.Variant.depth
+			case cmdMeta.Repeated != nil:
+				first = cmdMeta.Repeated.depth + 1
+			}
+			for i, parent := range parents[first:] {
+				parent.Required = appendUnique(parent.Required, names[first+i])
+			}
+		}
+		if cmdMeta.Repeated != nil && cmdMeta.Repeated.Required {
+			for i, parent := range parents[:cmdMeta.Repeated.depth+1] {
+				parent.Required = appendUnique(parent.Required, names[i])
+			}
+		}
+	}
+	return schema, nil
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func typeSchema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == Reflected[Counter]():
+		return &Schema{Type: "integer"}
+	case t == Reflected[time.Duration](), isParsedType(t):
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Slice:
+		return &Schema{Type: "array", Items: typeSchema(t.Elem())}
+	case reflect.PointerTo(t).Implements(Reflected[encoding.TextUnmarshaler]()):
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: new(float64)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	default:
+		return &Schema{Type: "string"}
+	}
+}
+
+// schemaValue converts a value written in the command line syntax to the
+// JSON representation matching schema.
+func schemaValue(schema *Schema, s, sep string) (interface{}, error) {
+	switch schema.Type {
+	case "integer":
+		if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return i, nil
+		}
+		return strconv.ParseUint(s, 0, 64)
+	case "number":
+		return strconv.ParseFloat(s, 64)
+	case "boolean":
+		return ParseBool(s)
+	case "array":
+		items, err := SplitList(s, sep)
+		if err != nil {
+			return nil, err
+		}
+		values := []interface{}{}
+		for _, item := range items {
+			v, err := schemaValue(schema.Items, item, sep)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	default:
+		return s, nil
+	}
+}
+
+// BindJSON parses obj the same way Describe does and populates the command found
+// at commandPath (see CommandSpec.Path) from a JSON document matching the
+// schema returned by JSONSchema.
+func BindJSON(obj interface{}, commandPath string, data []byte) error {
+	spec, err := Describe(obj, Options{})
+	if err != nil {
+		return err
+	}
+	record, err := findCommandRecord(spec, commandPath)
+	if err != nil {
+		return err
+	}
+	schema, err := commandSchema(record)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc map[string]interface{}
+	err = decoder.Decode(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to decode JSON for command %q: %w", commandPath, err)
+	}
+	err = checkUnknownProperties(schema, doc, "")
+	if err != nil {
+		return err
+	}
+
+	groups := NilGroups(record.obj, record.flags)
+	objValue := reflect.ValueOf(record.obj).Elem()
+	for _, cmdMeta := range append(append([]CommandMetadata{}, record.positionals...), record.flags...) {
+		if cmdMeta.Repeated != nil {
+			continue
+		}
+		field := FieldByAccesses(objValue, cmdMeta.Accesses)
+		property := strings.Join(append(append([]string{}, cmdMeta.GroupPath...), cmdMeta.LocalName), ".")
+		value, found := lookupProperty(doc, cmdMeta.GroupPath, cmdMeta.LocalName)
+		if !field.IsValid() {
+			// the field of a variant that isn't selected
+			if found {
+				return fmt.Errorf("JSON property %s can only be used with %s=%s", property, cmdMeta.Variant.Selector, cmdMeta.Variant.Name)
+			}
+			continue
+		}
+		if !found {
+			switch {
+			case cmdMeta.Default != nil:
+				err = cmdMeta.SetFromString(field, *cmdMeta.Default)
+			case cmdMeta.Required:
+				err = errors.New("property is required")
+			}
+		} else {
+			err = bindJSONValue(&cmdMeta, field, value)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set field %s from JSON property %s: %w", strings.Join(FieldPath(objValue.Type(), cmdMeta.Accesses), "."), property, err)
+		}
+	}
+	for _, group := range repeatedGroups(record.flags) {
+		err = bindJSONRepeated(objValue, group, doc)
+		if err != nil {
+			return err
+		}
+	}
+	ResetGroups(record.obj, groups, record.flags, func(cmdMeta *CommandMetadata) bool {
+		_, found := lookupProperty(doc, cmdMeta.GroupPath, cmdMeta.LocalName)
+		return found
+	})
+	return nil
+}
+
+// bindJSONRepeated sets the slice field of a repeated group from an array of
+// objects.
+func bindJSONRepeated(objValue reflect.Value, group []*CommandMetadata, doc map[string]interface{}) error {
+	rm := group[0].Repeated
+	groupPath := group[0].GroupPath[:rm.depth+1]
+	property := strings.Join(groupPath, ".")
+	fail := func(err error) error {
+		return fmt.Errorf("failed to set field %s from JSON property %s: %w", strings.Join(FieldPath(objValue.Type(), rm.accesses), "."), property, err)
+	}
+	value, found := lookupProperty(doc, groupPath[:rm.depth], groupPath[rm.depth])
+	if !found {
+		if rm.Required {
+			return fail(errors.New("property is required"))
+		}
+		return nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return fail(fmt.Errorf("expected an array, got %T", value))
+	}
+	slice := reflect.MakeSlice(rm.sliceType, 0, len(items))
+	for i, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return fail(fmt.Errorf("expected an object at index %d, got %T", i, item))
+		}
+		elem, err := rm.newElement(group, func(cmdMeta *CommandMetadata, field reflect.Value) (bool, error) {
+			value, found := lookupProperty(object, cmdMeta.GroupPath[rm.depth+1:], cmdMeta.LocalName)
+			if !found {
+				return false, nil
+			}
+			return true, bindJSONValue(cmdMeta, field, value)
+		})
+		var missing *RequiredFlagsError
+		if errors.As(err, &missing) {
+			for _, cmdMeta := range group {
+				if cmdMeta.Name == missing.Names[0] {
+					err = fmt.Errorf("property %s is required", strings.Join(append(append([]string{}, cmdMeta.GroupPath[rm.depth+1:]...), cmdMeta.LocalName), "."))
+				}
+			}
+		}
+		if err != nil {
+			return fail(fmt.Errorf("element %d: %w", i, err))
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	FieldByAccesses(objValue, rm.accesses).Elem().Set(slice)
+	return nil
+}
+
+func findCommandRecord(spec *CommandSpec, commandPath string) (*commandRecord, error) {
+	for _, name := range strings.Fields(commandPath) {
+		var found *CommandSpec
+		for _, sub := range spec.Subcommands {
+			if sub.Name == name {
+				found = sub
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("no command %q in %q", name, commandPath)
+		}
+		spec = found
+	}
+	return spec.record, nil
+}
+
+func checkUnknownProperties(schema *Schema, doc map[string]interface{}, path string) error {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		property, ok := schema.Properties[key]
+		if !ok {
+			return fmt.Errorf("unknown JSON property %s%s", path, key)
+		}
+		if nested, ok := doc[key].(map[string]interface{}); ok && property.Type == "object" {
+			err := checkUnknownProperties(property, nested, path+key+".")
+			if err != nil {
+				return err
+			}
+		}
+		if elements, ok := doc[key].([]interface{}); ok && property.Items != nil && property.Items.Type == "object" {
+			for i, element := range elements {
+				nested, ok := element.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				err := checkUnknownProperties(property.Items, nested, fmt.Sprintf("%s%s[%d].", path, key, i))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func lookupProperty(doc map[string]interface{}, groupPath []string, name string) (interface{}, bool) {
+	for _, group := range groupPath {
+		nested, ok := doc[group].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		doc = nested
+	}
+	value, ok := doc[name]
+	if value == nil {
+		return nil, false
+	}
+	return value, ok
+}
+
+func jsonScalarString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("expected a scalar value, got %T", value)
+	}
+}
+
+func bindJSONValue(cmdMeta *CommandMetadata, field reflect.Value, value interface{}) error {
+	items, isArray := value.([]interface{})
+	if !isArray {
+		s, err := jsonScalarString(value)
+		if err != nil {
+			return err
+		}
+		return cmdMeta.SetValueFromString(field, s)
+	}
+	if !cmdMeta.IsVariadic() {
+		return errors.New("got an array for a scalar field")
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		s, err := jsonScalarString(item)
+		if err != nil {
+			return err
+		}
+		values[i] = s
+	}
+	return cmdMeta.SetValueFromStrings(field, values)
+}
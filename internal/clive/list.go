@@ -0,0 +1,131 @@
+package clive
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DefaultSep separates the items of slice values unless a `sep:` tag says
+// otherwise.
+const DefaultSep = ","
+
+// Formats of slice environment variables, set with the `envformat:` tag.
+const (
+	// EnvFormatList splits the value with the separator of the field, the
+	// default.
+	EnvFormatList = "list"
+	// EnvFormatLines takes every non-empty line as an item.
+	EnvFormatLines = "lines"
+	// EnvFormatJSON reads a JSON array.
+	EnvFormatJSON = "json"
+)
+
+// SplitList splits a slice value into its items at sep. A backslash escapes
+// sep, a double quote or another backslash, other backslashes are kept. An
+// item starting with a double quote runs to the closing quote, seps inside
+// it are kept.
+func SplitList(s, sep string) ([]string, error) {
+	if sep == "" {
+		sep = DefaultSep
+	}
+	var items []string
+	var item strings.Builder
+	quoted, itemStart := false, true
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && (strings.HasPrefix(s[i+1:], sep) || s[i+1] == '\\' || s[i+1] == '"'):
+			if strings.HasPrefix(s[i+1:], sep) {
+				item.WriteString(sep)
+				i += 1 + len(sep)
+			} else {
+				item.WriteByte(s[i+1])
+				i += 2
+			}
+		case s[i] == '"' && itemStart:
+			quoted = true
+			i++
+		case s[i] == '"' && quoted:
+			quoted = false
+			i++
+			if i < len(s) && !strings.HasPrefix(s[i:], sep) {
+				return nil, fmt.Errorf("unexpected text after closing quote in %q", s)
+			}
+		case !quoted && strings.HasPrefix(s[i:], sep):
+			items = append(items, item.String())
+			item.Reset()
+			i += len(sep)
+			itemStart = true
+			continue
+		default:
+			item.WriteByte(s[i])
+			i++
+		}
+		itemStart = false
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	return append(items, item.String()), nil
+}
+
+// SplitEnv splits the value of a slice environment variable given in format.
+func SplitEnv(s, sep, format string) ([]string, error) {
+	// an empty variable is an empty list, not a list of one empty item
+	if s == "" {
+		return nil, nil
+	}
+	switch format {
+	case EnvFormatLines:
+		var items []string
+		for _, line := range strings.Split(s, "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if strings.TrimSpace(line) != "" {
+				items = append(items, line)
+			}
+		}
+		return items, nil
+	case EnvFormatJSON:
+		var raw []json.RawMessage
+		err := json.Unmarshal([]byte(s), &raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected a JSON array: %w", err)
+		}
+		items := make([]string, len(raw))
+		for i, item := range raw {
+			// strings are unquoted, numbers and bools are kept as written
+			if json.Unmarshal(item, &items[i]) != nil {
+				items[i] = string(item)
+			}
+		}
+		return items, nil
+	}
+	return SplitList(s, sep)
+}
+
+func checkEnvFormat(format string) error {
+	switch format {
+	case "", EnvFormatList, EnvFormatLines, EnvFormatJSON:
+		return nil
+	}
+	return fmt.Errorf("unknown envformat %q, expected %s, %s or %s", format, EnvFormatList, EnvFormatLines, EnvFormatJSON)
+}
+
+// Split splits a slice value given on the command line or as a default.
+func (cmdMeta *CommandMetadata) Split(s string) ([]string, error) {
+	return SplitList(s, cmdMeta.Sep)
+}
+
+// SetFromString sets field from a single string, splitting slice values at
+// the separator of the field.
+func (cmdMeta *CommandMetadata) SetFromString(field reflect.Value, s string) error {
+	if !cmdMeta.IsVariadic() {
+		return cmdMeta.SetValueFromString(field, s)
+	}
+	items, err := cmdMeta.Split(s)
+	if err != nil {
+		return err
+	}
+	return cmdMeta.SetValueFromStrings(field, items)
+}
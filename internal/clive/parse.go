@@ -0,0 +1,590 @@
+package clive
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// CommandModel is a command struct parsed into everything needed to build it
+// for a command line library. Parsing doesn't depend on the library, Build
+// turns models into urfave/cli commands and Describe into CommandSpecs that
+// other backends build from.
+type CommandModel struct {
+	Obj     interface{} // pointer to the command struct
+	ObjType reflect.Type
+	// Command is the embedded Command of the library, see Library.
+	Command interface{}
+	// Meta is read from the tag of the embedded command
+	Meta CommandMetadata
+	Name string
+	// Path identifies the command in App.Metadata, parentPath is the Path of
+	// its parent or empty for the root command.
+	Path       string
+	ParentPath string
+	// Run is the Run field of the library, if set.
+	Run interface{}
+
+	Positionals []CommandMetadata
+	Flags       []CommandMetadata
+	ArgsUsage   string
+	Subcommands []*CommandModel
+	// Globals are the global flags of the ancestors, see GlobalFlag.
+	Globals []GlobalFlag
+	// Injected are the indices of the fields tagged inject.
+	Injected []int
+
+	// Foreign is set for subcommands constructed by hand, there is nothing to
+	// parse in them.
+	Foreign bool
+	// Generated is set for command structs with generated code, see
+	// Options.Generated.
+	Generated *GeneratedCommand
+}
+
+// ParseCommand parses the command struct obj and its subcommands.
+func ParseCommand(obj interface{}, parentCommandPath string, bo *Options) (*CommandModel, error) {
+	if foreign(obj) {
+		return &CommandModel{Obj: obj, ParentPath: parentCommandPath, Foreign: true}, nil
+	}
+	if obj == nil {
+		return nil, ErrNil
+	}
+	if bo.Generated != nil {
+		gen, ok, err := bo.Generated(obj)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return parseGenerated(obj, gen, parentCommandPath, bo)
+		}
+	}
+
+	// recursively dereference
+	objValue := reflect.ValueOf(obj)
+	objIsPointer := false
+	for objValue.Kind() == reflect.Ptr {
+		objValue = objValue.Elem()
+		objIsPointer = true
+	}
+	if !objValue.CanAddr() || !objIsPointer {
+		return nil, &ByValueError{objValue.Type().Name()}
+	}
+
+	objType := objValue.Type()
+
+	if objType.NumField() == 0 {
+		return nil, &WrongFirstFieldError{NumFields: 0}
+	}
+
+	// the first field must be an embedded command of a library
+	command, library, meta, err := parseCommandField(objType.Field(0), objValue.Field(0), bo)
+	if err != nil {
+		if wffe, ok := err.(*WrongFirstFieldError); ok { //nolint:errorlint // parseCommandField returns WrongFirstFieldError explicitly
+			wffe.NumFields = objType.NumField()
+		}
+		return nil, err
+	}
+	model := &CommandModel{
+		Obj:        objValue.Addr().Interface(),
+		ObjType:    objType,
+		Command:    command,
+		Meta:       meta,
+		Name:       meta.Name,
+		ParentPath: parentCommandPath,
+	}
+
+	// name from tags takes precedence
+	if model.Name == "" && library.CommandName != nil {
+		model.Name = library.CommandName(command)
+	}
+	if model.Name == "" {
+		model.Name = strings.ToLower(objType.Name())
+	}
+
+	model.Path = commandPath(parentCommandPath, model.Name)
+
+	for i := 1; i < objType.NumField(); i++ {
+		fieldType := objType.Field(i)
+		if fieldType.Name == "Subcommands" {
+			model.Subcommands, err = parseSubcommands(model.Path, objValue.Field(i).Addr(), bo)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if isRunField(fieldType) {
+			if run := objValue.Field(i); !run.IsNil() {
+				model.Run = run.Interface()
+			}
+			continue
+		}
+		if tag, _ := ParseTag(fieldType.Tag.Get("cli")); tag.Inject {
+			model.Injected = append(model.Injected, i)
+			continue
+		}
+		err = parseFieldOrPositional(nil, []int{i}, fieldType, &model.Positionals, &model.Flags, bo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	model.ArgsUsage, err = argsUsage(model.Positionals)
+	if err != nil {
+		return nil, err
+	}
+	if parentCommandPath == "" {
+		model.inheritGlobals(nil)
+	}
+	return model, nil
+}
+
+// commandPath returns the Path of the command name with the parent
+// parentCommandPath.
+func commandPath(parentCommandPath, name string) string {
+	path := "/"
+	if parentCommandPath != "" {
+		path = fmt.Sprintf("%s%s/", path, parentCommandPath)
+	}
+	return fmt.Sprintf("%s%s", path, name)
+}
+
+func parseSubcommands(parentCommandPath string, subcommandsField reflect.Value, bo *Options) (models []*CommandModel, err error) {
+	subcommandsFieldValue := subcommandsField
+	for subcommandsFieldValue.Kind() == reflect.Ptr {
+		subcommandsFieldValue = subcommandsFieldValue.Elem()
+	}
+
+	subcommandsType := subcommandsFieldValue.Type()
+	subcommands := make([]interface{}, 0, subcommandsType.NumField())
+	for i := 0; i < subcommandsType.NumField(); i++ {
+		subcommandFieldType := subcommandsType.Field(i)
+		subcommand := subcommandsFieldValue.Field(i)
+		if subcommandFieldType.Type.Kind() == reflect.Struct {
+			var group []*CommandModel
+			group, err = parseSubcommands(parentCommandPath, subcommand, bo)
+			if err != nil {
+				return
+			}
+			models = append(models, group...)
+			continue
+		}
+
+		if subcommandFieldType.Type.Kind() != reflect.Pointer {
+			return nil, fmt.Errorf("type of subcommand (%s) for %s is passed by value, not by reference", subcommandFieldType.Type.Name(), parentCommandPath)
+		}
+		if subcommandFieldType.Type.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("type of subcommand (%v) for %s is a double pointer (Kind: %v), should be a pointer to struct", subcommandFieldType.Type.Name(), parentCommandPath, subcommand.Kind())
+		}
+		if subcommand.IsNil() {
+			subcommand.Set(reflect.New(subcommandFieldType.Type.Elem()))
+		}
+		subcommands = append(subcommands, subcommand.Interface())
+	}
+	for _, subcommand := range subcommands {
+		var model *CommandModel
+		model, err = ParseCommand(subcommand, parentCommandPath, bo)
+		if err != nil {
+			return
+		}
+		models = append(models, model)
+	}
+	return
+}
+
+func parseCommandField(fieldType reflect.StructField, fieldValue reflect.Value, bo *Options) (interface{}, Library, CommandMetadata, error) {
+	library, ok := libraryOf(fieldType.Type)
+	if fieldType.Name != "Command" || !ok {
+		return nil, library, CommandMetadata{}, &WrongFirstFieldError{
+			NumFields: 0,
+			FieldName: fieldType.Name,
+			Type:      fieldType.Type.String(),
+		}
+	}
+
+	if fieldValue.IsNil() {
+		fieldValue.Set(reflect.New(fieldType.Type.Elem()))
+	}
+
+	cmdMeta, err := parseMeta("", nil, fieldType, bo)
+	if err != nil {
+		return nil, library, cmdMeta, fmt.Errorf("failed to read cmdMeta tag on the embedded clive.Command struct pointer: %w", err)
+	}
+	return fieldValue.Interface(), library, cmdMeta, nil
+}
+
+// argsUsage checks the order of positional arguments and returns their usage
+// line.
+func argsUsage(positionals []CommandMetadata) (string, error) {
+	optionalStarted := false
+	var variadicStarted *string
+	var positionalUsage []string
+	for _, positional := range positionals {
+		if variadicStarted != nil {
+			return "", &PositionalAfterVariadicError{CurrentName: positional.Name, FirstName: *variadicStarted}
+		}
+		if positional.Hidden {
+			return "", &HiddenPositionalError{positional.Name}
+		}
+		usage := toScreamingSnake(positional.Name)
+		if positional.IsVariadic() {
+			variadicStarted = new(string)
+			*variadicStarted = positional.Name
+			usage = fmt.Sprintf("%s [%[1]s]", usage)
+		}
+		optional := !positional.Required
+		if optional {
+			optionalStarted = true
+			usage = fmt.Sprintf("[%s]", usage)
+		} else if optionalStarted {
+			return "", fmt.Errorf("positional argument %s cannot be non-optional after an optional argument", positional.Name)
+		}
+		positionalUsage = append(positionalUsage, usage)
+	}
+	return strings.Join(positionalUsage, " "), nil
+}
+
+type CommandMetadata struct {
+	TypeInterface
+	Tag
+	RawTag    string
+	Variants  []string
+	Group     string
+	FieldType reflect.Type
+	Accesses  []int
+
+	// VariantDescriptions describe variants in help, see
+	// HasVariantDescriptions.
+	VariantDescriptions map[string]string
+	// LocalName is the name of the field without the inline group prefix,
+	// GroupPath lists local names of the enclosing inline groups.
+	LocalName string
+	GroupPath []string
+	// Variant is set for fields of variant implementations, see
+	// RegisterVariant.
+	Variant *variantMeta
+	// Repeated is set for fields of repeated inline groups, see
+	// RepeatedMeta.
+	Repeated *RepeatedMeta
+	// optional lists the accesses of the enclosing pointer inline groups,
+	// they stay nil unless one of their flags is set.
+	optional [][]int
+}
+
+func parseFieldOrPositional(parent *CommandMetadata, accesses []int, fieldType reflect.StructField, positionals, flags *[]CommandMetadata, bo *Options) (err error) {
+	prefix := ""
+	if parent != nil {
+		prefix = parent.Name
+	}
+	var cmdMeta CommandMetadata
+	cmdMeta, err = parseMeta(prefix, accesses, fieldType, bo)
+	if err != nil {
+		return
+	}
+	if cmdMeta.Skipped {
+		return
+	}
+	if cmdMeta.Inject {
+		if parent != nil {
+			err = fmt.Errorf("injected field %s must be a field of the command struct", fieldType.Name)
+		}
+		return
+	}
+	if parent != nil {
+		cmdMeta.GroupPath = append(append([]string{}, parent.GroupPath...), parent.LocalName)
+		if parent.PrefixSet && parent.Prefix == "" {
+			// groups without a prefix are flattened into their parent
+			cmdMeta.GroupPath = append([]string{}, parent.GroupPath...)
+		}
+		// fields of variants, including those of inline groups in them
+		cmdMeta.Variant = parent.Variant
+		cmdMeta.Repeated = parent.Repeated
+		cmdMeta.optional = parent.optional
+		cmdMeta.Global = cmdMeta.Global || parent.Global
+	}
+	if cmdMeta.Inline {
+		structType := fieldType.Type
+		if structType.Kind() == reflect.Slice {
+			return parseRepeated(&cmdMeta, fieldType, flags, bo)
+		}
+		if structType.Kind() == reflect.Pointer && structType.Elem().Kind() == reflect.Struct {
+			if !fieldType.IsExported() {
+				err = fmt.Errorf("pointer inline field %s must be exported", fieldType.Name)
+				return
+			}
+			structType = structType.Elem()
+			cmdMeta.optional = append(append([][]int{}, cmdMeta.optional...), cmdMeta.Accesses)
+		}
+		if structType.Kind() != reflect.Struct {
+			err = fmt.Errorf("inline field %s is not a struct or a slice of structs", fieldType.Name)
+			return
+		}
+		startFlags, startPositionals := len(*flags), len(*positionals)
+		for i := 0; i < structType.NumField(); i++ {
+			fT := structType.Field(i)
+
+			fAccesses := make([]int, len(cmdMeta.Accesses)+1)
+			copy(fAccesses, cmdMeta.Accesses)
+			fAccesses[len(fAccesses)-1] = i
+
+			err = parseFieldOrPositional(&cmdMeta, fAccesses, fT, positionals, flags, bo)
+			if err != nil {
+				err = fmt.Errorf("parsing inline field %s: %w", fieldType.Name, err)
+				return
+			}
+		}
+		return applyDefaults(&cmdMeta, fieldType, (*flags)[startFlags:], (*positionals)[startPositionals:], bo)
+	}
+	if cmdMeta.Global {
+		if err = checkGlobal(&cmdMeta, fieldType); err != nil {
+			return
+		}
+	}
+	if vt, ok := cmdMeta.TypeInterface.(*VariantType); ok {
+		if cmdMeta.Positional {
+			err = fmt.Errorf("variant field %s can't be positional", fieldType.Name)
+			return
+		}
+		if cmdMeta.Repeated != nil {
+			err = fmt.Errorf("variant field %s can't be in a repeated group", fieldType.Name)
+			return
+		}
+		*flags = append(*flags, cmdMeta)
+		return parseVariants(&cmdMeta, vt, flags, bo)
+	}
+
+	if cmdMeta.Positional {
+		*positionals = append(*positionals, cmdMeta)
+	} else {
+		// automatically turn fields that begin with Flag into cli.Flag objects
+		*flags = append(*flags, cmdMeta)
+	}
+	return
+}
+
+func parseMeta(prefix string, accesses []int, fieldType reflect.StructField, bo *Options) (cmdMeta CommandMetadata, err error) {
+	s := fieldType.Tag.Get("cli")
+
+	cmdMeta.RawTag = s
+	cmdMeta.Tag, err = ParseTag(s)
+	if err != nil || cmdMeta.Skipped {
+		return cmdMeta, err
+	}
+	cmdMeta.Accesses = accesses
+	_, isCommand := libraryOf(fieldType.Type)
+	if fieldType.Anonymous && !cmdMeta.Inline && !isCommand && embeddedGroup(fieldType) {
+		// embedded structs are inline groups, without a prefix unless one
+		// is given
+		cmdMeta.Inline, cmdMeta.PrefixSet = true, true
+	}
+	if cmdMeta.PrefixSet && !cmdMeta.Inline {
+		return cmdMeta, fmt.Errorf("prefix is only supported for inline fields, %s is not one", fieldType.Name)
+	}
+	if cmdMeta.Positional {
+		if !cmdMeta.RequiredSet {
+			cmdMeta.Required = cmdMeta.Default == nil
+		}
+	}
+	if cmdMeta.Inject {
+		return cmdMeta, err
+	}
+	if !isCommand {
+		if !cmdMeta.Inline {
+			cmdMeta.TypeInterface, err = flagType(fieldType)
+			if err != nil {
+				err = fmt.Errorf("cant find type for %s field: %s", fieldType.Name, err.Error())
+				return cmdMeta, err
+			}
+			if cmdMeta.Layout != "" {
+				lt, ok := cmdMeta.TypeInterface.(LayoutType)
+				if ok {
+					cmdMeta.TypeInterface, err = lt.WithLayout(cmdMeta.Layout)
+				}
+				if !ok || err != nil {
+					return cmdMeta, fmt.Errorf("layout is not supported for field %s of type %s", fieldType.Name, fieldType.Type)
+				}
+			}
+			if (cmdMeta.Sep != "" || cmdMeta.EnvFormat != "") && !cmdMeta.IsVariadic() {
+				return cmdMeta, fmt.Errorf("sep and envformat are only supported for slices, field %s has type %s", fieldType.Name, fieldType.Type)
+			}
+			if cmdMeta.Unit != "" {
+				ut, ok := cmdMeta.TypeInterface.(UnitType)
+				if ok {
+					cmdMeta.TypeInterface, err = ut.WithUnit(cmdMeta.Unit)
+				} else {
+					err = errors.New("type takes no unit")
+				}
+				if err != nil {
+					return cmdMeta, fmt.Errorf("bad unit %q for field %s of type %s: %s", cmdMeta.Unit, fieldType.Name, fieldType.Type, err.Error())
+				}
+			}
+		}
+		if cmdMeta.Name == "" {
+			cmdMeta.Name = fieldType.Name
+		}
+		if !cmdMeta.Inline {
+			set, err := variantSetOf(fieldType.Type)
+			if err != nil {
+				return cmdMeta, err
+			}
+			if set != nil {
+				cmdMeta.Variants, cmdMeta.VariantDescriptions = set.names, set.descriptions
+				cmdMeta.TypeInterface = &strictVariants{TypeInterface: cmdMeta.TypeInterface, set: set}
+			}
+		}
+		if vt, ok := cmdMeta.TypeInterface.(*VariantType); ok {
+			cmdMeta.Variants = vt.names()
+		}
+		cmdMeta.FieldType = fieldType.Type
+		if !cmdMeta.Inline {
+			cmdMeta.Negatable, err = negatable(&cmdMeta, fieldType.Name, bo)
+			if err != nil {
+				return cmdMeta, err
+			}
+		}
+	}
+	cmdMeta.LocalName = toKebab(cmdMeta.Name)
+	if cmdMeta.Name != "" {
+		cmdMeta.Name = toKebab(cmdMeta.Name)
+	}
+	if cmdMeta.PrefixSet {
+		// the prefix of the flags of the group replaces its name
+		cmdMeta.Name = cmdMeta.Prefix
+		if cmdMeta.Prefix != "" {
+			cmdMeta.LocalName = cmdMeta.Prefix
+		}
+	}
+	if prefix != "" {
+		// the prefix is already a flag name, variant names are kept as is
+		if cmdMeta.Name == "" {
+			cmdMeta.Name = prefix
+		} else {
+			cmdMeta.Name = prefix + "-" + cmdMeta.Name
+		}
+		cmdMeta.Group = prefix
+	}
+	if len(cmdMeta.Envs) == 0 {
+		cmdMeta.Envs = []string{
+			bo.EnvVar(toScreamingSnake(cmdMeta.Name)),
+		}
+	}
+	if sv, ok := cmdMeta.TypeInterface.(*strictVariants); ok {
+		err = sv.checkDefault(&cmdMeta)
+	}
+	return cmdMeta, err
+}
+
+// applyDefaults sets the defaults given by the defaults tag of the inline
+// group to its flags and positional arguments. Groups are applied after the
+// groups inside them, so the outermost override wins.
+func applyDefaults(group *CommandMetadata, fieldType reflect.StructField, flags, positionals []CommandMetadata, bo *Options) error {
+	if len(group.Defaults) == 0 {
+		return nil
+	}
+	used := map[string]bool{}
+	for _, fields := range [][]CommandMetadata{flags, positionals} {
+		for i := range fields {
+			cmdMeta := &fields[i]
+			name := cmdMeta.Name
+			if group.Name != "" {
+				name = strings.TrimPrefix(name, group.Name+"-")
+			}
+			value, ok := group.Defaults[name]
+			if !ok {
+				continue
+			}
+			used[name] = true
+			cmdMeta.Default = &value
+			if sv, ok := cmdMeta.TypeInterface.(*strictVariants); ok {
+				if err := sv.checkDefault(cmdMeta); err != nil {
+					return err
+				}
+			}
+			if err := cmdMeta.SetFromString(reflect.New(cmdMeta.FieldType), value); err != nil {
+				return fmt.Errorf("defaults of inline field %s: bad default of %s: %w", fieldType.Name, cmdMeta.Name, err)
+			}
+			if cmdMeta.Positional && !cmdMeta.RequiredSet {
+				cmdMeta.Required = false
+			}
+			if !cmdMeta.Positional {
+				var err error
+				cmdMeta.Negatable, err = negatable(cmdMeta, name, bo)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(group.Defaults))
+	for name := range group.Defaults {
+		if !used[name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) != 0 {
+		sort.Strings(names)
+		return fmt.Errorf("defaults of inline field %s: no flag named %s", fieldType.Name, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// embeddedGroup reports whether the embedded field is an inline group: a
+// struct or a pointer to one without a flag type.
+func embeddedGroup(fieldType reflect.StructField) bool {
+	t := fieldType.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	_, err := flagType(fieldType)
+	return err != nil
+}
+
+// negatable decides whether a flag gets a --no-<name> counterpart: bool flags
+// tagged negatable do, and with NegatableBools so do bool flags defaulting to
+// true.
+func negatable(cmdMeta *CommandMetadata, fieldName string, bo *Options) (bool, error) {
+	t := cmdMeta.FieldType
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	isBoolFlag := t.Kind() == reflect.Bool && !cmdMeta.Positional
+	if cmdMeta.NegatableSet {
+		if cmdMeta.Negatable && !isBoolFlag {
+			return false, fmt.Errorf("negatable field %s is not a bool flag", fieldName)
+		}
+		return cmdMeta.Negatable, nil
+	}
+	if !bo.NegatableBools || !isBoolFlag || cmdMeta.Default == nil {
+		return false, nil
+	}
+	def, err := ParseBool(*cmdMeta.Default)
+	return err == nil && def, nil
+}
+
+// NegatedName returns the name of the flag negating the flag name.
+func NegatedName(name string) string {
+	return "no-" + name
+}
+
+// FlagUsage returns the usage text of a flag, including the list of
+// possible values for types implementing HasVariants.
+func (cmdMeta *CommandMetadata) FlagUsage() string {
+	return UsageWithVariants(cmdMeta.Usage, cmdMeta.Variants)
+}
+
+// UsageWithVariants appends the list of possible values to a flag usage text.
+func UsageWithVariants(usage string, variants []string) string {
+	if len(variants) == 0 {
+		return usage
+	}
+	var usageArr []string
+	if len(usage) > 0 {
+		usageArr = append(usageArr, usage)
+	}
+	usageArr = append(usageArr, fmt.Sprintf("possible values: [%s]", strings.Join(variants, ", ")))
+	return strings.Join(usageArr, ", ")
+}
@@ -0,0 +1,308 @@
+package clive
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RepeatedMeta is set on the flags of repeated inline groups: slices of
+// structs tagged inline. Every element of the slice gets the flags of the
+// struct, each occurrence of the flag of its first field starts a new element
+// and the flags of the other fields fill the last one.
+type RepeatedMeta struct {
+	// Group is the prefix of the names of the flags, Start the name of the
+	// flag starting a new element.
+	Group string
+	Start string
+	// Env is the prefix of the indexed environment variables, UPSTREAM for
+	// UPSTREAM_0_HOST.
+	Env string
+	// Required groups need at least one element.
+	Required bool
+	// accesses lead to the slice field, depth is the length of the GroupPath
+	// of the group.
+	accesses  []int
+	depth     int
+	sliceType reflect.Type
+}
+
+// indexedEnv returns the environment variable of the flag cmdMeta for the
+// element index.
+func (rm *RepeatedMeta) indexedEnv(cmdMeta *CommandMetadata, index string) string {
+	name := strings.TrimPrefix(cmdMeta.Name, rm.Group+"-")
+	return rm.Env + "_" + index + "_" + toScreamingSnake(name)
+}
+
+// parseRepeated adds the flags of the struct elements of the slice field
+// group to flags.
+func parseRepeated(group *CommandMetadata, fieldType reflect.StructField, flags *[]CommandMetadata, bo *Options) error {
+	if group.Variant != nil || group.Repeated != nil {
+		return fmt.Errorf("repeated field %s can't be in a variant or another repeated group", fieldType.Name)
+	}
+	if group.Positional {
+		return fmt.Errorf("repeated field %s can't be positional", fieldType.Name)
+	}
+	if group.Name == "" {
+		return fmt.Errorf("repeated field %s needs a prefix", fieldType.Name)
+	}
+	structType := fieldType.Type.Elem()
+	for structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("inline field %s is not a struct or a slice of structs", fieldType.Name)
+	}
+	group.Repeated = &RepeatedMeta{
+		Group:     group.Name,
+		Env:       bo.EnvVar(toScreamingSnake(group.Name)),
+		Required:  group.Required,
+		accesses:  group.Accesses,
+		depth:     len(group.GroupPath),
+		sliceType: fieldType.Type,
+	}
+	var positionals []CommandMetadata
+	start := len(*flags)
+	for i := 0; i < structType.NumField(); i++ {
+		accesses := append(append([]int{}, group.Accesses...), i)
+		err := parseFieldOrPositional(group, accesses, structType.Field(i), &positionals, flags, bo)
+		if err != nil {
+			return fmt.Errorf("parsing repeated field %s: %w", fieldType.Name, err)
+		}
+	}
+	if len(positionals) != 0 {
+		return fmt.Errorf("repeated field %s can't have positional arguments", fieldType.Name)
+	}
+	if len(*flags) == start {
+		return fmt.Errorf("repeated field %s has no flags", fieldType.Name)
+	}
+	if err := applyDefaults(group, fieldType, (*flags)[start:], nil, bo); err != nil {
+		return err
+	}
+	group.Repeated.Start = (*flags)[start].Name
+	for i := start; i < len(*flags); i++ {
+		cmdMeta := &(*flags)[i]
+		if cmdMeta.Negatable && cmdMeta.NegatableSet {
+			return fmt.Errorf("flag %s of repeated field %s can't be negatable", cmdMeta.Name, fieldType.Name)
+		}
+		// elements read indexed environment variables
+		cmdMeta.Negatable, cmdMeta.Envs = false, nil
+	}
+	return nil
+}
+
+// Occurrence is a value given for a flag of a repeated group.
+type Occurrence struct {
+	Name  string
+	Value string
+}
+
+// repeatedGroups returns the flags of every repeated group among flags.
+func repeatedGroups(flags []CommandMetadata) (groups [][]*CommandMetadata) {
+	index := map[string]int{}
+	for i := range flags {
+		cmdMeta := &flags[i]
+		if cmdMeta.Repeated == nil {
+			continue
+		}
+		j, ok := index[cmdMeta.Repeated.Group]
+		if !ok {
+			j = len(groups)
+			index[cmdMeta.Repeated.Group] = j
+			groups = append(groups, nil)
+		}
+		groups[j] = append(groups[j], cmdMeta)
+	}
+	return
+}
+
+// elements splits the values given for the flags of a repeated group into
+// the values of every element.
+func (rm *RepeatedMeta) elements(given []Occurrence) ([][]Occurrence, error) {
+	var elements [][]Occurrence
+	for _, o := range given {
+		if o.Name == rm.Start {
+			elements = append(elements, nil)
+		} else if len(elements) == 0 {
+			return nil, fmt.Errorf("flag --%s must follow --%s", o.Name, rm.Start)
+		}
+		elements[len(elements)-1] = append(elements[len(elements)-1], o)
+	}
+	return elements, nil
+}
+
+// envElements reads the values of the elements of a repeated group from
+// indexed environment variables, up to the first index none is set for.
+// Variables set for the indexes after it are reported.
+func (rm *RepeatedMeta) envElements(group []*CommandMetadata) (elements [][]Occurrence, err error) {
+	for i := 0; ; i++ {
+		var element []Occurrence
+		for _, cmdMeta := range group {
+			if value, ok := os.LookupEnv(rm.indexedEnv(cmdMeta, strconv.Itoa(i))); ok {
+				element = append(element, Occurrence{cmdMeta.Name, value})
+			}
+		}
+		if len(element) == 0 {
+			return elements, rm.checkGap(group, i)
+		}
+		elements = append(elements, element)
+	}
+}
+
+// checkGap reports an environment variable of the repeated group set for an
+// index after gap, the first one none is set for.
+func (rm *RepeatedMeta) checkGap(group []*CommandMetadata, gap int) error {
+	var past []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, cmdMeta := range group {
+			if index, ok := envIndex(rm.indexedEnv(cmdMeta, indexPlaceholder), name); ok && index > gap {
+				past = append(past, name)
+			}
+		}
+	}
+	if len(past) == 0 {
+		return nil
+	}
+	sort.Strings(past)
+	return fmt.Errorf("environment variable %s is set, but no %s_%d_* variable is: the elements of --%s are numbered from 0 without gaps", past[0], rm.Env, gap, rm.Start)
+}
+
+// newElement returns a new element of the slice of a repeated group. set sets
+// the field of a flag of the group and reports whether a value was given for
+// it, flags without one get their default.
+func (rm *RepeatedMeta) newElement(group []*CommandMetadata, set func(cmdMeta *CommandMetadata, field reflect.Value) (bool, error)) (reflect.Value, error) {
+	elemType := rm.sliceType.Elem()
+	structType := elemType
+	for structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+	elem := reflect.New(structType).Elem()
+	for _, cmdMeta := range group {
+		field := FieldByAccesses(elem, cmdMeta.Accesses[len(rm.accesses):])
+		ok, err := set(cmdMeta, field)
+		switch {
+		case err != nil:
+		case !ok && cmdMeta.Default != nil:
+			err = cmdMeta.SetFromString(field, *cmdMeta.Default)
+		case !ok && cmdMeta.Required:
+			return reflect.Value{}, &RequiredFlagsError{[]string{cmdMeta.Name}}
+		}
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("failed to set field %s: %w", strings.Join(FieldPath(structType, cmdMeta.Accesses[len(rm.accesses):]), "."), err)
+		}
+	}
+	if elemType.Kind() == reflect.Pointer {
+		return elem.Addr(), nil
+	}
+	return elem, nil
+}
+
+// BindRepeated sets the slice fields of the repeated groups among flags from
+// the values given for their flags, in the order they were given, or from
+// indexed environment variables if none were.
+func BindRepeated(obj interface{}, flags []CommandMetadata, given func(group []*CommandMetadata) []Occurrence) error {
+	objValue := reflect.ValueOf(obj).Elem()
+	for _, group := range repeatedGroups(flags) {
+		rm := group[0].Repeated
+		elements, err := rm.elements(given(group))
+		if err != nil {
+			return err
+		}
+		if len(elements) == 0 {
+			elements, err = rm.envElements(group)
+			if err != nil {
+				return err
+			}
+		}
+		if len(elements) == 0 {
+			if rm.Required {
+				return &RequiredFlagsError{[]string{rm.Start}}
+			}
+			continue
+		}
+		slice := reflect.MakeSlice(rm.sliceType, 0, len(elements))
+		for i, element := range elements {
+			elem, err := rm.newElement(group, func(cmdMeta *CommandMetadata, field reflect.Value) (bool, error) {
+				var values []string
+				for _, o := range element {
+					if o.Name == cmdMeta.Name {
+						values = append(values, o.Value)
+					}
+				}
+				if len(values) == 0 {
+					return false, nil
+				}
+				if !cmdMeta.IsVariadic() {
+					return true, cmdMeta.SetValueFromString(field, values[len(values)-1])
+				}
+				var items []string
+				for _, value := range values {
+					split, err := cmdMeta.Split(value)
+					if err != nil {
+						return true, err
+					}
+					items = append(items, split...)
+				}
+				return true, cmdMeta.SetValueFromStrings(field, items)
+			})
+			if err != nil {
+				return fmt.Errorf("element %d of --%s: %w", i, rm.Start, err)
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		FieldByAccesses(objValue, rm.accesses).Elem().Set(slice)
+	}
+	return nil
+}
+
+// givenInOrder returns the values of the flags of a repeated group in the
+// order values got them. FlagValues not implementing OrderedFlagValues give
+// the n-th value of every flag to the n-th element.
+func givenInOrder(values FlagValues, group []*CommandMetadata) (given []Occurrence) {
+	byName := map[string][]string{}
+	for _, cmdMeta := range group {
+		if v, ok := values.FlagValues(cmdMeta.Name); ok {
+			byName[cmdMeta.Name] = v
+		}
+	}
+	if ordered, ok := values.(OrderedFlagValues); ok {
+		next := map[string]int{}
+		for _, name := range ordered.FlagOrder() {
+			v, ok := byName[name]
+			if !ok {
+				continue
+			}
+			if i := next[name]; i < len(v) {
+				given = append(given, Occurrence{name, v[i]})
+			}
+			next[name]++
+		}
+		return
+	}
+	for i := 0; ; i++ {
+		found := false
+		for _, cmdMeta := range group {
+			if v := byName[cmdMeta.Name]; i < len(v) {
+				given = append(given, Occurrence{cmdMeta.Name, v[i]})
+				found = true
+			}
+		}
+		if !found {
+			return
+		}
+	}
+}
+
+// OrderedFlagValues is implemented by FlagValues knowing the order flags were
+// given in, repeated inline groups need it to tell which element a value
+// belongs to.
+type OrderedFlagValues interface {
+	FlagValues
+	// FlagOrder returns the names of the flags in the order they were given,
+	// once for every value.
+	FlagOrder() []string
+}
@@ -0,0 +1,227 @@
+package clive
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Suggest returns the candidates closest to name by edit distance, if they
+// are close enough for name to be a typo of them. It is used for unknown
+// flags, subcommands, variants and environment variables, and by generated
+// code.
+func Suggest(name string, candidates []string) (suggestions []string) {
+	maxDistance := len(name) / 3
+	if maxDistance == 0 {
+		maxDistance = 1
+	}
+	if maxDistance >= len(name) {
+		// any name of one letter is one edit away from any other
+		return nil
+	}
+	best := maxDistance + 1
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+		d := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		switch {
+		case d < best:
+			best, suggestions = d, []string{candidate}
+		case d == best && !contains(suggestions, candidate):
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent runes turning a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// three rows of the table, for the transpositions
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// didYouMean returns the hint listing suggestions appended to error messages.
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %s?", strings.Join(suggestions, " or "))
+}
+
+// flagArg returns the flag name as given on the command line.
+func flagArg(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// UnknownFlagError is returned for flags a command doesn't define.
+type UnknownFlagError struct {
+	Name string
+	// Suggestions are the names of the flags of the command closest to Name.
+	Suggestions []string
+}
+
+func (e *UnknownFlagError) Error() string {
+	suggestions := make([]string, len(e.Suggestions))
+	for i, s := range e.Suggestions {
+		suggestions[i] = flagArg(s)
+	}
+	return fmt.Sprintf("flag provided but not defined: -%s%s", e.Name, didYouMean(suggestions))
+}
+
+// UnknownCommandError is returned when the first argument of a command
+// without an Action of its own is not one of its subcommands.
+type UnknownCommandError struct {
+	Name string
+	// Suggestions are the names of the subcommands closest to Name.
+	Suggestions []string
+}
+
+func (e *UnknownCommandError) Error() string {
+	return fmt.Sprintf("unknown command %s%s", e.Name, didYouMean(e.Suggestions))
+}
+
+// UnknownEnvError is returned when environment variables with the
+// EnvPrefix of the BuildOptions set no flag of any command.
+type UnknownEnvError struct {
+	Names []string
+	// Suggestions are the environment variables closest to each of Names.
+	Suggestions map[string][]string
+}
+
+func (e *UnknownEnvError) Error() string {
+	var msgs []string
+	for _, name := range e.Names {
+		msgs = append(msgs, name+didYouMean(e.Suggestions[name]))
+	}
+	return fmt.Sprintf("unknown environment variables: %s", strings.Join(msgs, "; "))
+}
+
+// indexPlaceholder stands for the index in the indexed environment variables
+// of repeated groups returned by EnvVars.
+const indexPlaceholder = "#"
+
+// EnvVars returns the environment variables of the flags and positional
+// arguments of model and its subcommands. The indexed ones of repeated groups
+// have a # in place of the index, like APP_UPSTREAM_#_HOST.
+func (model *CommandModel) EnvVars() (envs []string) {
+	for _, fields := range [][]CommandMetadata{model.Flags, model.Positionals} {
+		for i := range fields {
+			cmdMeta := &fields[i]
+			if cmdMeta.Repeated != nil {
+				envs = append(envs, cmdMeta.Repeated.indexedEnv(cmdMeta, indexPlaceholder))
+			}
+			for _, env := range cmdMeta.Envs {
+				envs = append(envs, strings.TrimSpace(env))
+			}
+		}
+	}
+	if model.Generated != nil {
+		envs = append(envs, model.Generated.Envs...)
+	}
+	for _, sub := range model.Subcommands {
+		envs = append(envs, sub.EnvVars()...)
+	}
+	return
+}
+
+// matchEnv reports whether the environment variable name is env, or an
+// element of the indexed one env.
+func matchEnv(env, name string) bool {
+	if !strings.Contains(env, indexPlaceholder) {
+		return env == name
+	}
+	_, ok := envIndex(env, name)
+	return ok
+}
+
+// envIndex returns the index of the element the environment variable name
+// is for, if it is an element of the indexed one env. Indexes are written
+// without leading zeros.
+func envIndex(env, name string) (int, bool) {
+	head, tail, indexed := strings.Cut(env, indexPlaceholder)
+	if !indexed || len(name) <= len(head)+len(tail) || !strings.HasPrefix(name, head) || !strings.HasSuffix(name, tail) {
+		return 0, false
+	}
+	s := name[len(head) : len(name)-len(tail)]
+	index, err := strconv.Atoi(s)
+	if err != nil || strconv.Itoa(index) != s || index < 0 {
+		return 0, false
+	}
+	return index, true
+}
+
+// CheckEnv reports the environment variables with the EnvPrefix of bo that
+// match none of known, see EnvVars.
+func CheckEnv(bo *Options, known []string) error {
+	if bo.EnvPrefix == "" {
+		return nil
+	}
+	prefix := bo.EnvPrefix + "_"
+	// the prefix is left out of the edit distance, indexed variables are
+	// suggested for the first element
+	var candidates []string
+	for _, env := range known {
+		if trimmed, ok := strings.CutPrefix(env, prefix); ok {
+			candidates = append(candidates, strings.Replace(trimmed, indexPlaceholder, "0", 1))
+		}
+	}
+	unknown := &UnknownEnvError{Suggestions: map[string][]string{}}
+environ:
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		for _, env := range known {
+			if matchEnv(env, name) {
+				continue environ
+			}
+		}
+		unknown.Names = append(unknown.Names, name)
+		for _, suggestion := range Suggest(strings.TrimPrefix(name, prefix), candidates) {
+			unknown.Suggestions[name] = append(unknown.Suggestions[name], prefix+suggestion)
+		}
+	}
+	if len(unknown.Names) == 0 {
+		return nil
+	}
+	sort.Strings(unknown.Names)
+	return unknown
+}
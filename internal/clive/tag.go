@@ -0,0 +1,185 @@
+package clive
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tag holds the options set by a `cli` struct tag. It is shared by Build and
+// by static checkers, so both agree on what a valid tag is.
+type Tag struct {
+	Name            string
+	Usage           string
+	Envs            []string
+	Aliases         []string
+	Hidden          bool
+	Default         *string
+	Skipped         bool
+	Positional      bool
+	Inline          bool
+	Required        bool
+	UseShortOptions bool
+	Negatable       bool
+	// Global flags are accepted after the names of subcommands too.
+	Global bool
+	// Inject fields are set by a Provider instead of a flag.
+	Inject bool
+	// Layout is the layout time.Time values are parsed with.
+	Layout string
+	// Unit is the unit plain numbers are in for ByteSize and Duration values.
+	Unit string
+	// Sep separates the items of slice values, DefaultSep if empty.
+	Sep string
+	// EnvFormat is the format of slice environment variables, see
+	// EnvFormatList.
+	EnvFormat string
+	// Prefix replaces the name of an inline group in the names of its flags,
+	// an empty Prefix leaves them unprefixed.
+	Prefix string
+	// Defaults override the defaults of the flags of an inline group, keyed
+	// by their names without the prefix of the group.
+	Defaults map[string]string
+
+	// RequiredSet is true if Required was given explicitly.
+	RequiredSet bool
+	// NegatableSet is true if Negatable was given explicitly.
+	NegatableSet bool
+	// PrefixSet is true if Prefix was given explicitly.
+	PrefixSet bool
+}
+
+// ParseTag parses the value of a `cli` struct tag.
+func ParseTag(s string) (tag Tag, err error) {
+	if s == "-" {
+		tag.Skipped = true
+		return
+	}
+	// this code allows strings to be placed inside single-quotes in order to
+	// escape comma characters.
+	quotes := false
+	sections := strings.FieldsFunc(s, func(r rune) bool {
+		if r == '\'' && !quotes {
+			quotes = true
+		} else if r == '\'' && quotes {
+			quotes = false
+		}
+		if r == ',' && !quotes {
+			return true
+		}
+		return false
+	})
+	for _, section := range sections {
+		if section == "positional" {
+			tag.Positional = true
+			continue
+		}
+		if section == "inline" {
+			tag.Inline = true
+			continue
+		}
+		if section == "required" {
+			tag.Required = true
+			tag.RequiredSet = true
+			continue
+		}
+		if section == "negatable" {
+			tag.Negatable = true
+			tag.NegatableSet = true
+			continue
+		}
+		if section == "inject" {
+			tag.Inject = true
+			continue
+		}
+		if section == "global" {
+			tag.Global = true
+			continue
+		}
+		if section == "shortOpt" {
+			tag.UseShortOptions = true
+			continue
+		}
+		keyValue := strings.SplitN(section, ":", 2)
+		if len(keyValue) == 2 {
+			keyValue[1] = strings.Trim(keyValue[1], "'")
+			switch keyValue[0] {
+			case "name":
+				tag.Name = keyValue[1]
+			case "usage":
+				tag.Usage = keyValue[1] // trim single-quotes
+			case "required":
+				tag.Required, err = strconv.ParseBool(keyValue[1])
+				if err != nil {
+					err = fmt.Errorf("failed to parse 'required' as a bool %s", err.Error())
+				}
+				tag.RequiredSet = true
+			case "env":
+				tag.Envs = strings.Split(keyValue[1], ",")
+			case "alias":
+				tag.Aliases = strings.Split(keyValue[1], ",")
+			case "hidden":
+				tag.Hidden, err = strconv.ParseBool(keyValue[1])
+				if err != nil {
+					err = fmt.Errorf("failed to parse 'hidden' as a bool %s", err.Error())
+				}
+			case "default":
+				tag.Default = new(string)
+				*tag.Default = keyValue[1]
+			case "layout":
+				tag.Layout = keyValue[1]
+			case "unit":
+				tag.Unit = keyValue[1]
+			case "sep":
+				tag.Sep = keyValue[1]
+			case "prefix":
+				tag.Prefix = keyValue[1]
+				tag.PrefixSet = true
+			case "defaults":
+				tag.Defaults, err = parseDefaults(keyValue[1])
+			case "envformat":
+				tag.EnvFormat = keyValue[1]
+				err = checkEnvFormat(tag.EnvFormat)
+			case "negatable":
+				tag.Negatable, err = strconv.ParseBool(keyValue[1])
+				if err != nil {
+					err = fmt.Errorf("failed to parse 'negatable' as a bool %s", err.Error())
+				}
+				tag.NegatableSet = true
+			case "entrypoint":
+			case "shortOpt":
+				tag.UseShortOptions, err = strconv.ParseBool(keyValue[1])
+				if err != nil {
+					err = fmt.Errorf("failed to parse 'shortOpt' as a bool %s", err.Error())
+				}
+			default:
+				err = fmt.Errorf("unknown command tag: '%s:%s'", keyValue[0], keyValue[1])
+			}
+		} else {
+			err = fmt.Errorf("malformed tag: '%s'", section)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// parseDefaults parses the value of a defaults tag, name=value pairs
+// separated by commas. Items are split like SplitList does, so a comma inside
+// a value is escaped with a backslash or the item is double quoted.
+func parseDefaults(s string) (map[string]string, error) {
+	items, err := SplitList(s, ",")
+	if err != nil {
+		return nil, fmt.Errorf("malformed 'defaults': %w", err)
+	}
+	defaults := map[string]string{}
+	for _, item := range items {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("malformed 'defaults' item '%s', want name=value", item)
+		}
+		defaults[name] = value
+	}
+	return defaults, nil
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly || windows)
+
+package clive
+
+import "os"
+
+// terminalWidth doesn't know how to find the size of terminals here.
+func terminalWidth(f *os.File) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,26 @@
+package clive
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var getConsoleScreenBufferInfo = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleScreenBufferInfo")
+
+// terminalWidth returns the number of columns of the console window f is
+// attached to, false if it isn't one.
+func terminalWidth(f *os.File) (int, bool) {
+	var info struct {
+		size, cursorPosition struct{ x, y int16 }
+		attributes           uint16
+		left, top            int16
+		right, bottom        int16
+		maximumWindowSize    struct{ x, y int16 }
+	}
+	r, _, _ := getConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 0, false
+	}
+	return int(info.right - info.left + 1), true
+}
@@ -0,0 +1,233 @@
+package clive
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ByteSize is a number of bytes given with a decimal (KB, MB, ...) or binary
+// (KiB, MiB, ...) unit, like 10MiB or 1.5GB. Plain numbers are bytes unless a
+// `unit:` tag says otherwise.
+type ByteSize uint64
+
+var byteUnits = []struct {
+	name string
+	size uint64
+}{
+	{"EiB", 1 << 60}, {"PiB", 1 << 50}, {"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10},
+	{"EB", 1e18}, {"PB", 1e15}, {"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3},
+	{"B", 1},
+}
+
+// byteUnit returns the size of the unit name, ignoring case and allowing the
+// trailing B to be left out.
+func byteUnit(name string) (uint64, bool) {
+	name = strings.ToLower(name)
+	for _, unit := range byteUnits {
+		full := strings.ToLower(unit.name)
+		if name == full || (unit.size != 1 && name == strings.TrimSuffix(full, "b")) {
+			return unit.size, true
+		}
+	}
+	return 0, false
+}
+
+// splitNumber splits s into its leading number and the unit after it.
+func splitNumber(s string) (number, unit string) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// ParseByteSize parses a byte size like 10MiB or 1.5GB, plain numbers are
+// in unit.
+func ParseByteSize(s, unit string) (ByteSize, error) {
+	number, suffix := splitNumber(strings.TrimSpace(s))
+	if suffix == "" {
+		suffix = unit
+	}
+	size, ok := byteUnit(suffix)
+	if number == "" || !ok {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	if n, err := strconv.ParseUint(number, 10, 64); err == nil {
+		if n > math.MaxUint64/size {
+			return 0, fmt.Errorf("byte size %q out of range", s)
+		}
+		return ByteSize(n * size), nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	f *= float64(size)
+	if f >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size %q out of range", s)
+	}
+	return ByteSize(math.Round(f)), nil
+}
+
+// String formats b in the largest binary or decimal unit that fits it, with
+// at most three decimals, whichever is shorter. Sizes that can't be written
+// that way are formatted in bytes.
+func (b ByteSize) String() string {
+	best := ""
+	for _, family := range [][]struct {
+		name string
+		size uint64
+	}{byteUnits[:6], byteUnits[6:12]} {
+		for _, unit := range family {
+			if uint64(b) < unit.size {
+				continue
+			}
+			s := strconv.FormatFloat(float64(b)/float64(unit.size), 'f', -1, 64)
+			if i := strings.IndexByte(s, '.'); i >= 0 && len(s)-i-1 > 3 {
+				break
+			}
+			s += unit.name
+			if parsed, err := ParseByteSize(s, ""); err == nil && parsed == b && (best == "" || len(s) < len(best)) {
+				best = s
+			}
+			break
+		}
+	}
+	if best == "" {
+		best = strconv.FormatUint(uint64(b), 10) + "B"
+	}
+	return best
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) (err error) {
+	*b, err = ParseByteSize(string(text), "B")
+	return
+}
+
+// Percent is a fraction given in percent, 50% and 50 are both 0.5.
+type Percent float64
+
+// ParsePercent parses a percentage with an optional % sign.
+func ParsePercent(s string) (Percent, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "%")), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return Percent(f / 100), nil
+}
+
+func (p Percent) String() string {
+	// round away the error of dividing by 100
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(p)*100, 'g', 12, 64), 64)
+	return strconv.FormatFloat(f, 'f', -1, 64) + "%"
+}
+
+func (p *Percent) UnmarshalText(text []byte) (err error) {
+	*p, err = ParsePercent(string(text))
+	return
+}
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// Duration is a time.Duration that also accepts days (d) and weeks (w),
+// like 2w or 1d12h. Plain numbers are in the unit given by a `unit:` tag.
+type Duration time.Duration
+
+// ParseDuration parses a duration the way time.ParseDuration does, with d and
+// w units on top. Plain numbers are in unit, they are an error if unit is
+// empty, except for 0.
+func ParseDuration(s, unit string) (Duration, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		if unit == "" {
+			if f == 0 {
+				return 0, nil
+			}
+			return 0, fmt.Errorf("missing unit in duration %q", orig)
+		}
+		s += unit
+	}
+	sign := time.Duration(1)
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	var d time.Duration
+	for s != "" {
+		number, rest := splitNumber(s)
+		i := strings.IndexAny(rest, "0123456789.")
+		if i < 0 {
+			i = len(rest)
+		}
+		name, next := rest[:i], rest[i:]
+		if number == "" || name == "" {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		var part time.Duration
+		switch name {
+		case "d", "w":
+			f, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+			size := day
+			if name == "w" {
+				size = week
+			}
+			if f*float64(size) >= math.MaxInt64 {
+				return 0, fmt.Errorf("duration %q out of range", orig)
+			}
+			part = time.Duration(f * float64(size))
+		default:
+			var err error
+			part, err = time.ParseDuration(number + name)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+		}
+		if d > math.MaxInt64-part {
+			return 0, fmt.Errorf("duration %q out of range", orig)
+		}
+		d += part
+		s = next
+	}
+	return Duration(sign * d), nil
+}
+
+// String formats d like time.Duration does, with whole weeks and days split
+// off.
+func (d Duration) String() string {
+	td := time.Duration(d)
+	sign := ""
+	if td < 0 {
+		sign, td = "-", -td
+	}
+	switch {
+	case td == 0 || td < day:
+		return sign + td.String()
+	case td%week == 0:
+		return sign + strconv.FormatInt(int64(td/week), 10) + "w"
+	case td%day == 0:
+		return sign + strconv.FormatInt(int64(td/day), 10) + "d"
+	}
+	return sign + strconv.FormatInt(int64(td/day), 10) + "d" + (td % day).String()
+}
+
+func (d *Duration) UnmarshalText(text []byte) (err error) {
+	*d, err = ParseDuration(string(text), "")
+	return
+}
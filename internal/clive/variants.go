@@ -0,0 +1,229 @@
+package clive
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// variant is an implementation of an interface registered with
+// RegisterVariant.
+type variant struct {
+	name string
+	// typ is the struct type, its pointer implements the interface
+	typ reflect.Type
+}
+
+var (
+	variantsMu sync.RWMutex
+	variants   = map[reflect.Type][]variant{}
+)
+
+// RegisterVariant is clive.RegisterVariant.
+func RegisterVariant[I any, K ~string](key K, impl I) {
+	name := string(key)
+	iface := Reflected[I]()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Errorf("variants of %s can't be registered, it is not an interface", iface))
+	}
+	t := reflect.TypeOf(impl)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("variant %s of %s must be a pointer to a struct, got %T", name, iface, impl))
+	}
+	if hv, ok := interface{}(key).(HasVariants); ok && !slices.Contains(hv.Variants(), name) {
+		panic(fmt.Errorf("variant %s of %s is not one of the variants of %T: %s", name, iface, key, strings.Join(hv.Variants(), ", ")))
+	}
+	variantsMu.Lock()
+	defer variantsMu.Unlock()
+	for _, v := range variants[iface] {
+		if v.name == name {
+			panic(fmt.Errorf("variant %s of %s is already registered", name, iface))
+		}
+	}
+	variants[iface] = append(variants[iface], variant{name: name, typ: t.Elem()})
+}
+
+// variantTypeOf returns the type of fields selecting a variant of t, nil if
+// no variants of t are registered.
+func variantTypeOf(t reflect.Type) *VariantType {
+	if t.Kind() != reflect.Interface {
+		return nil
+	}
+	variantsMu.RLock()
+	defer variantsMu.RUnlock()
+	registered, ok := variants[t]
+	if !ok {
+		return nil
+	}
+	return &VariantType{iface: t, variants: append([]variant{}, registered...)}
+}
+
+// VariantType sets interface fields to the variant registered under the name
+// given.
+type VariantType struct {
+	iface    reflect.Type
+	variants []variant
+}
+
+func (vt *VariantType) names() []string {
+	names := make([]string, len(vt.variants))
+	for i, v := range vt.variants {
+		names[i] = v.name
+	}
+	return names
+}
+
+func (vt *VariantType) Predicate(fType reflect.Type) bool {
+	return fType == vt.iface
+}
+
+// SetValueFromString sets the interface value points to to a new instance of
+// the variant named s, unless it already holds one.
+func (vt *VariantType) SetValueFromString(value reflect.Value, s string) error {
+	for _, v := range vt.variants {
+		if v.name != s {
+			continue
+		}
+		field := value.Elem()
+		if field.IsNil() || field.Elem().Type() != reflect.PointerTo(v.typ) {
+			field.Set(reflect.New(v.typ))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown variant %q, expected one of: %s", s, strings.Join(vt.names(), ", "))
+}
+
+// ValueType is string, the name of the variant.
+func (vt *VariantType) ValueType() reflect.Type {
+	return Reflected[string]()
+}
+
+func (vt *VariantType) SetValue(value, v reflect.Value) error {
+	return vt.SetValueFromString(value, v.String())
+}
+
+func (vt *VariantType) IsVariadic() bool { return false }
+
+func (vt *VariantType) SetValueFromStrings(value reflect.Value, s []string) error {
+	if len(s) == 0 {
+		return nil
+	}
+	return vt.SetValueFromString(value, s[len(s)-1])
+}
+
+// variantMeta is set on the flags of variant implementations.
+type variantMeta struct {
+	// Selector is the name of the flag selecting the variant, Name the name
+	// it was registered under.
+	Selector string
+	Name     string
+	// depth is the length of the GroupPath of the fields of the variant.
+	depth int
+	iface reflect.Type
+	// parent is the variant the selector is a field of.
+	parent *variantMeta
+}
+
+// variantAccess marks the step of CommandMetadata.Accesses going through an
+// interface field into its variant i.
+func variantAccess(i int) int {
+	return -1 - i
+}
+
+// variantIndex is the inverse of variantAccess.
+func variantIndex(access int) int {
+	return -1 - access
+}
+
+// parseVariants adds the flags of every variant of the interface field
+// selector to flags, prefixed with the name of the variant.
+func parseVariants(selector *CommandMetadata, vt *VariantType, flags *[]CommandMetadata, bo *Options) error {
+	for parent := selector.Variant; parent != nil; parent = parent.parent {
+		if parent.iface == vt.iface {
+			return fmt.Errorf("field %s of variant %s selects a variant of %s again", selector.LocalName, parent.Name, vt.iface)
+		}
+	}
+	for i, v := range vt.variants {
+		group := CommandMetadata{
+			Tag:       Tag{Name: v.name},
+			LocalName: v.name,
+			GroupPath: selector.GroupPath,
+			Accesses:  append(append([]int{}, selector.Accesses...), variantAccess(i)),
+			Variant: &variantMeta{
+				Selector: selector.Name,
+				Name:     v.name,
+				depth:    len(selector.GroupPath) + 1,
+				iface:    vt.iface,
+				parent:   selector.Variant,
+			},
+		}
+		if selector.Group != "" {
+			group.Name = selector.Group + "-" + v.name
+		}
+		var variantPositionals []CommandMetadata
+		for j := 0; j < v.typ.NumField(); j++ {
+			accesses := append(append([]int{}, group.Accesses...), j)
+			err := parseFieldOrPositional(&group, accesses, v.typ.Field(j), &variantPositionals, flags, bo)
+			if err != nil {
+				return fmt.Errorf("parsing variant %s of field %s: %w", v.name, selector.LocalName, err)
+			}
+		}
+		if len(variantPositionals) != 0 {
+			return fmt.Errorf("variant %s of field %s can't have positional arguments", v.name, selector.LocalName)
+		}
+	}
+	return nil
+}
+
+// UnselectedVariantError is returned when a flag of a variant is given but
+// another variant is selected.
+type UnselectedVariantError struct {
+	Flag     string
+	Selector string
+	Variant  string
+}
+
+func (e *UnselectedVariantError) Error() string {
+	return fmt.Sprintf("flag --%s can only be used with --%s=%s", e.Flag, e.Selector, e.Variant)
+}
+
+// CheckVariants checks the flags of variants after binding: flags of
+// variants that weren't selected must not be given, required flags of the
+// selected ones must be set. lookup reports whether a flag was set and
+// whether it was set from an environment variable, which are ignored for
+// variants not selected.
+func CheckVariants(obj interface{}, flags []CommandMetadata, lookup func(cmdMeta *CommandMetadata) (set, fromEnv bool)) error {
+	objValue := reflect.ValueOf(obj).Elem()
+	var missing []string
+	for i := range flags {
+		cmdMeta := &flags[i]
+		if cmdMeta.Variant == nil {
+			continue
+		}
+		set, fromEnv := lookup(cmdMeta)
+		selected := existingField(objValue, cmdMeta.Accesses).IsValid()
+		switch {
+		case !selected && set && !fromEnv:
+			return &UnselectedVariantError{Flag: cmdMeta.Name, Selector: cmdMeta.Variant.Selector, Variant: cmdMeta.Variant.Name}
+		case selected && !set && cmdMeta.Required && cmdMeta.Default == nil:
+			missing = append(missing, cmdMeta.Name)
+		}
+	}
+	if len(missing) != 0 {
+		return &RequiredFlagsError{missing}
+	}
+	return nil
+}
+
+// EnvSet reports whether any of envs is set.
+func EnvSet(envs []string) bool {
+	for _, env := range envs {
+		if _, ok := os.LookupEnv(strings.TrimSpace(env)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package clive
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+	"github.com/urfave/cli/v2"
+)
+
+// listFlag reports whether the flag of cmdMeta is a ListFlag, urfave/cli
+// slice flags always split at commas.
+func listFlag(cmdMeta *core.CommandMetadata) bool {
+	return cmdMeta.IsVariadic() && !cmdMeta.Positional && (cmdMeta.Sep != "" || cmdMeta.EnvFormat != "")
+}
+
+// newListFlag constructs the ListFlag of a slice field.
+func newListFlag(cmdMeta core.CommandMetadata) (cli.Flag, error) {
+	f := &ListFlag{
+		StringSliceFlag: cli.StringSliceFlag{
+			Name:      cmdMeta.Name,
+			Aliases:   cmdMeta.Aliases,
+			Usage:     cmdMeta.FlagUsage(),
+			EnvVars:   cmdMeta.Envs,
+			Hidden:    cmdMeta.Hidden,
+			Required:  cmdMeta.Required,
+			TakesFile: core.TakesFile(cmdMeta.TypeInterface),
+		},
+		Sep:       cmdMeta.Sep,
+		EnvFormat: cmdMeta.EnvFormat,
+	}
+	if cmdMeta.Default != nil {
+		items, err := cmdMeta.Split(*cmdMeta.Default)
+		if err != nil {
+			return nil, err
+		}
+		// check the default now, the flag keeps strings
+		err = cmdMeta.SetValueFromStrings(reflect.New(cmdMeta.FieldType), items)
+		if err != nil {
+			return nil, err
+		}
+		f.Value = cli.NewStringSlice(items...)
+	}
+	return f, nil
+}
+
+// ListFlag is a cli.StringSliceFlag splitting its values at Sep, with the
+// escaping SplitList understands, and reading its environment variables in
+// EnvFormat. urfave/cli splits at commas only.
+type ListFlag struct {
+	cli.StringSliceFlag
+	Sep       string
+	EnvFormat string
+}
+
+func (f *ListFlag) Apply(set *flag.FlagSet) error {
+	value := &listValue{sep: f.Sep}
+	if f.Value != nil {
+		value.items = f.Value.Value()
+	}
+	for _, env := range f.EnvVars {
+		s, ok := os.LookupEnv(strings.TrimSpace(env))
+		if !ok {
+			continue
+		}
+		items, err := core.SplitEnv(s, f.Sep, f.EnvFormat)
+		if err != nil {
+			return fmt.Errorf("could not parse %q as slice value from environment variable %q for flag %s: %s", s, env, f.Name, err)
+		}
+		value.items = items
+		f.HasBeenSet = true
+		break
+	}
+	for _, name := range f.Names() {
+		set.Var(value, name, f.Usage)
+	}
+	return nil
+}
+
+func (f *ListFlag) String() string {
+	return cli.FlagStringer(f)
+}
+
+// listValue collects the items of a ListFlag, the first value given on the
+// command line replaces the default and the environment.
+type listValue struct {
+	sep   string
+	items []string
+	set   bool
+}
+
+func (v *listValue) Set(s string) error {
+	items, err := SplitList(s, v.sep)
+	if err != nil {
+		return err
+	}
+	if !v.set {
+		v.items, v.set = nil, true
+	}
+	v.items = append(v.items, items...)
+	return nil
+}
+
+func (v *listValue) String() string {
+	if v == nil {
+		return ""
+	}
+	if v.sep == "" {
+		return strings.Join(v.items, DefaultSep)
+	}
+	return strings.Join(v.items, v.sep)
+}
+
+func (v *listValue) Get() interface{} {
+	return v.items
+}
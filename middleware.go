@@ -0,0 +1,67 @@
+package clive
+
+import (
+	"github.com/urfave/cli/v2"
+)
+
+// Step is the step of running a command a middleware wraps.
+type Step int
+
+const (
+	// StepBefore binds the command struct from the command line, opens its
+	// files, injects its values and calls its Before. Binding errors, like
+	// missing required flags, are returned by this step.
+	StepBefore Step = iota
+	// StepAction calls the Action of the command struct.
+	StepAction
+	// StepAfter calls the After of the command struct. The files and
+	// values of the command are closed after it whatever the middleware
+	// does.
+	StepAfter
+)
+
+func (s Step) String() string {
+	switch s {
+	case StepBefore:
+		return "before"
+	case StepAction:
+		return "action"
+	case StepAfter:
+		return "after"
+	}
+	return "unknown"
+}
+
+// Invocation is a step of running a command, passed through middleware.
+type Invocation struct {
+	*cli.Context
+	Step Step
+	// Path is the space separated list of subcommand names leading to the
+	// command from the root, like CommandSpec.Path.
+	Path string
+	// Command is the command struct, bound from the command line. In
+	// StepBefore it is only bound once next is called.
+	Command interface{}
+}
+
+// ActionFunc runs a step of a command.
+type ActionFunc func(inv *Invocation) error
+
+// Middleware wraps the steps of commands. It can run code around next,
+// replace its error or return without calling it.
+type Middleware func(next ActionFunc) ActionFunc
+
+// HasMiddleware is implemented by command structs wrapping their steps and
+// those of their subcommands in middleware. It runs inside the middleware
+// of BuildOptions and of the ancestors.
+type HasMiddleware interface {
+	Middleware() []Middleware
+}
+
+// chain wraps step in middleware, the first one outermost.
+func chain(middleware []Middleware, step ActionFunc) ActionFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		step = middleware[i](step)
+	}
+	return step
+}
@@ -0,0 +1,40 @@
+package clive
+
+// CheckPortable makes sure the command spec describes and its subcommands
+// don't use features only Build implements: global flags, fields tagged
+// inject, middleware, providers and the hooks taking a *cli.Context.
+// Adapters for other command line libraries call it with the BuildOptions
+// they were given before building their commands.
+func CheckPortable(spec *CommandSpec, o BuildOptions) error {
+	unsupported := func(feature string) error {
+		return &UnsupportedError{Command: spec.DisplayName(), Feature: feature}
+	}
+	switch {
+	case len(o.Middleware) != 0:
+		return unsupported("middleware")
+	case len(o.Providers) != 0:
+		return unsupported("providers")
+	}
+	if err := spec.CheckPortable(); err != nil {
+		return err
+	}
+	switch spec.Object().(type) {
+	case WithAuthors, WithCopyright, WithCompiled, HasCommandNotFound, HasExitErrHandler, HasInvalidFlagAccessHandler:
+		return unsupported("methods setting fields of the cli.App")
+	}
+	return checkHooks(spec)
+}
+
+// checkHooks reports commands handling their errors parsing the command line
+// themselves, see HasOnUsageError.
+func checkHooks(spec *CommandSpec) error {
+	if _, ok := spec.Object().(HasOnUsageError); ok {
+		return &UnsupportedError{Command: spec.DisplayName(), Feature: "OnUsageError"}
+	}
+	for _, sub := range spec.Subcommands {
+		if err := checkHooks(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
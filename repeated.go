@@ -0,0 +1,94 @@
+package clive
+
+import (
+	"flag"
+	"reflect"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+	"github.com/urfave/cli/v2"
+)
+
+// repeatedRecord keeps the values given for the flags of a repeated group on
+// the command line, in order.
+type repeatedRecord struct {
+	given []core.Occurrence
+}
+
+// repeatedFlag is the flag of a field of a repeated group, the flags of a
+// group share a repeatedRecord.
+type repeatedFlag struct {
+	cli.GenericFlag
+	record   *repeatedRecord
+	start    bool
+	boolFlag bool
+}
+
+// newRepeatedFlag constructs the flag of a field of a repeated group.
+func newRepeatedFlag(cmdMeta core.CommandMetadata, record *repeatedRecord) (cli.Flag, error) {
+	t := cmdMeta.FieldType
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	f := &repeatedFlag{
+		GenericFlag: cli.GenericFlag{
+			Name:      cmdMeta.Name,
+			Aliases:   cmdMeta.Aliases,
+			Usage:     cmdMeta.FlagUsage(),
+			Hidden:    cmdMeta.Hidden,
+			TakesFile: core.TakesFile(cmdMeta.TypeInterface),
+		},
+		record:   record,
+		start:    cmdMeta.Name == cmdMeta.Repeated.Start,
+		boolFlag: t.Kind() == reflect.Bool || t == Reflected[Counter](),
+	}
+	if cmdMeta.Default != nil {
+		// check the default now, it is set for every element
+		err := cmdMeta.SetFromString(reflect.New(cmdMeta.FieldType), *cmdMeta.Default)
+		if err != nil {
+			return nil, err
+		}
+		f.DefaultText = *cmdMeta.Default
+	}
+	return f, nil
+}
+
+func (f *repeatedFlag) Apply(set *flag.FlagSet) error {
+	// flags are applied before the command line is parsed
+	if f.start {
+		f.record.given = nil
+	}
+	value := &repeatedValue{record: f.record, name: f.Name, boolFlag: f.boolFlag}
+	for _, name := range f.Names() {
+		set.Var(value, name, f.Usage)
+	}
+	return nil
+}
+
+func (f *repeatedFlag) String() string {
+	return cli.FlagStringer(f)
+}
+
+// repeatedValue adds the values of a flag of a repeated group to the record
+// of the group.
+type repeatedValue struct {
+	record   *repeatedRecord
+	name     string
+	boolFlag bool
+}
+
+func (v *repeatedValue) Set(s string) error {
+	v.record.given = append(v.record.given, core.Occurrence{Name: v.name, Value: s})
+	return nil
+}
+
+func (v *repeatedValue) String() string {
+	return ""
+}
+
+func (v *repeatedValue) Get() interface{} {
+	return v.record.given
+}
+
+func (v *repeatedValue) IsBoolFlag() bool {
+	return v.boolFlag
+}
@@ -0,0 +1,42 @@
+package clive
+
+import (
+	"strings"
+
+	core "github.com/ASMfreaK/clive2/internal/clive"
+	"github.com/urfave/cli/v2"
+)
+
+// unknownFlag returns an UnknownFlagError for the errors of the flag package
+// about undefined flags, suggesting the visible flags of the command of ctx.
+func unknownFlag(ctx *cli.Context, err error) error {
+	name, ok := strings.CutPrefix(err.Error(), "flag provided but not defined: -")
+	if !ok {
+		return err
+	}
+	var names []string
+	for _, flag := range ctx.Command.VisibleFlags() {
+		names = append(names, flag.Names()...)
+	}
+	name = strings.TrimPrefix(name, "-")
+	return &UnknownFlagError{Name: name, Suggestions: core.Suggest(name, names)}
+}
+
+// unknownCommand returns an UnknownCommandError if the first argument of ctx
+// doesn't name a subcommand of its command, or nil.
+func unknownCommand(ctx *cli.Context) error {
+	if !ctx.Args().Present() || len(ctx.Command.Subcommands) == 0 {
+		return nil
+	}
+	name := ctx.Args().First()
+	var names []string
+	for _, sub := range ctx.Command.Subcommands {
+		if sub.HasName(name) {
+			return nil
+		}
+		if !sub.Hidden {
+			names = append(names, sub.Names()...)
+		}
+	}
+	return &UnknownCommandError{Name: name, Suggestions: core.Suggest(name, names)}
+}
@@ -0,0 +1,109 @@
+package clive2_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+type LifecycleSub struct {
+	*clive.Command `cli:"name:sub"`
+	log            *[]string
+}
+
+func (s *LifecycleSub) Action(*cli.Context) error {
+	*s.log = append(*s.log, "sub action")
+	return nil
+}
+
+func (s *LifecycleSub) After(*cli.Context) error {
+	*s.log = append(*s.log, "sub after")
+	return nil
+}
+
+func (s *LifecycleSub) OnUsageError(_ *cli.Context, err error, isSubcommand bool) error {
+	return fmt.Errorf("sub usage (%v): %w", isSubcommand, err)
+}
+
+type LifecycleApp struct {
+	*clive.Command
+	Subcommands struct {
+		*LifecycleSub
+	}
+	Fail bool
+	log  []string
+}
+
+func (a *LifecycleApp) Action(ctx *cli.Context) error {
+	a.log = append(a.log, "action")
+	ctx.String("missing")
+	if a.Fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+func (a *LifecycleApp) After(*cli.Context) error {
+	a.log = append(a.log, "after")
+	return nil
+}
+
+func (*LifecycleApp) OnUsageError(_ *cli.Context, err error, isSubcommand bool) error {
+	return fmt.Errorf("usage (%v): %w", isSubcommand, err)
+}
+
+func (*LifecycleApp) Authors() []*cli.Author {
+	return []*cli.Author{{Name: "Jane Doe", Email: "jane@example.com"}}
+}
+
+func (*LifecycleApp) Copyright() string { return "(c) 2024 Example" }
+
+func (*LifecycleApp) Compiled() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+func (a *LifecycleApp) CommandNotFound(_ *cli.Context, command string) {
+	a.log = append(a.log, "not found "+command)
+}
+
+func (a *LifecycleApp) ExitErrHandler(_ *cli.Context, err error) {
+	if err != nil {
+		a.log = append(a.log, "exit "+err.Error())
+	}
+}
+
+func (a *LifecycleApp) InvalidFlagAccessHandler(_ *cli.Context, name string) {
+	a.log = append(a.log, "invalid flag "+name)
+}
+
+func TestAppLifecycle(t *testing.T) {
+	obj := &LifecycleApp{}
+	obj.Subcommands.LifecycleSub = &LifecycleSub{log: &obj.log}
+	app := clive.Build(obj)
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	assert.Equal(t, "Jane Doe", app.Authors[0].Name)
+	assert.Equal(t, "(c) 2024 Example", app.Copyright)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), app.Compiled)
+
+	err := app.Run([]string{"app", "--fail"})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, []string{"action", "invalid flag missing", "exit failed", "after"}, obj.log)
+
+	obj.log = nil
+	err = app.Run([]string{"app", "sub"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sub action", "sub after", "after"}, obj.log)
+
+	err = app.Run([]string{"app", "--nope"})
+	assert.EqualError(t, err, "usage (false): flag provided but not defined: -nope")
+	err = app.Run([]string{"app", "sub", "--nope"})
+	assert.EqualError(t, err, "sub usage (true): flag provided but not defined: -nope")
+
+	obj.log = nil
+	app.CommandNotFound(nil, "subb")
+	assert.Equal(t, []string{"not found subb"}, obj.log)
+}
@@ -312,9 +312,8 @@ func TestBuild(t *testing.T) {
 					Usage:   "possible values: [server, client]",
 				},
 				&cli.IntFlag{
-					Name:     "input-port",
-					EnvVars:  []string{"INPUT_PORT"},
-					Required: true,
+					Name:    "input-port",
+					EnvVars: []string{"INPUT_PORT"},
 				},
 				&cli.StringFlag{
 					Name:    "output-role",
@@ -322,9 +321,8 @@ func TestBuild(t *testing.T) {
 					Usage:   "possible values: [server, client]",
 				},
 				&cli.IntFlag{
-					Name:     "output-port",
-					EnvVars:  []string{"OUTPUT_PORT"},
-					Required: true,
+					Name:    "output-port",
+					EnvVars: []string{"OUTPUT_PORT"},
 				},
 			},
 			Commands: []*cli.Command{
@@ -411,7 +409,7 @@ func TestBuild(t *testing.T) {
 				{
 					Name: "c1",
 					Flags: []cli.Flag{
-						&cli.BoolFlag{Name: "bool", EnvVars: []string{"BOOL"}},
+						&clive.BoolFlag{BoolFlag: cli.BoolFlag{Name: "bool", EnvVars: []string{"BOOL"}}},
 						&cli.DurationFlag{Name: "duration", EnvVars: []string{"DURATION"}},
 						&cli.Float64Flag{Name: "float-64", EnvVars: []string{"FLOAT_64"}},
 						&cli.Int64Flag{Name: "int-64", EnvVars: []string{"INT_64"}},
@@ -453,7 +451,7 @@ func TestBuild(t *testing.T) {
 				{
 					Name: "c1",
 					Flags: []cli.Flag{
-						&cli.BoolFlag{Name: "bool", EnvVars: []string{"C12_BOOL"}},
+						&clive.BoolFlag{BoolFlag: cli.BoolFlag{Name: "bool", EnvVars: []string{"C12_BOOL"}}},
 						&cli.DurationFlag{Name: "duration", EnvVars: []string{"C12_DURATION"}},
 						&cli.Float64Flag{Name: "float-64", EnvVars: []string{"C12_FLOAT_64"}},
 						&cli.Int64Flag{Name: "int-64", EnvVars: []string{"C12_INT_64"}},
@@ -530,9 +528,13 @@ func TestBuild(t *testing.T) {
 			gotC.Before = nil
 			gotC.Action = nil
 			gotC.After = nil
+			gotC.OnUsageError = nil
 
-			// dont check Metadata (used internally)
+			// dont check Metadata (used internally) and the help template
+			// rendering from it
 			gotC.Metadata = nil
+			assert.Equal(t, "{{.Metadata.cliveRecord.Help}}", gotC.CustomAppHelpTemplate)
+			gotC.CustomAppHelpTemplate = ""
 			gotC.Name = ""
 			tt.wantC.Name = ""
 
@@ -543,6 +545,7 @@ func TestBuild(t *testing.T) {
 				next.Action = nil
 				next.Before = nil
 				next.After = nil
+				next.OnUsageError = nil
 				queue = append(queue, next.Subcommands...)
 			}
 
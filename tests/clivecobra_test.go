@@ -0,0 +1,176 @@
+package clive2_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ASMfreaK/clive2/clivecobra"
+	"github.com/ASMfreaK/clive2/clivecore"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+type CobraAdd struct {
+	*clivecore.Command `cli:"name:'add',alias:'plus',usage:'add numbers'"`
+
+	Verbose clivecore.Counter `cli:"alias:v"`
+	Base    int               `cli:"default:10,alias:'start'"`
+	Numbers []int             `cli:"positional"`
+
+	result int
+}
+
+func (add *CobraAdd) Action(cmd *cobra.Command, _ []string) error {
+	root := clivecobra.Root(cmd).(*CobraApp)
+	add.result = add.Base
+	for _, n := range add.Numbers {
+		add.result += n
+	}
+	root.ran = append(root.ran, "add")
+	return nil
+}
+
+type CobraApp struct {
+	*clivecore.Command `cli:"name:'calc',usage:'calculator'"`
+
+	Subcommands struct {
+		*CobraAdd
+	}
+
+	Debug  bool           `cli:"alias:d"`
+	Color  ColorT         `cli:"default:Blue"`
+	Input  ComposedOption `cli:"inline"`
+	Labels []string       `cli:"env:'CALC_LABELS'"`
+
+	ran []string
+}
+
+func (app *CobraApp) Before(_ *cobra.Command, _ []string) error {
+	app.ran = append(app.ran, "before")
+	return nil
+}
+
+func (app *CobraApp) After(_ *cobra.Command, _ []string) error {
+	app.ran = append(app.ran, "after")
+	return nil
+}
+
+func newCobraApp() (*CobraApp, *cobra.Command) {
+	app := &CobraApp{}
+	app.Subcommands.CobraAdd = &CobraAdd{}
+	cmd := clivecobra.Build(app)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SilenceUsage = true
+	return app, cmd
+}
+
+func TestCobra(t *testing.T) {
+	t.Setenv("CALC_LABELS", "a,b")
+	app, cmd := newCobraApp()
+
+	cmd.SetArgs([]string{"-d", "--input-port", "80", "--input-role", "client",
+		"plus", "-v", "-v", "--start", "1", "2", "3"})
+	assert.NoError(t, cmd.Execute())
+	assert.True(t, app.Debug)
+	assert.Equal(t, Blue, app.Color)
+	assert.Equal(t, ComposedOption{Role: Client, Port: 80}, app.Input)
+	assert.Equal(t, []string{"a", "b"}, app.Labels)
+	assert.Equal(t, []string{"before", "add", "after"}, app.ran)
+	assert.Equal(t, 2, app.Subcommands.CobraAdd.Verbose.Value)
+	assert.Equal(t, []int{2, 3}, app.Subcommands.CobraAdd.Numbers)
+	assert.Equal(t, 6, app.Subcommands.CobraAdd.result)
+
+	add, _, err := cmd.Find([]string{"add"})
+	assert.NoError(t, err)
+	assert.Same(t, app.Subcommands.CobraAdd, clivecobra.Current(add))
+	assert.Same(t, app, clivecobra.Parent(add))
+	assert.Equal(t, "add NUMBERS [NUMBERS...]", add.Use)
+
+	// flags are local to their command
+	cmd.SetArgs([]string{"--input-port", "81", "add", "1"})
+	assert.NoError(t, cmd.Execute())
+	assert.Equal(t, 81, app.Input.Port)
+	assert.Equal(t, 11, app.Subcommands.CobraAdd.result)
+	assert.Nil(t, add.Flag("input-port"))
+	assert.Nil(t, add.InheritedFlags().Lookup("input-port"))
+
+	cmd.SilenceErrors = true
+	cmd.SetArgs([]string{"add", "--input-port", "81", "1"})
+	assert.EqualError(t, cmd.Execute(), "unknown flag: --input-port")
+	cmd.SetArgs([]string{"--base", "1", "add"})
+	assert.EqualError(t, cmd.Execute(), "unknown flag: --base")
+}
+
+func TestCobraSeparateTrees(t *testing.T) {
+	first, firstCmd := newCobraApp()
+	second, secondCmd := newCobraApp()
+
+	firstCmd.SetArgs([]string{"--input-port", "1", "add", "1"})
+	assert.NoError(t, firstCmd.Execute())
+	secondCmd.SetArgs([]string{"--input-port", "2", "add", "2"})
+	assert.NoError(t, secondCmd.Execute())
+
+	firstAdd, _, _ := firstCmd.Find([]string{"add"})
+	secondAdd, _, _ := secondCmd.Find([]string{"add"})
+	assert.Same(t, first, clivecobra.Root(firstAdd))
+	assert.Same(t, second, clivecobra.Root(secondAdd))
+	assert.Equal(t, 1, first.Input.Port)
+	assert.Equal(t, 2, second.Input.Port)
+}
+
+func TestCobraHelp(t *testing.T) {
+	help := func(args ...string) string {
+		_, cmd := newCobraApp()
+		b := &strings.Builder{}
+		cmd.SetOut(b)
+		cmd.SetArgs(append(args, "--help"))
+		assert.NoError(t, cmd.Execute())
+		return b.String()
+	}
+
+	root := help()
+	assert.Contains(t, root, "--input-port int")
+	assert.Contains(t, root, "--labels strings")
+	assert.Contains(t, root, "[$CALC_LABELS]")
+	assert.Contains(t, root, `(default "Blue")`)
+	assert.NotContains(t, root, "(default )")
+	assert.NotContains(t, root, `(default "")`)
+
+	add := help("add")
+	assert.Contains(t, add, "--base int")
+	assert.Contains(t, add, "[$BASE] (default 10)")
+	assert.Contains(t, add, "-v, --verbose count ")
+	assert.NotContains(t, add, "[=")
+}
+
+func TestCobraErrors(t *testing.T) {
+	_, cmd := newCobraApp()
+	cmd.SilenceErrors = true
+
+	cmd.SetArgs([]string{"--input-port", "80", "--color", "Purple", "add", "1"})
+	assert.EqualError(t, cmd.Execute(), "failed to set field Color (type clive2_test.ColorT) from flag color: invalid value \"Purple\", expected one of [Red, Green, Blue]")
+
+	cmd.SetArgs([]string{"--input-port", "80", "add", "x"})
+	assert.EqualError(t, cmd.Execute(), `failed to set field Numbers (type []int) from positional argument NUMBERS: strconv.ParseInt: parsing "x": invalid syntax`)
+
+	cmd.SetArgs([]string{"add"})
+	assert.EqualError(t, cmd.Execute(), `Required flag "input-port" not set`)
+
+	cmd.SetArgs([]string{"--input-port", "80"})
+	assert.EqualError(t, cmd.Execute(), clivecore.ErrCommandNotImplemented().Error())
+
+	assert.Panics(t, func() { clivecobra.Build(&struct{}{}) })
+}
+
+func TestCobraUnsupported(t *testing.T) {
+	global := &FlagGlobal{}
+	global.Subcommands.FlagAdd = &FlagAdd{}
+	assert.PanicsWithError(t, (&clivecore.UnsupportedError{Command: "global", Feature: "global flag config"}).Error(), func() {
+		clivecobra.Build(global)
+	})
+	assert.PanicsWithError(t, `command "inject" uses fields tagged inject, which only clive.Build supports`, func() {
+		clivecobra.Build(&FlagInject{})
+	})
+}
@@ -0,0 +1,163 @@
+package clive2_test
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/ASMfreaK/clive2/clivecore"
+	"github.com/ASMfreaK/clive2/cliveflag"
+	"github.com/stretchr/testify/assert"
+)
+
+type FlagAdd struct {
+	*clivecore.Command `cli:"name:'add',alias:'plus',usage:'add numbers'"`
+
+	Verbose clivecore.Counter `cli:"alias:v"`
+	Base    int               `cli:"default:10,alias:'start'"`
+	Numbers []int             `cli:"positional"`
+
+	result int
+}
+
+func (add *FlagAdd) Action(context.Context) error {
+	add.result = add.Base
+	for _, n := range add.Numbers {
+		add.result += n
+	}
+	return nil
+}
+
+type FlagApp struct {
+	*clivecore.Command `cli:"name:'calc',usage:'calculator'"`
+
+	Subcommands struct {
+		*FlagAdd
+	}
+
+	Debug  bool           `cli:"alias:d"`
+	Color  ColorT         `cli:"default:Blue"`
+	Input  ComposedOption `cli:"inline"`
+	Labels []string       `cli:"env:'CALC_LABELS'"`
+
+	ran []string
+}
+
+func (app *FlagApp) Before(context.Context) error {
+	app.ran = append(app.ran, "before")
+	return nil
+}
+
+func (app *FlagApp) After(context.Context) error {
+	app.ran = append(app.ran, "after")
+	return nil
+}
+
+func newFlagApp(output io.Writer) (*FlagApp, *cliveflag.Command) {
+	app := &FlagApp{}
+	app.Subcommands.FlagAdd = &FlagAdd{}
+	cmd := cliveflag.Build(app)
+	cmd.FlagSet.SetOutput(output)
+	for _, sub := range cmd.Subcommands {
+		sub.FlagSet.SetOutput(output)
+	}
+	return app, cmd
+}
+
+func TestFlag(t *testing.T) {
+	t.Setenv("CALC_LABELS", "a,b")
+	app, cmd := newFlagApp(io.Discard)
+
+	err := cmd.Run(context.Background(), []string{"-d", "--input-port", "80", "-input-role=client",
+		"plus", "-v", "-v", "--start", "1", "2", "3"})
+	assert.NoError(t, err)
+	assert.True(t, app.Debug)
+	assert.Equal(t, Blue, app.Color)
+	assert.Equal(t, ComposedOption{Role: Client, Port: 80}, app.Input)
+	assert.Equal(t, []string{"a", "b"}, app.Labels)
+	assert.Equal(t, []string{"before", "after"}, app.ran)
+	assert.Equal(t, 2, app.Subcommands.FlagAdd.Verbose.Value)
+	assert.Equal(t, []int{2, 3}, app.Subcommands.FlagAdd.Numbers)
+	assert.Equal(t, 6, app.Subcommands.FlagAdd.result)
+	assert.Same(t, app.Subcommands.FlagAdd, cmd.Subcommands[0].Current())
+	assert.Equal(t, "calc add", cmd.Subcommands[0].Spec().HelpName)
+
+	// flags given in the previous run are forgotten, base is back to default
+	err = cmd.Run(context.Background(), []string{"--input-port", "81", "add", "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 81, app.Input.Port)
+	assert.Equal(t, 11, app.Subcommands.FlagAdd.result)
+}
+
+func TestFlagErrors(t *testing.T) {
+	var help strings.Builder
+	_, cmd := newFlagApp(&help)
+
+	err := cmd.Run(context.Background(), []string{"--input-port", "80", "--color", "Purple", "add", "1"})
+	assert.EqualError(t, err, "failed to set field Color (type clive2_test.ColorT) from flag color: invalid value \"Purple\", expected one of [Red, Green, Blue]")
+
+	err = cmd.Run(context.Background(), []string{"--input-port", "80", "add", "x"})
+	assert.EqualError(t, err, `failed to set field Numbers (type []int) from positional argument NUMBERS: strconv.ParseInt: parsing "x": invalid syntax`)
+
+	err = cmd.Run(context.Background(), []string{"add"})
+	assert.EqualError(t, err, `Required flag "input-port" not set`)
+
+	err = cmd.Run(context.Background(), []string{"--input-port", "80"})
+	assert.EqualError(t, err, clivecore.ErrCommandNotImplemented().Error())
+
+	help.Reset()
+	err = cmd.Run(context.Background(), []string{"-h"})
+	assert.ErrorIs(t, err, flag.ErrHelp)
+	assert.Contains(t, help.String(), "calc - calculator")
+	assert.Contains(t, help.String(), "--input-port")
+
+	assert.Panics(t, func() { cliveflag.Build(&struct{}{}) })
+}
+
+type FlagGlobal struct {
+	*clivecore.Command `cli:"name:'global'"`
+
+	Subcommands struct {
+		*FlagAdd
+	}
+
+	Config string `cli:"global"`
+}
+
+type FlagInject struct {
+	*clivecore.Command `cli:"name:'inject'"`
+
+	Config *InjectConfig `cli:"inject"`
+}
+
+func TestFlagUnsupported(t *testing.T) {
+	global := &FlagGlobal{}
+	global.Subcommands.FlagAdd = &FlagAdd{}
+	assert.PanicsWithError(t, (&clivecore.UnsupportedError{Command: "global", Feature: "global flag config"}).Error(), func() {
+		cliveflag.Build(global)
+	})
+	assert.PanicsWithError(t, `command "inject" uses fields tagged inject, which only clive.Build supports`, func() {
+		cliveflag.Build(&FlagInject{})
+	})
+}
+
+func TestCliveflagDeps(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	for _, pkg := range []string{"clivecore", "cliveflag"} {
+		out, err := exec.Command(gobin, "list", "-deps", "github.com/ASMfreaK/clive2/"+pkg).Output()
+		assert.NoError(t, err)
+		for _, dep := range strings.Fields(string(out)) {
+			if strings.HasPrefix(dep, "github.com/ASMfreaK/clive2/") || strings.HasPrefix(dep, "vendor/") {
+				continue
+			}
+			// the standard library has no dots in import paths
+			assert.NotContains(t, dep, ".", "%s depends on %s", pkg, dep)
+		}
+	}
+}
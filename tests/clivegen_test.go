@@ -0,0 +1,156 @@
+package clive2_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/ASMfreaK/clive2/clivegen"
+	"github.com/ASMfreaK/clive2/tests/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestCliveGenUpToDate(t *testing.T) {
+	want, err := clivegen.Generate("gen")
+	assert.NoError(t, err)
+	got, err := os.ReadFile(filepath.Join("gen", "clive_gen.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "run go generate ./tests/gen")
+}
+
+// runGen runs args against gen.App and returns the bound command structs.
+func runGen(args []string, o clive.BuildOptions) (app *gen.App, serve *gen.Serve, flags []cli.Flag, err error) {
+	app = &gen.App{Run: func(c *clive.Command, ctx *cli.Context) error {
+		app = c.Current(ctx).(*gen.App)
+		return nil
+	}}
+	app.Subcommands.Serve = &gen.Serve{Run: func(c *clive.Command, ctx *cli.Context) error {
+		serve = c.Current(ctx).(*gen.Serve)
+		return nil
+	}}
+	c := clive.BuildCustom(app, o)
+	c.Writer, c.ErrWriter = io.Discard, io.Discard
+	flags = append(append([]cli.Flag{}, c.Flags...), c.Commands[0].Flags...)
+	err = c.Run(append([]string{"gen"}, args...))
+	if app != nil {
+		app.Command, app.Run, app.Subcommands.Serve = nil, nil, nil
+	}
+	if serve != nil {
+		serve.Command, serve.Run = nil, nil
+	}
+	return
+}
+
+func TestCliveGenMatchesReflection(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"--debug", "--name", "me", "--count", "7", "--ports", "1", "--ports", "2"},
+		{"serve", "--listen-port", "80", "root"},
+		{"serve", "--listen-port", "80", "-l", "warn", "--levels", "debug,info", "-v", "-v", "--limit", "10",
+			"--timeout", "1m", "--ratio", "0.25", "--retries", "1s,2s", "--features", "false", "--weights", "1.5",
+			"--secret", "s", "root", "a", "b"},
+		{"serve", "root"},
+		{"serve", "--listen-port", "80"},
+		{"serve", "--listen-port", "80", "-l", "nope", "root"},
+		{"serve", "--listen-port", "80", "--levels", "debug,infp", "root"},
+		{"serve", "--listen-port", "80", "--retries", "forever", "root"},
+	}
+	for i, args := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			app, serve, flags, err := runGen(args, clive.BuildOptions{EnvPrefix: "GEN"})
+			wantApp, wantServe, wantFlags, wantErr := runGen(args, clive.BuildOptions{EnvPrefix: "GEN", IgnoreGenerated: true})
+			assert.Equal(t, wantFlags, flags)
+			assert.Equal(t, wantErr, err)
+			assert.Equal(t, wantApp, app)
+			assert.Equal(t, wantServe, serve)
+		})
+	}
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("GEN_LEVEL", "nope")
+		args := []string{"serve", "--listen-port", "80", "root"}
+		_, _, _, err := runGen(args, clive.BuildOptions{EnvPrefix: "GEN"})
+		_, _, _, wantErr := runGen(args, clive.BuildOptions{EnvPrefix: "GEN", IgnoreGenerated: true})
+		assert.Equal(t, wantErr, err)
+		var bind *clive.FieldBindError
+		if assert.ErrorAs(t, err, &bind) {
+			assert.Equal(t, "environment variable GEN_LEVEL", bind.Source)
+		}
+	})
+}
+
+type staleCommand struct {
+	*clive.Command
+	Run clive.RunFunc
+	One string
+	Two string
+}
+
+func (*staleCommand) CliveFlags(*clive.BuildOptions) []cli.Flag {
+	return []cli.Flag{&cli.StringFlag{Name: "one"}}
+}
+
+func (*staleCommand) CliveBind(*cli.Context) error { return nil }
+
+type staleHashCommand struct {
+	*clive.Command
+	Run clive.RunFunc
+	One string
+	Two string `cli:"default:changed"`
+}
+
+func (*staleHashCommand) CliveFlags(*clive.BuildOptions) []cli.Flag {
+	return []cli.Flag{&cli.StringFlag{Name: "one"}, &cli.StringFlag{Name: "two"}}
+}
+
+func (*staleHashCommand) CliveBind(*cli.Context) error { return nil }
+
+func (obj *staleHashCommand) CliveCommand() clive.GeneratedCommand {
+	if obj.Command == nil {
+		obj.Command = new(clive.Command)
+	}
+	return clive.GeneratedCommand{Command: obj.Command}
+}
+
+func (*staleHashCommand) CliveHashes() []string {
+	return []string{
+		clive.FieldHash("one", []string{"string"}, []string{""}),
+		clive.FieldHash("two", []string{"string"}, []string{""}),
+	}
+}
+
+func TestCliveGenStale(t *testing.T) {
+	assert.NoError(t, clive.CheckGenerated(&gen.App{}))
+
+	err := clive.CheckGenerated(&staleCommand{})
+	assert.EqualError(t, err, (&clive.StaleGeneratedError{Type: "staleCommand"}).Error())
+
+	err = clive.CheckGenerated(&staleHashCommand{})
+	assert.EqualError(t, err, (&clive.StaleGeneratedError{Type: "staleHashCommand", Field: "two"}).Error())
+
+	// Build only notices code generated before CliveCommand
+	assert.PanicsWithError(t, (&clive.StaleGeneratedError{Type: "staleCommand"}).Error(), func() { clive.Build(&staleCommand{}) })
+	assert.NotPanics(t, func() { clive.Build(&staleHashCommand{}) })
+
+	assert.NotPanics(t, func() { clive.BuildCustom(&staleCommand{}, clive.BuildOptions{IgnoreGenerated: true}) })
+}
+
+func TestCliveGenHelp(t *testing.T) {
+	help := func(args []string, o clive.BuildOptions) string {
+		b := &strings.Builder{}
+		c := clive.BuildCustom(&gen.App{}, o)
+		c.Writer, c.ErrWriter = b, io.Discard
+		assert.NoError(t, c.Run(append([]string{"gen"}, args...)))
+		return b.String()
+	}
+	for _, args := range [][]string{{"--help"}, {"serve", "--help"}} {
+		want := help(args, clive.BuildOptions{EnvPrefix: "GEN", IgnoreGenerated: true})
+		assert.NotEmpty(t, want)
+		assert.Equal(t, want, help(args, clive.BuildOptions{EnvPrefix: "GEN"}), args)
+	}
+}
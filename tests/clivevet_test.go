@@ -0,0 +1,12 @@
+package clive2_test
+
+import (
+	"testing"
+
+	"github.com/ASMfreaK/clive2/clivevet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestClivevet(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), clivevet.Analyzer, "a")
+}
@@ -0,0 +1,56 @@
+package clive2_test
+
+import (
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribe(t *testing.T) {
+	_, err := clive.Describe(&struct{}{})
+	assert.EqualError(t, err, (&clive.WrongFirstFieldError{}).Error())
+
+	spec, err := clive.DescribeCustom(&App{}, clive.BuildOptions{EnvPrefix: "APP"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "", spec.Path)
+	assert.Equal(t, "clive2_test.App", spec.Type)
+	assert.Equal(t, "some-version-string", spec.Version)
+
+	setOption := spec.Subcommand("config setoption")
+	if assert.NotNil(t, setOption) {
+		assert.Equal(t, "setoption", setOption.Name)
+		assert.Equal(t, "clive2_test.SetOption", setOption.Type)
+		assert.Empty(t, setOption.Flags)
+		if assert.Len(t, setOption.Positionals, 2) {
+			value := setOption.Positionals[1]
+			assert.Equal(t, &clive.FieldSpec{
+				Name:       "value",
+				FieldPath:  []string{"Value"},
+				Type:       "clive2_test.JSON",
+				Tag:        "positional",
+				Required:   true,
+				Positional: true,
+			}, value)
+		}
+	}
+	assert.Nil(t, spec.Subcommand("config nope"))
+
+	byName := map[string]*clive.FieldSpec{}
+	for _, flag := range spec.Flags {
+		byName[flag.Name] = flag
+	}
+	assert.Equal(t, &clive.FieldSpec{
+		Name:      "api-address",
+		FieldPath: []string{"ApplicationAPIAddress"},
+		Type:      "*string",
+		Tag:       "name:api_address,alias:'a,i'",
+		Aliases:   []string{"a", "i"},
+		Envs:      []string{"APP_API_ADDRESS"},
+	}, byName["api-address"])
+	assert.Equal(t, []string{"Output", "Role"}, byName["output-role"].FieldPath)
+	assert.Equal(t, []string{"server", "client"}, byName["output-role"].Variants)
+	assert.True(t, byName["process-schedule"].Hidden)
+	assert.True(t, byName["uints-64"].Variadic)
+}
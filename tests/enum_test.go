@@ -0,0 +1,185 @@
+package clive2_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/ASMfreaK/clive2/clivecobra"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+type Shade string
+
+func (*Shade) Variants() []string { return []string{"light", "dark"} }
+
+func (*Shade) VariantAliases() map[string]string {
+	return map[string]string{"l": "light", "d": "dark"}
+}
+
+func (*Shade) CaseInsensitiveVariants() bool { return true }
+
+func (s *Shade) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "light", "dark":
+		*s = Shade(text)
+		return nil
+	}
+	return fmt.Errorf("unexpected shade %s", text)
+}
+
+type Paint struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Color  ColorT `cli:"default:Red"`
+	Shade  Shade  `cli:"default:L"`
+	Colors []ColorT
+	Shades []Shade
+
+	Target ColorT `cli:"positional,default:Blue"`
+}
+
+func TestStrictVariants(t *testing.T) {
+	got, err := run(&Paint{})
+	assert.NoError(t, err)
+	assert.Equal(t, Red, got.Color)
+	assert.Equal(t, Shade("light"), got.Shade)
+	assert.Equal(t, Blue, got.Target)
+
+	got, err = run(&Paint{}, "--shade", "DARK", "--shades", "l,Dark", "--colors", "Green,Blue", "Green")
+	assert.NoError(t, err)
+	assert.Equal(t, Shade("dark"), got.Shade)
+	assert.Equal(t, []Shade{"light", "dark"}, got.Shades)
+	assert.Equal(t, []ColorT{Green, Blue}, got.Colors)
+	assert.Equal(t, Green, got.Target)
+
+	_, err = run(&Paint{}, "--color", "red")
+	assert.EqualError(t, err, `failed to set field Color (type clive2_test.ColorT) from flag color: invalid value "red", expected one of [Red, Green, Blue], did you mean Red?`)
+	var invalid *clive.InvalidVariantError
+	if assert.ErrorAs(t, err, &invalid) {
+		assert.Equal(t, "red", invalid.Value)
+		assert.Equal(t, []string{"Red", "Green", "Blue"}, invalid.Variants)
+	}
+	_, err = run(&Paint{}, "--colors", "Green,Purple")
+	assert.EqualError(t, err, `failed to set field Colors (type []clive2_test.ColorT) from flag colors: invalid value "Purple", expected one of [Red, Green, Blue]`)
+	_, err = run(&Paint{}, "--shade", "ligth")
+	assert.EqualError(t, err, `failed to set field Shade (type clive2_test.Shade) from flag shade: invalid value "ligth", expected one of [light, dark], did you mean light?`)
+	_, err = run(&Paint{}, "Purple")
+	assert.EqualError(t, err, `failed to set field Target (type clive2_test.ColorT) from positional argument TARGET: invalid value "Purple", expected one of [Red, Green, Blue]`)
+
+	t.Setenv("SHADES", "d,grey")
+	_, err = run(&Paint{})
+	assert.EqualError(t, err, `failed to set field Shades (type []clive2_test.Shade) from flag shades: invalid value "grey", expected one of [light, dark]`)
+}
+
+func TestStrictVariantsDefaults(t *testing.T) {
+	spec, err := clive.Describe(&Paint{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Red", "Green", "Blue"}, spec.Flags[2].Variants)
+
+	_, err = clive.Describe(&struct {
+		*clive.Command
+		Color ColorT `cli:"default:Purple"`
+	}{})
+	assert.EqualError(t, err, `bad default of color: invalid value "Purple", expected one of [Red, Green, Blue]`)
+	_, err = clive.Describe(&struct {
+		*clive.Command
+		Colors []ColorT `cli:"default:'Red,Pink'"`
+	}{})
+	assert.EqualError(t, err, `bad default of colors: invalid value "Pink", expected one of [Red, Green, Blue]`)
+	_, err = clive.Describe(&struct {
+		*clive.Command
+		Paint struct {
+			Color ColorT
+		} `cli:"inline,defaults:'color=Purple'"`
+	}{})
+	assert.EqualError(t, err, `bad default of paint-color: invalid value "Purple", expected one of [Red, Green, Blue]`)
+}
+
+type Size int
+
+const (
+	Small Size = iota
+	Medium
+	Large
+)
+
+func init() {
+	clive.RegisterEnum(map[Size]string{Large: "large", Small: "small", Medium: "medium"})
+	clive.RegisterEnumDescriptions(map[Size]string{Small: "fits in a pocket", Large: "needs a truck"})
+}
+
+type Order struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Size    Size `cli:"default:medium"`
+	Sizes   []Size
+	Wrapped clive.Enum[Size] `cli:"default:small"`
+	Box     *Size
+}
+
+func TestEnum(t *testing.T) {
+	got, err := run(&Order{}, "--sizes", "large,small", "--box", "large")
+	assert.NoError(t, err)
+	assert.Equal(t, Medium, got.Size)
+	assert.Equal(t, []Size{Large, Small}, got.Sizes)
+	assert.Equal(t, clive.Enum[Size]{Value: Small}, got.Wrapped)
+	assert.Equal(t, Large, *got.Box)
+
+	_, err = run(&Order{}, "--size", "huge")
+	assert.EqualError(t, err, `failed to set field Size (type clive2_test.Size) from flag size: invalid value "huge", expected one of [small, medium, large]`)
+	_, err = run(&Order{}, "--wrapped", "lage")
+	assert.EqualError(t, err, `failed to set field Wrapped (type clive.Enum[github.com/ASMfreaK/clive2/tests_test.Size]) from flag wrapped: invalid value "lage", expected one of [small, medium, large], did you mean large?`)
+
+	spec, err := clive.Describe(&Order{})
+	assert.NoError(t, err)
+	for _, flag := range spec.Flags {
+		assert.Equal(t, []string{"small", "medium", "large"}, flag.Variants, flag.Name)
+		assert.Equal(t, map[string]string{"small": "fits in a pocket", "large": "needs a truck"}, flag.VariantDescriptions, flag.Name)
+	}
+
+	var b bytes.Buffer
+	assert.NoError(t, clive.DefaultHelpRenderer.RenderHelp(&b, spec))
+	assert.Contains(t, b.String(), "(one of: small, medium, large)")
+	assert.Contains(t, b.String(), "small: fits in a pocket\n")
+	assert.NotContains(t, b.String(), "medium: ")
+
+	cmd := clivecobra.Build(&Order{})
+	complete, ok := cmd.GetFlagCompletionFunc("size")
+	if assert.True(t, ok) {
+		completions, directive := complete(cmd, nil, "")
+		assert.Equal(t, []string{"small\tfits in a pocket", "medium", "large\tneeds a truck"}, completions)
+		assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+	}
+}
+
+func TestEnumMethods(t *testing.T) {
+	e := clive.Enum[Size]{Value: Large}
+	assert.Equal(t, "large", e.String())
+	text, err := e.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "large", string(text))
+	assert.NoError(t, e.UnmarshalText([]byte("medium")))
+	assert.Equal(t, Medium, e.Value)
+	assert.EqualError(t, e.UnmarshalText([]byte("Medium")), `invalid value "Medium", expected one of [small, medium, large]`)
+	assert.Equal(t, []string{"small", "medium", "large"}, e.Variants())
+
+	e.Value = Size(7)
+	assert.Equal(t, "7", e.String())
+	_, err = e.MarshalText()
+	assert.EqualError(t, err, "value 7 of enum clive2_test.Size has no name")
+
+	assert.PanicsWithError(t, "enum clive2_test.Size is already registered", func() {
+		clive.RegisterEnum(map[Size]string{Small: "s"})
+	})
+	assert.PanicsWithError(t, "enum string has more than one value named a", func() {
+		clive.RegisterEnum(map[string]string{"x": "a", "y": "a"})
+	})
+	assert.PanicsWithError(t, "enum float64 is not registered, see RegisterEnum", func() {
+		clive.RegisterEnumDescriptions(map[float64]string{1: "one"})
+	})
+}
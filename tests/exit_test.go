@@ -0,0 +1,152 @@
+package clive2_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+type ExitApp struct {
+	*clive.Command
+	Color ColorT
+	Fail  string
+
+	Src  string `cli:"positional"`
+	Dest string `cli:"positional,default:out"`
+}
+
+func (a *ExitApp) Action(*cli.Context) error {
+	switch a.Fail {
+	case "runtime":
+		return errors.New("failed")
+	case "exit":
+		return cli.Exit("failed", 5)
+	}
+	return nil
+}
+
+func runExitApp(options clive.BuildOptions, args ...string) (*cli.App, error) {
+	app := clive.BuildCustom(&ExitApp{}, options)
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	// keep urfave/cli from exiting on cli.Exit
+	app.ExitErrHandler = func(*cli.Context, error) {}
+	return app, app.Run(append([]string{"exit"}, args...))
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		args  []string
+		class clive.ErrorClass
+		code  int
+	}{
+		{[]string{"a"}, clive.RuntimeErrors, 0},
+		{[]string{"--nope", "a"}, clive.UsageErrors, 2},
+		{[]string{}, clive.UsageErrors, 2},
+		{[]string{"a", "b", "c"}, clive.UsageErrors, 2},
+		{[]string{"--color", "Purple", "a"}, clive.UsageErrors, 2},
+		{[]string{"--fail", "runtime", "a"}, clive.RuntimeErrors, 1},
+		{[]string{"--fail", "exit", "a"}, clive.RuntimeErrors, 5},
+	}
+	for _, tt := range tests {
+		app, err := runExitApp(clive.DefaultBuildOptions, tt.args...)
+		assert.Equal(t, tt.code, clive.ExitCode(app, err), "%v: %v", tt.args, err)
+		if err != nil {
+			assert.Equal(t, tt.class, clive.ClassOf(err), "%v: %v", tt.args, err)
+		}
+	}
+
+	options := clive.DefaultBuildOptions
+	options.ExitCodes = map[clive.ErrorClass]int{clive.UsageErrors: 64}
+	app, err := runExitApp(options, "a", "b", "c")
+	assert.Equal(t, 64, clive.ExitCode(app, err))
+	app, err = runExitApp(options, "--fail", "runtime", "a")
+	assert.Equal(t, 1, clive.ExitCode(app, err))
+
+	// Apps built with ExitOnError exit with the exit codes of their errors
+	var exited []int
+	defer func(exiter func(int)) { cli.OsExiter = exiter }(cli.OsExiter)
+	cli.OsExiter = func(code int) { exited = append(exited, code) }
+	options.ExitOnError = true
+	for _, args := range [][]string{{"a"}, {"a", "b", "c"}, {"--fail", "runtime", "a"}, {"--fail", "exit", "a"}} {
+		app = clive.BuildCustom(&ExitApp{}, options)
+		app.Writer, app.ErrWriter = io.Discard, io.Discard
+		_ = app.Run(append([]string{"exit"}, args...))
+	}
+	assert.Equal(t, []int{64, 1, 5}, exited)
+
+	// others return them
+	exited = nil
+	app = clive.Build(&ExitApp{})
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	err = app.Run([]string{"exit", "a", "b", "c"})
+	var tooMany *clive.TooManyArgumentsError
+	assert.ErrorAs(t, err, &tooMany)
+	assert.Empty(t, exited)
+}
+
+func TestBindErrors(t *testing.T) {
+	_, err := runExitApp(clive.DefaultBuildOptions, "--color", "Purple", "a")
+	var bind *clive.FieldBindError
+	if assert.ErrorAs(t, err, &bind) {
+		assert.Equal(t, "Color", bind.Field)
+		assert.Equal(t, "flag color", bind.Source)
+		assert.Equal(t, "Purple", bind.Value)
+		assert.EqualError(t, bind.Err, `invalid value "Purple", expected one of [Red, Green, Blue]`)
+	}
+
+	// the source is where the value came from
+	t.Run("source", func(t *testing.T) {
+		t.Setenv("COLOR", "Purple")
+		_, err := runExitApp(clive.DefaultBuildOptions, "a")
+		var bind *clive.FieldBindError
+		if assert.ErrorAs(t, err, &bind) {
+			assert.Equal(t, "environment variable COLOR", bind.Source)
+			assert.Equal(t, "Purple", bind.Value)
+		}
+		_, err = runExitApp(clive.DefaultBuildOptions, "--color", "Pink", "a")
+		if assert.ErrorAs(t, err, &bind) {
+			assert.Equal(t, "flag color", bind.Source)
+			assert.Equal(t, "Pink", bind.Value)
+		}
+		_, err = runFlag(&ExitApp{}, "a")
+		if assert.ErrorAs(t, err, &bind) {
+			assert.Equal(t, "environment variable COLOR", bind.Source)
+		}
+		_, err = runFlag(&ExitApp{}, "--color", "Pink", "a")
+		if assert.ErrorAs(t, err, &bind) {
+			assert.Equal(t, "flag color", bind.Source)
+		}
+	})
+
+	_, err = runExitApp(clive.DefaultBuildOptions)
+	var tooFew *clive.TooFewArgumentsError
+	if assert.ErrorAs(t, err, &tooFew) {
+		assert.Equal(t, "SRC", tooFew.Name)
+	}
+	assert.EqualError(t, err, "too few arguments: SRC is required")
+
+	_, err = runExitApp(clive.DefaultBuildOptions, "a", "b", "c", "d")
+	var tooMany *clive.TooManyArgumentsError
+	if assert.ErrorAs(t, err, &tooMany) {
+		assert.Equal(t, []string{"c", "d"}, tooMany.Args)
+	}
+
+	_, err = runExitApp(clive.DefaultBuildOptions, "--nope", "a")
+	var usage *clive.UsageError
+	assert.ErrorAs(t, err, &usage)
+
+	_, err = run(&struct {
+		*clive.Command
+		Run   clive.RunFunc
+		Token string `cli:"required"`
+	}{})
+	var required *clive.RequiredFlagsError
+	if assert.ErrorAs(t, err, &required) {
+		assert.Equal(t, []string{"token"}, required.Names)
+	}
+	assert.Equal(t, clive.UsageErrors, clive.ClassOf(err))
+}
@@ -0,0 +1,202 @@
+package clive2_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/ASMfreaK/clive2/cliveflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+type Copy struct {
+	*clive.Command `cli:"name:copy"`
+	Run            clive.RunFunc
+
+	Out    clive.OutputFile `cli:"default:-"`
+	Dir    clive.ExistingDir
+	Config *clive.ExistingFile
+	Cache  clive.Path
+	Extra  []clive.InputFile
+	In     clive.InputFile `cli:"positional"`
+}
+
+type CopyApp struct {
+	*clive.Command
+	Subcommands struct {
+		*Copy
+	}
+}
+
+// copyFiles copies every input to Out and keeps the files it used.
+func copyFiles(c *Copy) ([]*os.File, error) {
+	files := []*os.File{c.In.File, c.Out.File}
+	_, err := io.Copy(c.Out, c.In)
+	for _, extra := range c.Extra {
+		files = append(files, extra.File)
+		if err == nil {
+			_, err = io.Copy(c.Out, extra)
+		}
+	}
+	return files, err
+}
+
+func runCopy(fail error, args ...string) (got Copy, files []*os.File, err error) {
+	obj := &Copy{Run: func(c *clive.Command, ctx *cli.Context) (err error) {
+		current := c.Current(ctx).(*Copy)
+		files, err = copyFiles(current)
+		got = *current
+		if err == nil {
+			err = fail
+		}
+		return
+	}}
+	root := &CopyApp{}
+	root.Subcommands.Copy = obj
+	app := clive.Build(root)
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	err = app.Run(append([]string{"app", "copy"}, args...))
+	return
+}
+
+func assertClosed(t *testing.T, files []*os.File) {
+	for _, f := range files {
+		_, err := f.Stat()
+		assert.ErrorIs(t, err, os.ErrClosed, f.Name())
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	in := filepath.Join(dir, "in.txt")
+	assert.NoError(t, os.WriteFile(in, []byte("hello "), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "extra.txt"), []byte("world"), 0o644))
+	out := filepath.Join(dir, "out.txt")
+
+	got, files, err := runCopy(nil, "--out", out, "--dir", "~", "--config", "~/in.txt", "--cache", "~/cache",
+		"--extra", "~/extra.txt", in)
+	assert.NoError(t, err)
+	assertClosed(t, files)
+	data, _ := os.ReadFile(out)
+	assert.Equal(t, "hello world", string(data))
+	assert.Equal(t, clive.ExistingDir(dir), got.Dir)
+	assert.Equal(t, clive.ExistingFile(in), *got.Config)
+	assert.Equal(t, clive.Path(filepath.Join(dir, "cache")), got.Cache)
+	assert.Equal(t, out, got.Out.Path)
+
+	// files are closed when the command fails too
+	_, files, err = runCopy(errors.New("failed"), "--out", out, in)
+	assert.EqualError(t, err, "failed")
+	assertClosed(t, files)
+
+	// - is stdin and stdout, which are left open
+	stdin, stdout := os.Stdin, os.Stdout
+	defer func() { os.Stdin, os.Stdout = stdin, stdout }()
+	os.Stdin, err = os.Open(in)
+	assert.NoError(t, err)
+	os.Stdout, err = os.Create(out)
+	assert.NoError(t, err)
+	_, files, err = runCopy(nil, "-")
+	assert.NoError(t, err)
+	assert.Equal(t, []*os.File{os.Stdin, os.Stdout}, files)
+	_, err = os.Stdout.Stat()
+	assert.NoError(t, err)
+	data, _ = os.ReadFile(out)
+	assert.Equal(t, "hello ", string(data))
+	os.Stdin.Close()
+	os.Stdout.Close()
+	os.Stdin, os.Stdout = stdin, stdout
+
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{filepath.Join(dir, "missing")}, `failed to set field In (type clive.InputFile) from positional argument IN: file "` + filepath.Join(dir, "missing") + `" does not exist`},
+		{[]string{"--dir", in, in}, `failed to set field Dir (type clive.ExistingDir) from flag dir: "` + in + `" is not a directory`},
+		{[]string{"--config", dir, in}, `failed to set field Config (type *clive.ExistingFile) from flag config: "` + dir + `" is a directory, not a file`},
+		{[]string{"--out", dir, in}, `failed to set field Out (type clive.OutputFile) from flag out: "` + dir + `" is a directory, not a file`},
+	} {
+		_, _, err = runCopy(nil, tt.args...)
+		assert.EqualError(t, err, tt.err)
+	}
+
+	// an output file that can't be created fails before the command runs
+	_, _, err = runCopy(nil, "--out", filepath.Join(dir, "missing", "out.txt"), in)
+	assert.EqualError(t, err, `failed to set field Out (type clive.OutputFile) from flag out: directory "`+filepath.Join(dir, "missing")+`" does not exist`)
+
+	// and so do files without the permissions they need
+	if os.Geteuid() != 0 {
+		locked := filepath.Join(dir, "locked")
+		assert.NoError(t, os.Mkdir(locked, 0o500))
+		unreadable := filepath.Join(dir, "unreadable.txt")
+		assert.NoError(t, os.WriteFile(unreadable, nil, 0o200))
+		readOnly := filepath.Join(dir, "read-only.txt")
+		assert.NoError(t, os.WriteFile(readOnly, nil, 0o400))
+		_, _, err = runCopy(nil, unreadable)
+		assert.EqualError(t, err, `failed to set field In (type clive.InputFile) from positional argument IN: file "`+unreadable+`" can't be read: permission denied`)
+		_, _, err = runCopy(nil, "--out", readOnly, in)
+		assert.EqualError(t, err, `failed to set field Out (type clive.OutputFile) from flag out: file "`+readOnly+`" can't be written: permission denied`)
+		_, _, err = runCopy(nil, "--out", filepath.Join(locked, "out.txt"), in)
+		assert.EqualError(t, err, `failed to set field Out (type clive.OutputFile) from flag out: directory "`+locked+`" can't be written: permission denied`)
+	}
+
+	// and when they are fields of the root command
+	var rootFiles []*os.File
+	root := &Copy{Run: func(c *clive.Command, ctx *cli.Context) (err error) {
+		rootFiles, err = copyFiles(c.Current(ctx).(*Copy))
+		return
+	}}
+	app := clive.Build(root)
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	assert.NoError(t, app.Run([]string{"copy", "--out", out, in}))
+	assert.Len(t, rootFiles, 2)
+	assertClosed(t, rootFiles)
+
+	spec, err := clive.Describe(&Copy{})
+	assert.NoError(t, err)
+	for _, field := range append(spec.Flags, spec.Positionals...) {
+		assert.True(t, field.TakesFile, field.Name)
+	}
+	app = clive.Build(&Copy{})
+	assert.True(t, app.Flags[0].(*cli.StringFlag).TakesFile)
+	assert.True(t, app.Flags[4].(*cli.StringSliceFlag).TakesFile)
+}
+
+type FlagCopy struct {
+	*clive.Command
+
+	Out clive.OutputFile `cli:"default:-"`
+	In  clive.InputFile  `cli:"positional"`
+
+	files []*os.File `cli:"-"`
+}
+
+func (c *FlagCopy) Action(context.Context) error {
+	c.files = []*os.File{c.In.File, c.Out.File}
+	_, err := io.Copy(c.Out, c.In)
+	return err
+}
+
+func TestFilesFlag(t *testing.T) {
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.txt"), filepath.Join(dir, "out.txt")
+	assert.NoError(t, os.WriteFile(in, []byte("hello"), 0o644))
+
+	obj := &FlagCopy{}
+	cmd := cliveflag.Build(obj)
+	cmd.FlagSet.SetOutput(io.Discard)
+	assert.NoError(t, cmd.Run(context.Background(), []string{"-out", out, in}))
+	assertClosed(t, obj.files)
+	data, _ := os.ReadFile(out)
+	assert.Equal(t, "hello", string(data))
+
+	err := cmd.Run(context.Background(), []string{"-out", out, filepath.Join(dir, "missing")})
+	assert.True(t, strings.HasSuffix(err.Error(), "does not exist"), err.Error())
+}
@@ -0,0 +1,251 @@
+// Code generated by clive-gen. DO NOT EDIT.
+
+package gen
+
+import (
+	"strings"
+	"time"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/urfave/cli/v2"
+)
+
+func (obj *Serve) CliveCommand() clive.GeneratedCommand {
+	if obj.Command == nil {
+		obj.Command = new(clive.Command)
+	}
+	command := clive.GeneratedCommand{
+		Command:   obj.Command,
+		Usage:     "serve files",
+		ArgsUsage: "ROOT [FILES [FILES]]",
+		Required:  []string{"listen-port"},
+	}
+	if obj.Run != nil {
+		command.Run = obj.Run
+	}
+	return command
+}
+
+func (obj *Serve) CliveFlags(bo *clive.BuildOptions) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "listen-host",
+			EnvVars: []string{bo.EnvVar("LISTEN_HOST")},
+			Value:   "localhost",
+		},
+		&cli.IntFlag{
+			Name:    "listen-port",
+			EnvVars: []string{bo.EnvVar("LISTEN_PORT")},
+		},
+		&cli.StringFlag{
+			Name:    "level",
+			EnvVars: []string{bo.EnvVar("LEVEL")},
+			Aliases: []string{"l"},
+			Value:   "info",
+			Usage:   clive.UsageWithVariants("", (*Level)(nil).Variants()),
+		},
+		&cli.StringSliceFlag{
+			Name:    "levels",
+			EnvVars: []string{bo.EnvVar("LEVELS")},
+			Usage:   clive.UsageWithVariants("extra levels", (*Level)(nil).Variants()),
+		},
+		&cli.DurationFlag{
+			Name:    "timeout",
+			EnvVars: []string{bo.EnvVar("TIMEOUT")},
+			Value:   time.Duration(5000000000),
+		},
+		&cli.Float64Flag{
+			Name:    "ratio",
+			EnvVars: []string{bo.EnvVar("RATIO")},
+			Value:   float64(float32(0.5)),
+		},
+		&cli.Uint64Flag{
+			Name:    "limit",
+			EnvVars: []string{"SERVE_LIMIT"},
+		},
+		&cli.BoolFlag{
+			Name:    "verbose",
+			EnvVars: []string{bo.EnvVar("VERBOSE")},
+			Aliases: []string{"v"},
+			Count:   new(int),
+		},
+		&cli.StringSliceFlag{
+			Name:    "retries",
+			EnvVars: []string{bo.EnvVar("RETRIES")},
+		},
+		&cli.StringSliceFlag{
+			Name:    "features",
+			EnvVars: []string{bo.EnvVar("FEATURES")},
+			Value:   cli.NewStringSlice("true", "false"),
+		},
+		&cli.Float64SliceFlag{
+			Name:    "weights",
+			EnvVars: []string{bo.EnvVar("WEIGHTS")},
+		},
+		&cli.StringFlag{
+			Name:    "secret",
+			EnvVars: []string{bo.EnvVar("SECRET")},
+			Hidden:  true,
+		},
+	}
+}
+
+func (obj *Serve) CliveBind(ctx *cli.Context) (err error) {
+	args := ctx.Args().Slice()
+	if len(args) == 0 {
+		return &clive.TooFewArgumentsError{Name: "ROOT"}
+	} else {
+		if err = clive.ParseValue(&obj.Root, args[0]); err != nil {
+			return &clive.FieldBindError{Field: "Root", Type: "string", Source: "positional argument ROOT", Value: args[0], Err: err}
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		if err = clive.ParseValue(&obj.Files, "index\\,old.html,index.html"); err != nil {
+			return &clive.FieldBindError{Field: "Files", Type: "[]string", Source: "default value of positional argument FILES", Value: "index\\,old.html,index.html", Err: err}
+		}
+	} else {
+		if err = clive.ParseValues(&obj.Files, args); err != nil {
+			return &clive.FieldBindError{Field: "Files", Type: "[]string", Source: "positional argument FILES", Value: strings.Join(args, " "), Err: err}
+		}
+		args = []string{}
+	}
+	{
+		obj.Listen.Host = ctx.String("listen-host")
+	}
+	if ctx.IsSet("listen-port") {
+		obj.Listen.Port = ctx.Int("listen-port")
+	}
+	{
+		if err = clive.UnmarshalVariant(&obj.Level, ctx.String("level")); err != nil {
+			return &clive.FieldBindError{Field: "Level", Type: "gen.Level", Source: clive.FlagSource(ctx, "level"), Value: clive.RawFlagValue(ctx, "level"), Err: err}
+		}
+	}
+	if ctx.IsSet("levels") {
+		values := ctx.StringSlice("levels")
+		converted := make([]Level, len(values))
+		for i, value := range values {
+			if err = clive.UnmarshalVariant(&converted[i], value); err != nil {
+				return &clive.FieldBindError{Field: "Levels", Type: "[]gen.Level", Source: clive.FlagSource(ctx, "levels"), Value: clive.RawFlagValue(ctx, "levels"), Err: err}
+			}
+		}
+		obj.Levels = converted
+	}
+	{
+		obj.Timeout = ctx.Duration("timeout")
+	}
+	{
+		obj.Ratio = float32(ctx.Float64("ratio"))
+	}
+	if ctx.IsSet("limit") {
+		if obj.Limit == nil {
+			obj.Limit = new(uint64)
+		}
+		(*obj.Limit) = ctx.Uint64("limit")
+	}
+	if ctx.IsSet("verbose") {
+		obj.Verbose.Value = ctx.Count("verbose")
+	}
+	if ctx.IsSet("retries") {
+		values := ctx.StringSlice("retries")
+		obj.Retries = make([]time.Duration, len(values))
+		for i, value := range values {
+			if err = clive.ParseValue(&obj.Retries[i], value); err != nil {
+				return &clive.FieldBindError{Field: "Retries", Type: "[]time.Duration", Source: clive.FlagSource(ctx, "retries"), Value: clive.RawFlagValue(ctx, "retries"), Err: err}
+			}
+		}
+	}
+	{
+		values := ctx.StringSlice("features")
+		obj.Features = make([]bool, len(values))
+		for i, value := range values {
+			if err = clive.ParseValue(&obj.Features[i], value); err != nil {
+				return &clive.FieldBindError{Field: "Features", Type: "[]bool", Source: clive.FlagSource(ctx, "features"), Value: clive.RawFlagValue(ctx, "features"), Err: err}
+			}
+		}
+	}
+	if ctx.IsSet("weights") {
+		values := ctx.Float64Slice("weights")
+		obj.Weights = make([]float32, len(values))
+		for i, value := range values {
+			obj.Weights[i] = float32(value)
+		}
+	}
+	if ctx.IsSet("secret") {
+		obj.Secret = ctx.String("secret")
+	}
+	if len(args) > 0 {
+		return &clive.TooManyArgumentsError{Args: args}
+	}
+	return nil
+}
+
+func (obj *Serve) CliveHashes() []string {
+	return []string{"548fb776b871dafb", "26f13090df3f1ed5", "ec55342f69fbd62a", "11f03028cbe8f88d", "1941fb6c4a215f8a", "fc71c208f0f28ef3", "0382eb795ddd5d25", "e2e7250eebda6d08", "87dd04c320277b83", "b6befb390d59d2fb", "9ca882847550d2e7", "db4cc5d5bb885cac", "3120340bd2bd3cb5", "a21e1fed4837e5d3"}
+}
+
+func (obj *App) CliveCommand() clive.GeneratedCommand {
+	if obj.Command == nil {
+		obj.Command = new(clive.Command)
+	}
+	if obj.Subcommands.Serve == nil {
+		obj.Subcommands.Serve = new(Serve)
+	}
+	command := clive.GeneratedCommand{
+		Command:     obj.Command,
+		Name:        "gen",
+		Usage:       "generated commands",
+		Subcommands: []interface{}{obj.Subcommands.Serve},
+	}
+	if obj.Run != nil {
+		command.Run = obj.Run
+	}
+	return command
+}
+
+func (obj *App) CliveFlags(bo *clive.BuildOptions) []cli.Flag {
+	return []cli.Flag{
+		&clive.BoolFlag{BoolFlag: cli.BoolFlag{
+			Name:    "debug",
+			EnvVars: []string{bo.EnvVar("DEBUG")},
+		}},
+		&cli.StringFlag{
+			Name:    "name",
+			EnvVars: []string{bo.EnvVar("NAME")},
+			Value:   "anonymous",
+		},
+		&cli.Int64Flag{
+			Name:    "count",
+			EnvVars: []string{bo.EnvVar("COUNT")},
+			Value:   3,
+		},
+		&cli.IntSliceFlag{
+			Name:    "ports",
+			EnvVars: []string{bo.EnvVar("PORTS")},
+			Value:   cli.NewIntSlice(80, 443),
+		},
+	}
+}
+
+func (obj *App) CliveBind(ctx *cli.Context) (err error) {
+	if ctx.IsSet("debug") {
+		obj.Debug = ctx.Bool("debug")
+	}
+	{
+		if obj.Name == nil {
+			obj.Name = new(string)
+		}
+		(*obj.Name) = ctx.String("name")
+	}
+	{
+		obj.Count = ctx.Int64("count")
+	}
+	{
+		obj.Ports = ctx.IntSlice("ports")
+	}
+	return nil
+}
+
+func (obj *App) CliveHashes() []string {
+	return []string{"44ebff57e2846931", "db0759e4ae8459fb", "e2f2a3ebf714be1b", "6f8e64728b464239"}
+}
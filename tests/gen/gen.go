@@ -0,0 +1,80 @@
+// Package gen holds command structs with code generated by clive-gen.
+package gen
+
+import (
+	"fmt"
+	"time"
+
+	clive "github.com/ASMfreaK/clive2"
+)
+
+//go:generate go run github.com/ASMfreaK/clive2/cmd/clive-gen
+
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+)
+
+var levelStrings = []string{
+	Debug: "debug",
+	Info:  "info",
+	Warn:  "warn",
+}
+
+func (l *Level) Variants() []string {
+	return levelStrings
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	for i, variant := range levelStrings {
+		if string(text) == variant {
+			*l = Level(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid level: %s", text)
+}
+
+type Endpoint struct {
+	Host string `cli:"default:localhost"`
+	Port int    `cli:"required"`
+}
+
+type Serve struct {
+	*clive.Command `cli:"usage:'serve files'"`
+
+	Run clive.RunFunc
+
+	Root  string   `cli:"positional"`
+	Files []string `cli:"positional,required:false,default:'index\\,old.html,index.html'"`
+
+	Listen   Endpoint      `cli:"inline"`
+	Level    Level         `cli:"default:info,alias:l"`
+	Levels   []Level       `cli:"usage:'extra levels'"`
+	Timeout  time.Duration `cli:"default:5s"`
+	Ratio    float32       `cli:"default:0.5"`
+	Limit    *uint64       `cli:"env:'SERVE_LIMIT'"`
+	Verbose  clive.Counter `cli:"alias:v"`
+	Retries  []time.Duration
+	Features []bool `cli:"default:'true,false'"`
+	Weights  []float32
+	Secret   string `cli:"hidden:true"`
+}
+
+type App struct {
+	*clive.Command `cli:"name:'gen',usage:'generated commands'"`
+
+	Run clive.RunFunc
+
+	Subcommands struct {
+		*Serve
+	}
+
+	Debug bool
+	Name  *string `cli:"default:'anonymous'"`
+	Count int64   `cli:"default:3"`
+	Ports []int   `cli:"default:'80,443'"`
+}
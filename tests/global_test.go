@@ -0,0 +1,144 @@
+package clive2_test
+
+import (
+	"bytes"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+type GlobalLeaf struct {
+	*clive.Command `cli:"name:leaf"`
+	Verbose        bool
+	Name           string
+}
+
+func (*GlobalLeaf) Action(*cli.Context) error { return nil }
+
+type GlobalSub struct {
+	*clive.Command `cli:"name:sub"`
+	Subcommands    struct {
+		*GlobalLeaf
+	}
+	Force bool
+}
+
+func (*GlobalSub) Action(*cli.Context) error { return nil }
+
+type GlobalRoot struct {
+	*clive.Command
+	Subcommands struct {
+		*GlobalSub
+	}
+	Verbose bool   `cli:"global,negatable"`
+	Config  string `cli:"global,default:app.yaml"`
+	Log     struct {
+		Level string `cli:"default:info"`
+	} `cli:"inline,global"`
+}
+
+func (*GlobalRoot) Action(*cli.Context) error { return nil }
+
+func TestGlobal(t *testing.T) {
+	obj, err := run(&GlobalRoot{}, "sub", "--verbose", "leaf", "--config", "x.yaml", "--log-level", "debug")
+	assert.NoError(t, err)
+	assert.True(t, obj.Verbose)
+	assert.Equal(t, "x.yaml", obj.Config)
+	assert.Equal(t, "debug", obj.Log.Level)
+	// descendants get the value of global flags they have a field for
+	assert.True(t, obj.Subcommands.GlobalSub.Subcommands.GlobalLeaf.Verbose)
+
+	obj, err = run(&GlobalRoot{}, "--verbose", "--config", "y.yaml", "sub", "leaf")
+	assert.NoError(t, err)
+	assert.True(t, obj.Verbose)
+	assert.Equal(t, "y.yaml", obj.Config)
+	assert.True(t, obj.Subcommands.GlobalSub.Subcommands.GlobalLeaf.Verbose)
+
+	// the innermost value wins
+	obj, err = run(&GlobalRoot{}, "--verbose", "--config", "y.yaml", "sub", "--no-verbose", "leaf", "--config", "z.yaml")
+	assert.NoError(t, err)
+	assert.False(t, obj.Verbose)
+	assert.Equal(t, "z.yaml", obj.Config)
+	assert.False(t, obj.Subcommands.GlobalSub.Subcommands.GlobalLeaf.Verbose)
+
+	obj, err = run(&GlobalRoot{}, "sub")
+	assert.NoError(t, err)
+	assert.False(t, obj.Verbose)
+	assert.Equal(t, "app.yaml", obj.Config)
+	assert.Equal(t, "info", obj.Log.Level)
+
+	// the environment doesn't override a value given to an ancestor
+	t.Setenv("CONFIG", "env.yaml")
+	obj, err = run(&GlobalRoot{}, "--config", "cli.yaml", "sub", "leaf")
+	assert.NoError(t, err)
+	assert.Equal(t, "cli.yaml", obj.Config)
+	obj, err = run(&GlobalRoot{}, "sub", "leaf")
+	assert.NoError(t, err)
+	assert.Equal(t, "env.yaml", obj.Config)
+	obj, err = run(&GlobalRoot{}, "sub", "leaf", "--config", "leaf.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "leaf.yaml", obj.Config)
+
+	// flags that aren't global stay where they are declared
+	_, err = run(&GlobalRoot{}, "sub", "leaf", "--force")
+	assert.EqualError(t, err, "flag provided but not defined: -force")
+
+	assert.PanicsWithError(t, "global field Name can't be positional", func() {
+		clive.Build(&struct {
+			*clive.Command
+			Name string `cli:"global,positional"`
+		}{})
+	})
+	assert.PanicsWithError(t, "global field Name can't be required", func() {
+		clive.Build(&struct {
+			*clive.Command
+			Name string `cli:"global,required"`
+		}{})
+	})
+}
+
+type GlobalColors struct {
+	*clive.Command
+	Subcommands struct {
+		*GlobalLeaf
+	}
+	Colors []ColorT `cli:"global,sep:;"`
+}
+
+func (*GlobalColors) Action(*cli.Context) error { return nil }
+
+func TestGlobalBindError(t *testing.T) {
+	_, err := run(&GlobalColors{}, "leaf", "--colors", "Red;Purple")
+	var bind *clive.FieldBindError
+	if assert.ErrorAs(t, err, &bind) {
+		assert.Equal(t, "Colors", bind.Field)
+		assert.Equal(t, "[]clive2_test.ColorT", bind.Type)
+		assert.Equal(t, "flag colors", bind.Source)
+		assert.Equal(t, "Red,Purple", bind.Value)
+	}
+	assert.Equal(t, clive.UsageErrors, clive.ClassOf(err))
+}
+
+func TestGlobalHelp(t *testing.T) {
+	spec, err := clive.Describe(&GlobalRoot{})
+	assert.NoError(t, err)
+	leaf := spec.Subcommand("sub leaf")
+	var names []string
+	for _, flag := range leaf.GlobalFlags {
+		names = append(names, flag.Name)
+	}
+	// --verbose of the leaf replaces the global one
+	assert.Equal(t, []string{"config", "log-level"}, names)
+	assert.Equal(t, []string{"Config"}, leaf.GlobalFlags[0].FieldPath)
+
+	b := &bytes.Buffer{}
+	assert.NoError(t, (&clive.TextHelpRenderer{Width: 80}).RenderHelp(b, spec.Subcommand("sub")))
+	assert.Contains(t, b.String(), `
+GLOBAL OPTIONS:
+   --[no-]verbose     [$VERBOSE]
+   --config value     (default: "app.yaml") [$CONFIG]
+   --log-level value  (default: "info") [$LOG_LEVEL]
+`)
+}
@@ -0,0 +1,144 @@
+package clive2_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestHelpRenderers(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		gotC := clive.BuildCustom(&App{}, clive.BuildOptions{HelpRenderer: &clive.JSONHelpRenderer{}})
+		b := &bytes.Buffer{}
+		gotC.Name = "app"
+		gotC.Writer = b
+		err := gotC.Run([]string{"app", "--help"})
+		assert.NoError(t, err)
+
+		spec := &clive.CommandSpec{}
+		assert.NoError(t, json.Unmarshal(b.Bytes(), spec))
+		assert.Equal(t, "app", spec.HelpName)
+		assert.Equal(t, "some-version-string", spec.Version)
+		assert.Len(t, spec.Subcommands, 3)
+		assert.Equal(t, "setoption", spec.Subcommands[2].Subcommands[0].Name)
+		assert.Len(t, spec.Subcommands[2].Subcommands[0].Positionals, 2)
+
+		var color, inputPort *clive.FieldSpec
+		for _, flag := range spec.Flags {
+			switch flag.Name {
+			case "color":
+				color = flag
+			case "input-port":
+				inputPort = flag
+			}
+		}
+		if assert.NotNil(t, color) {
+			assert.Equal(t, []string{"Red", "Green", "Blue"}, color.Variants)
+			assert.Equal(t, "", color.Usage)
+			assert.Equal(t, "Blue", *color.Default)
+			assert.Equal(t, []string{"COLOR"}, color.Envs)
+		}
+		if assert.NotNil(t, inputPort) {
+			assert.Equal(t, "input", inputPort.Group)
+			assert.Equal(t, "int", inputPort.Type)
+			assert.True(t, inputPort.Required)
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		gotC := clive.BuildCustom(&App{}, clive.BuildOptions{HelpRenderer: &clive.TextHelpRenderer{Width: 40}})
+		b := &bytes.Buffer{}
+		gotC.Name = "app"
+		gotC.Writer = b
+		err := gotC.Run([]string{"app", "--input-port=1", "--output-port=2", "config", "setoption", "--help"})
+		assert.NoError(t, err)
+		assert.Contains(t, b.String(), "app config setoption NAME VALUE")
+
+		b.Reset()
+		err = gotC.Run([]string{"app", "--help"})
+		assert.NoError(t, err)
+		help := b.String()
+		assert.Contains(t, help, "[$POSTGRES_DSN]")
+		assert.Contains(t, help, "INPUT OPTIONS:")
+		assert.NotContains(t, help, "process-schedule")
+		for _, line := range strings.Split(help, "\n") {
+			assert.LessOrEqual(t, len(line), 40, line)
+		}
+	})
+}
+
+type failingRenderer struct{}
+
+func (failingRenderer) RenderHelp(io.Writer, *clive.CommandSpec) error {
+	return errors.New("broken")
+}
+
+func TestHelpPerApp(t *testing.T) {
+	printer := reflect.ValueOf(cli.HelpPrinter).Pointer()
+	app := clive.BuildCustom(&App{}, clive.BuildOptions{HelpRenderer: failingRenderer{}})
+	assert.Equal(t, printer, reflect.ValueOf(cli.HelpPrinter).Pointer())
+
+	b, e := &bytes.Buffer{}, &bytes.Buffer{}
+	app.Name = "app"
+	app.Writer, app.ErrWriter = b, e
+	err := app.Run([]string{"app", "--help"})
+	assert.NoError(t, err)
+	assert.Equal(t, "failed to render help of app: broken\n", e.String())
+	assert.Contains(t, b.String(), "GLOBAL OPTIONS:")
+
+	b.Reset()
+	e.Reset()
+	err = app.Run([]string{"app", "--input-port=1", "--output-port=2", "config", "--help"})
+	assert.NoError(t, err)
+	assert.Equal(t, "failed to render help of app config: broken\n", e.String())
+	assert.Contains(t, b.String(), "app config command [command options]")
+
+	// other Apps keep their renderer, templates set by hand are left alone
+	app = clive.BuildCustom(&App{}, clive.BuildOptions{HelpRenderer: &clive.TextHelpRenderer{Width: 40}})
+	app.Name = "app"
+	app.Writer = b
+	app.Command("config").Command("setoption").CustomHelpTemplate = "custom {{.Name}}\n"
+	b.Reset()
+	err = app.Run([]string{"app", "--input-port=1", "--output-port=2", "config", "--help"})
+	assert.NoError(t, err)
+	assert.Contains(t, b.String(), "app config")
+	assert.NotContains(t, b.String(), "GLOBAL OPTIONS:")
+	b.Reset()
+	err = app.Run([]string{"app", "--input-port=1", "--output-port=2", "config", "setoption", "--help"})
+	assert.NoError(t, err)
+	assert.Equal(t, "custom setoption\n", b.String())
+}
+
+// countingRenderer counts the help it renders.
+type countingRenderer struct {
+	rendered []string
+}
+
+func (r *countingRenderer) RenderHelp(w io.Writer, spec *clive.CommandSpec) error {
+	r.rendered = append(r.rendered, spec.HelpName)
+	return clive.DefaultHelpRenderer.RenderHelp(w, spec)
+}
+
+func TestHelpRenderedOnDemand(t *testing.T) {
+	renderer := &countingRenderer{}
+	app := clive.BuildCustom(&SuggestApp{}, clive.BuildOptions{HelpRenderer: renderer})
+	app.Name = "app"
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	err := app.Run([]string{"app", "serve", "--port", "80", "--", "--help"})
+	assert.NoError(t, err)
+	assert.Nil(t, renderer.rendered)
+
+	err = app.Run([]string{"app", "serve", "-h"})
+	assert.NoError(t, err)
+	err = app.Run([]string{"app", "--help"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app serve", "app"}, renderer.rendered)
+}
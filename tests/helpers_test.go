@@ -0,0 +1,46 @@
+package clive2_test
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/ASMfreaK/clive2/clivecore"
+	"github.com/ASMfreaK/clive2/cliveflag"
+	"github.com/urfave/cli/v2"
+)
+
+// run builds the command struct obj with clive.Build, runs it with args and
+// returns what got bound. A nil Run field of obj is set to do nothing.
+func run[T any](obj *T, args ...string) (T, error) {
+	return runCustom(obj, clive.DefaultBuildOptions, args...)
+}
+
+// runCustom is run with BuildOptions.
+func runCustom[T any](obj *T, o clive.BuildOptions, args ...string) (T, error) {
+	if field := reflect.ValueOf(obj).Elem().FieldByName("Run"); field.IsValid() && field.IsNil() {
+		field.Set(reflect.ValueOf(clive.RunFunc(func(*clive.Command, *cli.Context) error { return nil })))
+	}
+	app := clive.BuildCustom(obj, o)
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	err := app.Run(append([]string{app.Name}, args...))
+	return *obj, err
+}
+
+// runFlag builds the command struct obj with cliveflag.Build, runs it with
+// args and returns what got bound. Commands without an Action are only bound.
+func runFlag[T any](obj *T, args ...string) (T, error) {
+	return runFlagCustom(obj, clivecore.Options{}, args...)
+}
+
+// runFlagCustom is runFlag with Options.
+func runFlagCustom[T any](obj *T, o clivecore.Options, args ...string) (T, error) {
+	cmd := cliveflag.BuildCustom(obj, o)
+	cmd.FlagSet.SetOutput(io.Discard)
+	err := cmd.Run(context.Background(), args)
+	if err != nil && err.Error() == clivecore.ErrCommandNotImplemented().Error() {
+		err = nil
+	}
+	return *obj, err
+}
@@ -0,0 +1,120 @@
+package clive2_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+type InjectConfig struct {
+	DSN string
+}
+
+type InjectDB struct {
+	DSN string
+	log *[]string
+}
+
+func (db *InjectDB) Close() error {
+	*db.log = append(*db.log, "close db")
+	return nil
+}
+
+type InjectCache struct {
+	DB  *InjectDB
+	log *[]string
+}
+
+func (c *InjectCache) Close() error {
+	*c.log = append(*c.log, "close cache")
+	return nil
+}
+
+type InjectQuery struct {
+	*clive.Command `cli:"name:query"`
+	Cache          *InjectCache `cli:"inject"`
+	log            *[]string
+}
+
+func (q *InjectQuery) Before(*cli.Context) error {
+	*q.log = append(*q.log, "before query "+q.Cache.DB.DSN)
+	return nil
+}
+
+func (q *InjectQuery) Action(*cli.Context) error {
+	*q.log = append(*q.log, "query")
+	return nil
+}
+
+type InjectApp struct {
+	*clive.Command
+	Subcommands struct {
+		*InjectQuery
+	}
+	DSN    string        `cli:"default:postgres://localhost"`
+	Config *InjectConfig `cli:"inject"`
+}
+
+func (*InjectApp) Action(*cli.Context) error { return nil }
+
+func TestInject(t *testing.T) {
+	var log []string
+	obj := &InjectApp{}
+	obj.Subcommands.InjectQuery = &InjectQuery{log: &log}
+	app := clive.BuildCustom(obj, clive.BuildOptions{Providers: []clive.Provider{
+		clive.Provide(func(root *InjectApp) *InjectConfig { return &InjectConfig{DSN: root.DSN} }),
+		clive.Provide(func(db *InjectDB) (*InjectCache, error) {
+			log = append(log, "open cache")
+			return &InjectCache{DB: db, log: &log}, nil
+		}),
+		clive.Provide(func(config *InjectConfig) (*InjectDB, error) {
+			log = append(log, "open db")
+			return &InjectDB{DSN: config.DSN, log: &log}, nil
+		}),
+	}})
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	err := app.Run([]string{"app", "--dsn", "postgres://db", "query"})
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://db", obj.Config.DSN)
+	assert.Equal(t, []string{"open db", "open cache", "before query postgres://db", "query", "close cache", "close db"}, log)
+
+	// values are only constructed for the commands being run
+	log = nil
+	err = app.Run([]string{"app"})
+	assert.NoError(t, err)
+	assert.Empty(t, log)
+}
+
+func TestInjectErrors(t *testing.T) {
+	obj := &InjectApp{}
+	obj.Subcommands.InjectQuery = &InjectQuery{}
+	assert.PanicsWithError(t, "no provider for *clive2_test.InjectDB needed by provider of *clive2_test.InjectCache", func() {
+		clive.BuildCustom(obj, clive.BuildOptions{Providers: []clive.Provider{
+			clive.Provide(func() *InjectConfig { return nil }),
+			clive.Provide(func(*InjectDB) *InjectCache { return nil }),
+		}})
+	})
+	assert.PanicsWithError(t, "providers depend on each other: *clive2_test.InjectCache -> *clive2_test.InjectDB -> *clive2_test.InjectCache", func() {
+		clive.BuildCustom(obj, clive.BuildOptions{Providers: []clive.Provider{
+			clive.Provide(func() *InjectConfig { return nil }),
+			clive.Provide(func(*InjectDB) *InjectCache { return nil }),
+			clive.Provide(func(*InjectCache) *InjectDB { return nil }),
+		}})
+	})
+	assert.PanicsWithError(t, "provider func() (string, string) must be a function returning a value, or a value and an error", func() {
+		clive.Provide(func() (string, string) { return "", "" })
+	})
+
+	// providers failing stop the command
+	app := clive.BuildCustom(&InjectApp{}, clive.BuildOptions{Providers: []clive.Provider{
+		clive.Provide(func() (*InjectConfig, error) { return nil, errors.New("no config") }),
+		clive.Provide(func() *InjectCache { return nil }),
+	}})
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	err := app.Run([]string{"app"})
+	assert.EqualError(t, err, "failed to inject field Config: no config")
+}
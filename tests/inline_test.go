@@ -0,0 +1,130 @@
+package clive2_test
+
+import (
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+)
+
+type LogOptions struct {
+	Verbose bool
+	Format  string `cli:"default:text"`
+}
+
+type DBOptions struct {
+	Host string `cli:"default:localhost"`
+	Port int    `cli:"default:5432"`
+}
+
+type Serve struct {
+	*clive.Command
+	LogOptions
+	Run clive.RunFunc
+
+	Listen   string
+	DB       *DBOptions `cli:"inline,prefix:db"`
+	Cache    *DBOptions `cli:"inline,prefix:cache"`
+	Settings struct {
+		Timeout int
+	} `cli:"inline,prefix:''"`
+}
+
+func TestInlinePrefix(t *testing.T) {
+	spec, err := clive.Describe(&Serve{})
+	assert.NoError(t, err)
+	var names, envs []string
+	for _, flag := range spec.Flags {
+		names = append(names, flag.Name)
+		envs = append(envs, flag.Envs...)
+	}
+	assert.Equal(t, []string{"verbose", "format", "listen", "db-host", "db-port", "cache-host", "cache-port", "timeout"}, names)
+	assert.Equal(t, []string{"VERBOSE", "FORMAT", "LISTEN", "DB_HOST", "DB_PORT", "CACHE_HOST", "CACHE_PORT", "TIMEOUT"}, envs)
+
+	got, err := run(&Serve{}, "--verbose", "--db-port", "6543", "--timeout", "3")
+	assert.NoError(t, err)
+	assert.Equal(t, LogOptions{Verbose: true, Format: "text"}, got.LogOptions)
+	assert.Equal(t, &DBOptions{Host: "localhost", Port: 6543}, got.DB)
+	assert.Nil(t, got.Cache)
+	assert.Equal(t, 3, got.Settings.Timeout)
+
+	t.Setenv("CACHE_HOST", "redis")
+	got, err = run(&Serve{})
+	assert.NoError(t, err)
+	assert.Nil(t, got.DB)
+	assert.Equal(t, &DBOptions{Host: "redis", Port: 5432}, got.Cache)
+
+	assert.PanicsWithError(t, `prefix is only supported for inline fields, Name is not one`, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Name string `cli:"prefix:db"`
+		}{})
+	})
+}
+
+func TestInlinePrefixBind(t *testing.T) {
+	got, err := runFlag(&Serve{}, "-format", "json", "-db-host", "db")
+	assert.NoError(t, err)
+	assert.Equal(t, "json", got.Format)
+	assert.Equal(t, &DBOptions{Host: "db", Port: 5432}, got.DB)
+	assert.Nil(t, got.Cache)
+
+	obj := &Serve{}
+	err = clive.BindJSON(obj, "", []byte(`{"cache": {"port": 1}}`))
+	assert.NoError(t, err)
+	assert.Nil(t, obj.DB)
+	assert.Equal(t, &DBOptions{Host: "localhost", Port: 1}, obj.Cache)
+}
+
+type Migrate struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	DB DBOptions `cli:"inline,prefix:db,defaults:'host=db.internal,port=6000'"`
+}
+
+func TestInlineDefaults(t *testing.T) {
+	got, err := run(&Migrate{}, "--db-port", "7000")
+	assert.NoError(t, err)
+	assert.Equal(t, DBOptions{Host: "db.internal", Port: 7000}, got.DB)
+
+	spec, err := clive.Describe(&Migrate{})
+	assert.NoError(t, err)
+	assert.Equal(t, "6000", *spec.Flags[1].Default)
+
+	// the override only applies to the group it is given for
+	spec, err = clive.Describe(&Serve{})
+	assert.NoError(t, err)
+	assert.Equal(t, "5432", *spec.Flags[4].Default)
+
+	_, err = clive.Describe(&struct {
+		*clive.Command
+		DB DBOptions `cli:"inline,defaults:'hots=db,prot=1,port=1'"`
+	}{})
+	assert.EqualError(t, err, `defaults of inline field DB: no flag named hots, prot`)
+	_, err = clive.Describe(&struct {
+		*clive.Command
+		DB DBOptions `cli:"inline,defaults:host"`
+	}{})
+	assert.EqualError(t, err, `malformed 'defaults' item 'host', want name=value`)
+	_, err = clive.Describe(&struct {
+		*clive.Command
+		DB DBOptions `cli:"inline,defaults:'port=abc'"`
+	}{})
+	assert.ErrorContains(t, err, `defaults of inline field DB: bad default of db-port: `)
+}
+
+type ListOptions struct {
+	Tags  []string
+	Names []string `cli:"sep:;"`
+}
+
+func TestInlineDefaultsEscaped(t *testing.T) {
+	got, err := run(&struct {
+		*clive.Command
+		Run  clive.RunFunc
+		List ListOptions `cli:"inline,defaults:'tags=a\\,b,\"names=c;d,e\"'"`
+	}{})
+	assert.NoError(t, err)
+	assert.Equal(t, ListOptions{Tags: []string{"a", "b"}, Names: []string{"c", "d,e"}}, got.List)
+}
@@ -0,0 +1,73 @@
+package clive2_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchema(t *testing.T) {
+	schemas, err := clive.JSONSchema(&App{})
+	assert.NoError(t, err)
+	assert.Len(t, schemas, 5)
+
+	root := schemas[""]
+	if assert.NotNil(t, root) {
+		assert.Equal(t, "object", root.Type)
+		assert.Equal(t, "test command", root.Description)
+		assert.Equal(t, []string{"input", "output"}, root.Required)
+		assert.Equal(t, &clive.Schema{Type: "string", Enum: []string{"Red", "Green", "Blue"}, Default: "Blue"}, root.Properties["color"])
+		assert.Equal(t, "array", root.Properties["uints-64"].Type)
+		assert.Equal(t, "integer", root.Properties["uints-64"].Items.Type)
+
+		input := root.Properties["input"]
+		if assert.NotNil(t, input) {
+			assert.Equal(t, "object", input.Type)
+			assert.Equal(t, []string{"port"}, input.Required)
+			assert.Equal(t, []string{"server", "client"}, input.Properties["role"].Enum)
+			assert.Equal(t, "integer", input.Properties["port"].Type)
+		}
+	}
+
+	setOption := schemas["config setoption"]
+	if assert.NotNil(t, setOption) {
+		assert.Equal(t, []string{"name", "value"}, setOption.Required)
+	}
+
+	_, err = json.Marshal(schemas)
+	assert.NoError(t, err)
+}
+
+func TestBindJSON(t *testing.T) {
+	app := &App{}
+	err := clive.BindJSON(app, "", []byte(`{
+		"postgres-dsn": "postgres://",
+		"api-address": "127.0.0.1",
+		"uints-64": [1, 2, 3],
+		"input": {"role": "client", "port": 80},
+		"output": {"port": 8080}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://", app.PostgresDsn)
+	assert.Equal(t, "127.0.0.1", *app.ApplicationAPIAddress)
+	assert.Equal(t, []uint64{1, 2, 3}, app.Uints64)
+	assert.Equal(t, Blue, app.Color)
+	assert.Equal(t, ComposedOption{Role: Client, Port: 80}, app.Input)
+	assert.Equal(t, ComposedOption{Role: Server, Port: 8080}, app.Output)
+
+	err = clive.BindJSON(app, "config setoption", []byte(`{"name": "answer", "value": "42"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "answer", app.Subcommands.Config.Subcommands.SetOption.Name)
+	assert.Equal(t, float64(42), app.Subcommands.Config.Subcommands.SetOption.Value.Value)
+
+	err = clive.BindJSON(&App{}, "", []byte(`{"input": {"port": 1}}`))
+	assert.EqualError(t, err, "failed to set field Output.Port from JSON property output.port: property is required")
+
+	err = clive.BindJSON(&App{}, "", []byte(`{"input": {"port": 1, "nope": 1}}`))
+	assert.EqualError(t, err, "unknown JSON property input.nope")
+
+	err = clive.BindJSON(&App{}, "config nope", []byte(`{}`))
+	assert.EqualError(t, err, `no command "nope" in "config nope"`)
+}
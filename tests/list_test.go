@@ -0,0 +1,172 @@
+package clive2_test
+
+import (
+	"strings"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestSplitList(t *testing.T) {
+	for _, tt := range []struct {
+		s, sep string
+		items  []string
+		err    string
+	}{
+		{s: "a,b", items: []string{"a", "b"}},
+		{s: "", items: []string{""}},
+		{s: `a\,b,c`, items: []string{"a,b", "c"}},
+		{s: `"a,b",c`, items: []string{"a,b", "c"}},
+		{s: `"say \"hi\", bye"`, items: []string{`say "hi", bye`}},
+		{s: `C:\dir,\\,x`, items: []string{`C:\dir`, `\`, "x"}},
+		{s: `a"b,c`, items: []string{`a"b`, "c"}},
+		{s: "select 1, 2;select 3", sep: ";", items: []string{"select 1, 2", "select 3"}},
+		{s: `a\;b;c`, sep: ";", items: []string{"a;b", "c"}},
+		{s: "a::b", sep: "::", items: []string{"a", "b"}},
+		{s: `"a,b`, err: `unterminated quote in "\"a,b"`},
+		{s: `"a"b,c`, err: `unexpected text after closing quote in "\"a\"b,c"`},
+	} {
+		items, err := clive.SplitList(tt.s, tt.sep)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.items, items, tt.s)
+	}
+}
+
+type Word struct {
+	Text string
+}
+
+func (w *Word) UnmarshalText(text []byte) error {
+	w.Text = strings.ToUpper(string(text))
+	return nil
+}
+
+type Lists struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Queries []string `cli:"sep:;,env:QUERIES,default:'select 1, 2;select 3'"`
+	Plain   []string `cli:"default:'a\\,b,c',env:PLAIN"`
+	Nets    []string `cli:"envformat:lines,env:NETS"`
+	Ports   []int    `cli:"envformat:json,env:PORTS"`
+	Words   []Word   `cli:"sep:' ',default:'hello world'"`
+	Files   []string `cli:"positional,sep:':',default:'a:b'"`
+}
+
+func TestSeparators(t *testing.T) {
+	got, err := run(&Lists{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"select 1, 2", "select 3"}, got.Queries)
+	assert.Equal(t, []string{"a,b", "c"}, got.Plain)
+	assert.Nil(t, got.Nets)
+	assert.Equal(t, []Word{{"HELLO"}, {"WORLD"}}, got.Words)
+	assert.Equal(t, []string{"a", "b"}, got.Files)
+
+	got, err = run(&Lists{}, "--queries", "select 4, 5", "--queries", "select 6;select 7", "--words", `"big apple" pie`, "x:y")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"select 4, 5", "select 6", "select 7"}, got.Queries)
+	assert.Equal(t, []Word{{"BIG APPLE"}, {"PIE"}}, got.Words)
+	assert.Equal(t, []string{"x:y"}, got.Files)
+
+	t.Setenv("QUERIES", `select 1, 2;select '\;'`)
+	t.Setenv("NETS", "10.0.0.0/8\r\n\n192.168.0.0/16\n")
+	t.Setenv("PORTS", `[80, 443]`)
+	got, err = run(&Lists{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"select 1, 2", "select ';'"}, got.Queries)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.0.0/16"}, got.Nets)
+	assert.Equal(t, []int{80, 443}, got.Ports)
+
+	// the command line wins over the environment
+	got, err = run(&Lists{}, "--ports", "1,2")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, got.Ports)
+
+	// slices without sep or envformat are urfave/cli slice flags, which
+	// split at commas only, their defaults are escaped
+	got, err = run(&Lists{}, "--plain", `x\,y,z`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`x\`, "y", "z"}, got.Plain)
+
+	// an empty environment variable is an empty list
+	t.Setenv("QUERIES", "")
+	got, err = run(&Lists{})
+	assert.NoError(t, err)
+	assert.Empty(t, got.Queries)
+	t.Setenv("PLAIN", "")
+	got, err = runFlag(&Lists{})
+	assert.NoError(t, err)
+	assert.Empty(t, got.Plain)
+	assert.Empty(t, got.Queries)
+
+	t.Setenv("PORTS", `80`)
+	_, err = run(&Lists{})
+	assert.ErrorContains(t, err, `could not parse "80" as slice value from environment variable "PORTS" for flag ports: expected a JSON array: json: cannot unmarshal number`)
+
+	assert.PanicsWithError(t, `sep and envformat are only supported for slices, field Name has type string`, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Name string `cli:"sep:;"`
+		}{})
+	})
+	_, err = clive.ParseTag("envformat:yaml")
+	assert.EqualError(t, err, `unknown envformat "yaml", expected list, lines or json`)
+
+	spec, err := clive.Describe(&Lists{})
+	assert.NoError(t, err)
+	assert.Equal(t, ";", spec.Flags[0].Sep)
+	assert.Equal(t, "lines", spec.Flags[2].EnvFormat)
+
+	schemas, err := clive.JSONSchema(&Lists{})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"select 1, 2", "select 3"}, schemas[""].Properties["queries"].Default)
+	assert.Equal(t, []interface{}{"a,b", "c"}, schemas[""].Properties["plain"].Default)
+}
+
+func TestSeparatorsBind(t *testing.T) {
+	t.Setenv("PORTS", `[1, "2"]`)
+	got, err := runFlag(&Lists{}, "-queries", "select 1;select 2, 3")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"select 1", "select 2, 3"}, got.Queries)
+	assert.Equal(t, []int{1, 2}, got.Ports)
+	assert.Equal(t, []string{"a,b", "c"}, got.Plain)
+
+	t.Setenv("QUERIES", `a\;b;c`)
+	t.Setenv("PORTS", `[1`)
+	got, err = runFlag(&Lists{})
+	assert.EqualError(t, err, `failed to set field Ports (type []int) from environment variable PORTS: expected a JSON array: unexpected end of JSON input`)
+	assert.Equal(t, []string{"a;b", "c"}, got.Queries)
+}
+
+type NativeLists struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Names []string `cli:"default:'a,b'"`
+	Ports []int
+	Sizes []uint64
+}
+
+func TestNativeSliceFlags(t *testing.T) {
+	var names []string
+	var ports []int
+	var sizes []uint64
+	obj := &NativeLists{Run: func(_ *clive.Command, ctx *cli.Context) error {
+		names, ports, sizes = ctx.StringSlice("names"), ctx.IntSlice("ports"), ctx.Uint64Slice("sizes")
+		return nil
+	}}
+	got, err := run(obj, "--ports", "1,2", "--sizes", "3")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+	assert.Equal(t, []int{1, 2}, ports)
+	assert.Equal(t, []uint64{3}, sizes)
+	assert.Equal(t, names, got.Names)
+	assert.Equal(t, ports, got.Ports)
+	assert.Equal(t, sizes, got.Sizes)
+}
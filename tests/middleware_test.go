@@ -0,0 +1,104 @@
+package clive2_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+type MiddlewareAdmin struct {
+	*clive.Command `cli:"name:admin"`
+	Subcommands    struct {
+		*MiddlewareDrop
+	}
+	Token string
+}
+
+func (*MiddlewareAdmin) Action(*cli.Context) error { return nil }
+
+// only admins may run the admin subtree
+func (*MiddlewareAdmin) Middleware() []clive.Middleware {
+	return []clive.Middleware{func(next clive.ActionFunc) clive.ActionFunc {
+		return func(inv *clive.Invocation) error {
+			if inv.Step == clive.StepAction && inv.Path != "admin" {
+				admin := inv.Command.(clive.CommandLike).Parent(inv.Context).(*MiddlewareAdmin)
+				if admin.Token != "secret" {
+					return errors.New("not allowed")
+				}
+			}
+			return next(inv)
+		}
+	}}
+}
+
+type MiddlewareDrop struct {
+	*clive.Command `cli:"name:drop"`
+	Table          string `cli:"positional"`
+}
+
+func (d *MiddlewareDrop) Action(*cli.Context) error {
+	if d.Table == "users" {
+		return errors.New("table in use")
+	}
+	return nil
+}
+
+type MiddlewareApp struct {
+	*clive.Command
+	Subcommands struct {
+		*MiddlewareAdmin
+	}
+}
+
+func (*MiddlewareApp) Action(*cli.Context) error { return nil }
+
+func TestMiddleware(t *testing.T) {
+	var log []string
+	logging := func(next clive.ActionFunc) clive.ActionFunc {
+		return func(inv *clive.Invocation) error {
+			err := next(inv)
+			log = append(log, fmt.Sprintf("%s %q %T", inv.Step, inv.Path, inv.Command))
+			if err != nil {
+				return fmt.Errorf("%s: %w", inv.Path, err)
+			}
+			return nil
+		}
+	}
+	run := func(args ...string) error {
+		app := clive.BuildCustom(&MiddlewareApp{}, clive.BuildOptions{Middleware: []clive.Middleware{logging}})
+		app.Writer, app.ErrWriter = io.Discard, io.Discard
+		return app.Run(append([]string{"app"}, args...))
+	}
+
+	err := run("admin", "--token", "secret", "drop", "logs")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`before "" *clive2_test.MiddlewareApp`,
+		`before "admin" *clive2_test.MiddlewareAdmin`,
+		`before "admin drop" *clive2_test.MiddlewareDrop`,
+		`action "admin drop" *clive2_test.MiddlewareDrop`,
+		`after "admin drop" *clive2_test.MiddlewareDrop`,
+		`after "admin" *clive2_test.MiddlewareAdmin`,
+		`after "" *clive2_test.MiddlewareApp`,
+	}, log)
+
+	err = run("admin", "drop", "logs")
+	assert.EqualError(t, err, "admin drop: not allowed")
+	err = run("admin", "--token", "secret", "drop", "users")
+	assert.EqualError(t, err, "admin drop: table in use")
+	// binding errors go through the middleware too
+	log = nil
+	err = run("admin", "--token", "secret", "drop")
+	var tooFew *clive.TooFewArgumentsError
+	assert.ErrorAs(t, err, &tooFew)
+	assert.ErrorContains(t, err, "admin drop: ")
+	assert.Contains(t, log, `before "admin drop" *clive2_test.MiddlewareDrop`)
+	// the middleware of admin doesn't apply to its ancestors
+	err = run()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,164 @@
+package clive2_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/ASMfreaK/clive2/clivecobra"
+	"github.com/ASMfreaK/clive2/clivecore"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+type Negatable struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Color bool  `cli:"default:true"`
+	Keep  bool  `cli:"default:true,negatable:false"`
+	Plain bool  `cli:"env:'NEG_PLAIN'"`
+	Tri   *bool `cli:"negatable"`
+}
+
+func TestNegatable(t *testing.T) {
+	global := clive.BuildOptions{NegatableBools: true}
+
+	got, err := runCustom(&Negatable{}, global)
+	assert.NoError(t, err)
+	assert.True(t, got.Color)
+	assert.True(t, got.Keep)
+	assert.Nil(t, got.Tri)
+
+	got, err = runCustom(&Negatable{}, global, "--no-color", "--no-tri")
+	assert.NoError(t, err)
+	assert.False(t, got.Color)
+	if assert.NotNil(t, got.Tri) {
+		assert.False(t, *got.Tri)
+	}
+
+	got, err = runCustom(&Negatable{}, global, "--tri")
+	assert.NoError(t, err)
+	if assert.NotNil(t, got.Tri) {
+		assert.True(t, *got.Tri)
+	}
+
+	// without the option only fields tagged negatable get --no-
+	obj := &Negatable{Run: func(*clive.Command, *cli.Context) error { return nil }}
+	app := clive.Build(obj)
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	assert.Error(t, app.Run([]string{"neg", "--no-color"}))
+	assert.NoError(t, app.Run([]string{"neg", "--no-tri"}))
+	app = clive.BuildCustom(obj, global)
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	assert.Error(t, app.Run([]string{"neg", "--no-keep"}))
+
+	spec, err := clive.DescribeCustom(&Negatable{}, global)
+	assert.NoError(t, err)
+	var b bytes.Buffer
+	assert.NoError(t, clive.DefaultHelpRenderer.RenderHelp(&b, spec))
+	assert.Contains(t, b.String(), "--[no-]color")
+	assert.Contains(t, b.String(), "--[no-]tri")
+	assert.NotContains(t, b.String(), "--[no-]keep")
+
+	// bool flags are clive.BoolFlags, their negations cli.BoolFlags
+	app = clive.Build(obj)
+	for _, flag := range app.Flags {
+		switch flag.Names()[0] {
+		case "color", "plain", "tri":
+			if assert.IsType(t, &clive.BoolFlag{}, flag) {
+				assert.Equal(t, flag.Names()[0], flag.(*clive.BoolFlag).BoolFlag.Name)
+			}
+		case "no-tri":
+			assert.IsType(t, &cli.BoolFlag{}, flag)
+		}
+	}
+
+	assert.PanicsWithError(t, "negatable field Name is not a bool flag", func() {
+		clive.Build(&struct {
+			*clive.Command
+			Name string `cli:"negatable"`
+		}{})
+	})
+}
+
+func TestNegatableConflict(t *testing.T) {
+	global := clive.BuildOptions{NegatableBools: true}
+	obj := &Negatable{Run: func(*clive.Command, *cli.Context) error { return nil }}
+	app := clive.BuildCustom(obj, global)
+	app.Writer, app.ErrWriter = io.Discard, io.Discard
+	err := app.Run([]string{"neg", "--color", "--no-color"})
+	assert.EqualError(t, err, "flags --color and --no-color can't be given together")
+	assert.IsType(t, &clive.NegatedFlagError{}, err)
+	assert.Equal(t, 2, clive.ExitCode(app, err))
+
+	// a value from the environment is overridden by --no-<name>
+	t.Setenv("NEG_LOUD", "yes")
+	loud, err := run(&struct {
+		*clive.Command
+		Run  clive.RunFunc
+		Loud bool `cli:"negatable,env:'NEG_LOUD'"`
+	}{}, "--no-loud")
+	assert.NoError(t, err)
+	assert.False(t, loud.Loud)
+
+	_, err = runFlag(&Negatable{}, "-no-tri", "-tri")
+	assert.EqualError(t, err, "flags --tri and --no-tri can't be given together")
+
+	cobraCmd := clivecobra.Build(&NegatableCobra{})
+	cobraCmd.SetOut(io.Discard)
+	cobraCmd.SetErr(io.Discard)
+	cobraCmd.SetArgs([]string{"--tri", "--no-tri"})
+	assert.EqualError(t, cobraCmd.Execute(), "flags --tri and --no-tri can't be given together")
+}
+
+func TestBoolEnvSpellings(t *testing.T) {
+	for value, want := range map[string]bool{"yes": true, "ON": true, "y": true, "1": true, "no": false, "Off": false, "": false} {
+		t.Setenv("NEG_PLAIN", value)
+		got, err := run(&Negatable{})
+		assert.NoError(t, err)
+		assert.Equal(t, want, got.Plain, value)
+	}
+
+	t.Setenv("NEG_PLAIN", "maybe")
+	_, err := run(&Negatable{})
+	assert.EqualError(t, err,
+		`could not parse "maybe" as bool value from environment variable "NEG_PLAIN" for flag plain: strconv.ParseBool: parsing "maybe": invalid syntax`)
+}
+
+// NegatableBackend is Negatable for the backends running methods of the
+// command struct.
+type NegatableBackend struct {
+	*clive.Command
+
+	Color bool  `cli:"default:true"`
+	Plain bool  `cli:"env:'NEG_PLAIN'"`
+	Tri   *bool `cli:"negatable"`
+}
+
+type NegatableCobra NegatableBackend
+
+func (*NegatableCobra) Action(*cobra.Command, []string) error { return nil }
+
+func TestNegatableBackends(t *testing.T) {
+	t.Setenv("NEG_PLAIN", "on")
+	global := clivecore.Options{NegatableBools: true}
+
+	got, err := runFlagCustom(&Negatable{}, global, "-no-color", "-no-tri")
+	assert.NoError(t, err)
+	assert.False(t, got.Color)
+	assert.True(t, got.Plain)
+	if assert.NotNil(t, got.Tri) {
+		assert.False(t, *got.Tri)
+	}
+
+	cobraObj := &NegatableCobra{}
+	cobraCmd := clivecobra.BuildCustom(cobraObj, global)
+	cobraCmd.SetArgs([]string{"--no-color"})
+	assert.NoError(t, cobraCmd.Execute())
+	assert.False(t, cobraObj.Color)
+	assert.True(t, cobraObj.Plain)
+	assert.Nil(t, cobraObj.Tri)
+}
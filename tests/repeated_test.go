@@ -0,0 +1,183 @@
+package clive2_test
+
+import (
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+)
+
+type Upstream struct {
+	Host   string `cli:"usage:address of the upstream"`
+	Port   int    `cli:"default:80"`
+	Weight int    `cli:"required"`
+	Backup bool
+}
+
+type Balancer struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Upstreams []Upstream `cli:"inline,name:upstream,required"`
+	Mirrors   []*struct {
+		URL string
+	} `cli:"inline"`
+}
+
+func TestRepeated(t *testing.T) {
+	got, err := run(&Balancer{},
+		"--upstream-host", "a", "--upstream-weight", "2", "--upstream-backup",
+		"--mirrors-url", "http://m",
+		"--upstream-host", "b", "--upstream-port", "8080", "--upstream-weight", "1",
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []Upstream{
+		{Host: "a", Port: 80, Weight: 2, Backup: true},
+		{Host: "b", Port: 8080, Weight: 1},
+	}, got.Upstreams)
+	assert.Len(t, got.Mirrors, 1)
+	assert.Equal(t, "http://m", got.Mirrors[0].URL)
+
+	_, err = run(&Balancer{}, "--upstream-host", "a", "--upstream-weight", "1", "--upstream-host", "b")
+	assert.EqualError(t, err, `element 1 of --upstream-host: Required flag "upstream-weight" not set`)
+	_, err = run(&Balancer{}, "--upstream-weight", "1", "--upstream-host", "a")
+	assert.EqualError(t, err, `flag --upstream-weight must follow --upstream-host`)
+	_, err = run(&Balancer{})
+	assert.EqualError(t, err, `Required flag "upstream-host" not set`)
+	_, err = run(&Balancer{}, "--upstream-host", "a", "--upstream-weight", "heavy")
+	assert.EqualError(t, err, `element 0 of --upstream-host: failed to set field Weight: strconv.ParseInt: parsing "heavy": invalid syntax`)
+
+	// indexed environment variables are read when no flags are given
+	t.Setenv("UPSTREAM_0_HOST", "a")
+	t.Setenv("UPSTREAM_0_WEIGHT", "3")
+	t.Setenv("UPSTREAM_1_HOST", "b")
+	t.Setenv("UPSTREAM_1_WEIGHT", "4")
+	got, err = run(&Balancer{})
+	assert.NoError(t, err)
+	assert.Equal(t, []Upstream{{Host: "a", Port: 80, Weight: 3}, {Host: "b", Port: 80, Weight: 4}}, got.Upstreams)
+	assert.Nil(t, got.Mirrors)
+
+	// variables past an index none is set for are not ignored
+	t.Setenv("UPSTREAM_3_HOST", "skipped")
+	_, err = run(&Balancer{})
+	assert.EqualError(t, err, "environment variable UPSTREAM_3_HOST is set, but no UPSTREAM_2_* variable is: the elements of --upstream-host are numbered from 0 without gaps")
+	got, err = run(&Balancer{}, "--upstream-host", "c", "--upstream-weight", "5")
+	assert.NoError(t, err)
+	assert.Equal(t, []Upstream{{Host: "c", Port: 80, Weight: 5}}, got.Upstreams)
+}
+
+func TestRepeatedDescribe(t *testing.T) {
+	spec, err := clive.Describe(&Balancer{})
+	assert.NoError(t, err)
+	var names []string
+	for _, flag := range spec.Flags {
+		names = append(names, flag.Name)
+	}
+	assert.Equal(t, []string{"upstream-host", "upstream-port", "upstream-weight", "upstream-backup", "mirrors-url"}, names)
+	assert.Equal(t, "upstream-host", spec.Flags[2].Repeated)
+	assert.Equal(t, "UPSTREAM_<N>_WEIGHT", spec.Flags[2].IndexedEnv)
+	assert.Empty(t, spec.Flags[2].Envs)
+	assert.Equal(t, []string{"Upstreams", "Weight"}, spec.Flags[2].FieldPath)
+	assert.Equal(t, "MIRRORS_<N>_URL", spec.Flags[4].IndexedEnv)
+
+	assert.PanicsWithError(t, `repeated field Items can't have positional arguments`, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Items []struct {
+				Name string `cli:"positional"`
+			} `cli:"inline"`
+		}{})
+	})
+	assert.PanicsWithError(t, `inline field Items is not a struct or a slice of structs`, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Items []string `cli:"inline"`
+		}{})
+	})
+	assert.PanicsWithError(t, `parsing repeated field Outer: repeated field Inner can't be in a variant or another repeated group`, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Outer []struct {
+				Name  string
+				Inner []struct{ Name string } `cli:"inline"`
+			} `cli:"inline"`
+		}{})
+	})
+
+	schemas, err := clive.JSONSchema(&Balancer{})
+	assert.NoError(t, err)
+	schema := schemas[""]
+	assert.Equal(t, "array", schema.Properties["upstream"].Type)
+	assert.Equal(t, []string{"weight"}, schema.Properties["upstream"].Items.Required)
+	assert.Equal(t, []string{"upstream"}, schema.Required)
+
+	obj := &Balancer{}
+	err = clive.BindJSON(obj, "", []byte(`{"upstream": [{"host": "a", "weight": 2}, {"host": "b", "weight": 1, "port": 81}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Upstream{{Host: "a", Port: 80, Weight: 2}, {Host: "b", Port: 81, Weight: 1}}, obj.Upstreams)
+	err = clive.BindJSON(&Balancer{}, "", []byte(`{"upstream": [{"host": "a"}]}`))
+	assert.EqualError(t, err, `failed to set field Upstreams from JSON property upstream: element 0: property weight is required`)
+	err = clive.BindJSON(&Balancer{}, "", []byte(`{"upstream": [{"host": "a", "wieght": 1}]}`))
+	assert.EqualError(t, err, `unknown JSON property upstream[0].wieght`)
+}
+
+func TestRepeatedBind(t *testing.T) {
+	got, err := runFlag(&Balancer{},
+		"-upstream-host", "a", "-upstream-backup", "-upstream-weight", "2",
+		"-upstream-host", "b", "-upstream-weight", "1",
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []Upstream{{Host: "a", Port: 80, Weight: 2, Backup: true}, {Host: "b", Port: 80, Weight: 1}}, got.Upstreams)
+}
+
+type Buckets struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Bucket   string `cli:"name:s3-bucket"`
+	Replicas []struct {
+		Region string `cli:"name:s3-region"`
+	} `cli:"inline,name:replica"`
+}
+
+func TestEnvNames(t *testing.T) {
+	// digits are split from letters, like strcase.ToScreamingSnake does
+	t.Setenv("S_3_BUCKET", "logs")
+	t.Setenv("REPLICA_0_S_3_REGION", "eu-west-1")
+	got, err := run(&Buckets{})
+	assert.NoError(t, err)
+	assert.Equal(t, "logs", got.Bucket)
+	if assert.Len(t, got.Replicas, 1) {
+		assert.Equal(t, "eu-west-1", got.Replicas[0].Region)
+	}
+
+	spec, err := clive.Describe(&Buckets{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"S_3_BUCKET"}, spec.Flags[0].Envs)
+
+	// indexed and variant environment variables with a prefix are known
+	strict := clive.BuildOptions{EnvPrefix: "APP", StrictEnv: true}
+	t.Run("indexed", func(t *testing.T) {
+		t.Setenv("APP_REPLICA_0_S_3_REGION", "eu-west-1")
+		t.Setenv("APP_REPLICA_1_S_3_REGION", "us-east-1")
+		got, err := runCustom(&Buckets{}, strict)
+		assert.NoError(t, err)
+		assert.Len(t, got.Replicas, 2)
+		t.Setenv("APP_REPLICA_X_S_3_REGION", "eu-west-1")
+		_, err = runCustom(&Buckets{}, strict)
+		assert.EqualError(t, err, "unknown environment variables: APP_REPLICA_X_S_3_REGION, did you mean APP_REPLICA_0_S_3_REGION?")
+	})
+	t.Run("leading zeros", func(t *testing.T) {
+		t.Setenv("APP_REPLICA_0_S_3_REGION", "eu-west-1")
+		t.Setenv("APP_REPLICA_01_S_3_REGION", "us-east-1")
+		_, err := runCustom(&Buckets{}, strict)
+		assert.EqualError(t, err, "unknown environment variables: APP_REPLICA_01_S_3_REGION, did you mean APP_REPLICA_0_S_3_REGION?")
+	})
+	t.Run("variant", func(t *testing.T) {
+		t.Setenv("APP_STORAGE", "s3")
+		t.Setenv("APP_S_3_BUCKET", "logs")
+		got, err := runCustom(&Backup{}, strict)
+		assert.NoError(t, err)
+		assert.Equal(t, &S3Storage{Bucket: "logs", Region: "us-east-1"}, got.Storage)
+	})
+}
@@ -0,0 +1,78 @@
+package clive2_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+type SuggestServe struct {
+	*clive.Command `cli:"name:serve,alias:run"`
+	Port           int
+}
+
+func (*SuggestServe) Action(*cli.Context) error { return nil }
+
+type SuggestApp struct {
+	*clive.Command
+	Subcommands struct {
+		*SuggestServe
+	}
+
+	PostgresDsn string
+	Color       ColorT
+}
+
+func runSuggestApp(args ...string) error {
+	return runSuggestAppCustom(clive.BuildOptions{EnvPrefix: "SUGGEST", StrictEnv: true}, io.Discard, args...)
+}
+
+func runSuggestAppCustom(o clive.BuildOptions, errWriter io.Writer, args ...string) error {
+	app := clive.BuildCustom(&SuggestApp{}, o)
+	app.Writer, app.ErrWriter = io.Discard, errWriter
+	return app.Run(append([]string{"app"}, args...))
+}
+
+func TestSuggest(t *testing.T) {
+	assert.Equal(t, []string{"Green"}, clive.Suggest("gren", colorStrings))
+	assert.Equal(t, []string{"postgres-dsn"}, clive.Suggest("postgres-dns", []string{"postgres-dsn", "color"}))
+	assert.Equal(t, []string{"serve", "servo"}, clive.Suggest("serv", []string{"serve", "servo", "start"}))
+	assert.Nil(t, clive.Suggest("x", []string{"v", "help"}))
+	assert.Nil(t, clive.Suggest("Red", colorStrings))
+
+	err := runSuggestApp("--postgres-dns", "db")
+	assert.EqualError(t, err, "flag provided but not defined: -postgres-dns, did you mean --postgres-dsn?")
+	var unknownFlag *clive.UnknownFlagError
+	if assert.ErrorAs(t, err, &unknownFlag) {
+		assert.Equal(t, "postgres-dns", unknownFlag.Name)
+	}
+	err = runSuggestApp("serve", "--prot", "1")
+	assert.EqualError(t, err, "flag provided but not defined: -prot, did you mean --port?")
+	err = runSuggestApp("--nope")
+	assert.EqualError(t, err, "flag provided but not defined: -nope")
+
+	err = runSuggestApp("sevre")
+	assert.EqualError(t, err, "unknown command sevre, did you mean serve?")
+	assert.Equal(t, clive.UsageErrors, clive.ClassOf(err))
+	err = runSuggestApp("rn")
+	assert.EqualError(t, err, "unknown command rn, did you mean run?")
+
+	err = runSuggestApp("--color", "Gren")
+	assert.EqualError(t, err, "failed to set field Color (type clive2_test.ColorT) from flag color: invalid value \"Gren\", expected one of [Red, Green, Blue], did you mean Green?")
+
+	t.Setenv("SUGGEST_PORTT", "80")
+	t.Setenv("SUGGEST_OTHER", "1")
+	err = runSuggestApp("serve")
+	assert.EqualError(t, err, "unknown environment variables: SUGGEST_OTHER; SUGGEST_PORTT, did you mean SUGGEST_PORT?")
+	assert.Equal(t, clive.UsageErrors, clive.ClassOf(err))
+
+	// without StrictEnv they are only warned about
+	var warnings strings.Builder
+	err = runSuggestAppCustom(clive.BuildOptions{EnvPrefix: "SUGGEST"}, &warnings, "serve")
+	assert.NoError(t, err)
+	assert.Equal(t, "warning: unknown environment variables: SUGGEST_OTHER; SUGGEST_PORTT, did you mean SUGGEST_PORT?\n", warnings.String())
+}
@@ -0,0 +1,88 @@
+package a
+
+import (
+	"net"
+	"os"
+	"time"
+
+	clive "github.com/ASMfreaK/clive2"
+)
+
+type Good struct {
+	*clive.Command `cli:"usage:'a good command'"`
+	Run            clive.RunFunc
+
+	Subcommands struct {
+		*Sub
+		Group struct {
+			*Sub2
+		}
+	}
+
+	Port     int           `cli:"default:8080"`
+	Timeout  time.Duration `cli:"default:1m"`
+	Verbose  clive.Counter `cli:"default:1"`
+	Names    []string      `cli:"default:'a,b'"`
+	Skipped  chan int      `cli:"-"`
+	Position string        `cli:"positional"`
+}
+
+type Sub struct {
+	*clive.Command
+}
+
+type Sub2 struct {
+	*clive.Command
+}
+
+type NotACommand struct {
+	Port int
+}
+
+type Options struct {
+	Port uint8 `cli:"default:300"` // want `bad default value "300" for field Port of type uint8: strconv.ParseUint: parsing "300": value out of range`
+}
+
+type Mirror struct {
+	URL  string
+	Path string `cli:"positional"`
+}
+
+type Bad struct {
+	*clive.Command `cli:"nope:1"` // want `bad cli tag on the embedded clive.Command: unknown command tag: 'nope:1'`
+
+	Subcommands struct {
+		Sub                   // want `subcommand Sub \(type Sub\) is passed by value, not by reference`
+		Other    *NotACommand // want `the first field of subcommand Other must be an embedded \*clive.Command`
+		Indirect **Sub        // want `subcommand Indirect has type \*\*Sub, should be a pointer to struct`
+	}
+
+	Port     int            `cli:"default:http"`    // want `bad default value "http" for field Port of type int: strconv.ParseInt: parsing "http": invalid syntax`
+	Timeout  time.Duration  `cli:"default:soon"`    // want `bad default value "soon" for field Timeout of type time.Duration: time: invalid duration "soon"`
+	Ratios   []float64      `cli:"default:'1.5,x'"` // want `bad default value "1.5,x" for field Ratios of type \[\]float64: strconv.ParseFloat: parsing "x": invalid syntax`
+	Flag     bool           `cli:"hidden:maybe"`    // want `bad cli tag on field Flag: failed to parse 'hidden' as a bool strconv.ParseBool: parsing "maybe": invalid syntax`
+	Strange  string         `cli:"usage"`           // want `bad cli tag on field Strange: malformed tag: 'usage'`
+	Quiet    bool           `cli:"negatable,default:yes"`
+	Name     string         `cli:"negatable"` // want `negatable field Name is not a bool flag`
+	Listen   net.IP         `cli:"default:127.0.0.1"`
+	Gateway  net.IP         `cli:"default:router"` // want `bad default value "router" for field Gateway of type net.IP: invalid IP address "router"`
+	Since    time.Time      `cli:"layout:2006-01-02,default:2024-02-29"`
+	Cache    clive.ByteSize `cli:"default:1.5GB"`
+	Spill    clive.ByteSize `cli:"default:10,unit:MiB"`
+	Limit    clive.ByteSize `cli:"default:lots"` // want `bad default value "lots" for field Limit of type clive.ByteSize: invalid byte size "lots"`
+	Share    clive.Percent  `cli:"default:50%"`
+	Keep     clive.Duration `cli:"default:2w"`
+	Expire   clive.Duration `cli:"default:30"` // want `bad default value "30" for field Expire of type clive.Duration: missing unit in duration "30"`
+	Mode     os.FileMode    `cli:"default:rw"` // want `bad default value "rw" for field Mode of type os.FileMode: invalid file mode "rw", expected octal permissions like 0644`
+	Opts     Options        `cli:"inline"`
+	NotGroup int            `cli:"inline"` // want `inline field NotGroup is not a struct or a slice of structs`
+	Mirrors  []Mirror       `cli:"inline"` // want `repeated field Mirrors can't have positional arguments`
+	Files    []string       `cli:"positional"`
+	Last     string         `cli:"positional,hidden:true"` // want `positional argument Last cannot be Hidden` `cant add positional argument Last after variadic \(slice of x\) argument Files`
+}
+
+func main() {
+	clive.Build(&Good{})
+	clive.Build(Good{})         // want `command struct Good is passed by value, pass by reference`
+	clive.Build(&NotACommand{}) // want `the first field of command struct NotACommand must be an embedded \*clive.Command`
+}
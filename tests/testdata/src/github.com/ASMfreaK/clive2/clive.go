@@ -0,0 +1,20 @@
+// Package clive is a stub of the clive API used by clivevet tests.
+package clive
+
+type Command struct{}
+
+func (c *Command) Action() error { return nil }
+
+type RunFunc func(*Command) error
+
+type Counter struct {
+	Value int
+}
+
+func Build(obj interface{}) {}
+
+type ByteSize uint64
+
+type Percent float64
+
+type Duration int64
@@ -0,0 +1,304 @@
+package clive2_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	Port    uint16
+	Mode    string
+	Level   int8
+	Names   []string
+	Enabled bool
+)
+
+type Widths struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Int8    int8   `cli:"default:-128"`
+	Int16   int16  `cli:"default:32767"`
+	Int32   int32  `cli:"default:-2147483648"`
+	Uint8   uint8  `cli:"default:255"`
+	Uint16  uint16 `cli:"default:65535"`
+	Uint32  uint32 `cli:"default:4294967295"`
+	Ints8   []int8 `cli:"default:'1,-2'"`
+	Uints32 []uint32
+	Port    Port `cli:"default:8080"`
+	Ports   []Port
+	Mode    Mode `cli:"default:fast"`
+	Level   *Level
+	Names   Names   `cli:"default:'a,b'"`
+	Enabled Enabled `cli:"negatable,default:true"`
+}
+
+func TestIntegerWidths(t *testing.T) {
+	got, err := run(&Widths{})
+	assert.NoError(t, err)
+	assert.Equal(t, int8(-128), got.Int8)
+	assert.Equal(t, int16(32767), got.Int16)
+	assert.Equal(t, int32(-2147483648), got.Int32)
+	assert.Equal(t, uint8(255), got.Uint8)
+	assert.Equal(t, uint16(65535), got.Uint16)
+	assert.Equal(t, uint32(4294967295), got.Uint32)
+	assert.Equal(t, []int8{1, -2}, got.Ints8)
+
+	got, err = run(&Widths{}, "--int-8", "7", "--uint-16", "0x10", "--uints-32", "1", "--uints-32", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, int8(7), got.Int8)
+	assert.Equal(t, uint16(16), got.Uint16)
+	assert.Equal(t, []uint32{1, 2}, got.Uints32)
+
+	_, err = run(&Widths{}, "--int-8", "128")
+	assert.EqualError(t, err, `failed to set field Int8 (type int8) from flag int-8: strconv.ParseInt: parsing "128": value out of range`)
+	_, err = run(&Widths{}, "--uint-8", "256")
+	assert.EqualError(t, err, `failed to set field Uint8 (type uint8) from flag uint-8: strconv.ParseUint: parsing "256": value out of range`)
+	_, err = run(&Widths{}, "--ints-8", "1", "--ints-8", "-129")
+	assert.EqualError(t, err, `failed to set field Ints8 (type []int8) from flag ints-8: strconv.ParseInt: parsing "-129": value out of range`)
+
+	assert.Panics(t, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Small uint8 `cli:"default:300"`
+		}{})
+	})
+}
+
+func TestNamedTypes(t *testing.T) {
+	got, err := run(&Widths{})
+	assert.NoError(t, err)
+	assert.Equal(t, Port(8080), got.Port)
+	assert.Equal(t, Mode("fast"), got.Mode)
+	assert.Nil(t, got.Level)
+	assert.Equal(t, Names{"a", "b"}, got.Names)
+	assert.Equal(t, Enabled(true), got.Enabled)
+
+	got, err = run(&Widths{}, "--port", "80", "--ports", "1,2", "--mode", "slow", "--level", "-3", "--names", "c", "--no-enabled")
+	assert.NoError(t, err)
+	assert.Equal(t, Port(80), got.Port)
+	assert.Equal(t, []Port{1, 2}, got.Ports)
+	assert.Equal(t, Mode("slow"), got.Mode)
+	if assert.NotNil(t, got.Level) {
+		assert.Equal(t, Level(-3), *got.Level)
+	}
+	assert.Equal(t, Names{"c"}, got.Names)
+	assert.Equal(t, Enabled(false), got.Enabled)
+
+	_, err = run(&Widths{}, "--port", "70000")
+	assert.EqualError(t, err, `failed to set field Port (type clive2_test.Port) from flag port: strconv.ParseUint: parsing "70000": value out of range`)
+
+	spec, err := clive.Describe(&Widths{})
+	assert.NoError(t, err)
+	assert.Equal(t, "clive2_test.Port", spec.Flags[8].Type)
+
+	// the same through Bind
+	got, err = runFlag(&Widths{}, "-port", "81", "-ports", "3", "-ports", "4", "-level", "5", "-no-enabled")
+	assert.NoError(t, err)
+	assert.Equal(t, Port(81), got.Port)
+	assert.Equal(t, []Port{3, 4}, got.Ports)
+	assert.Equal(t, Level(5), *got.Level)
+	assert.Equal(t, Enabled(false), got.Enabled)
+	_, err = runFlag(&Widths{}, "-int-16", "40000")
+	assert.EqualError(t, err,
+		`failed to set field Int16 (type int16) from flag int-16: strconv.ParseInt: parsing "40000": value out of range`)
+}
+
+type Stdlib struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	IP       net.IP         `cli:"default:127.0.0.1"`
+	Network  net.IPNet      `cli:"default:10.0.0.0/8"`
+	Addr     netip.Addr     `cli:"default:'::1'"`
+	AddrPort netip.AddrPort `cli:"default:'127.0.0.1:80'"`
+	Prefix   netip.Prefix   `cli:"default:192.168.0.0/16"`
+	Endpoint *url.URL       `cli:"default:'https://example.com/api'"`
+	Mirrors  []*url.URL
+	Match    *regexp.Regexp `cli:"default:'^a+$'"`
+	Since    time.Time      `cli:"layout:2006-01-02,default:2024-02-29"`
+	At       time.Time
+	Zone     *time.Location `cli:"default:UTC"`
+	Mode     os.FileMode    `cli:"default:0644"`
+	Big      *big.Int       `cli:"default:0x10000000000000000"`
+	Ratio    *big.Float
+	Peers    []netip.AddrPort
+	Modes    []os.FileMode `cli:"default:'0600,0755'"`
+}
+
+func TestStdlibTypes(t *testing.T) {
+	got, err := run(&Stdlib{})
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", got.IP.String())
+	assert.Equal(t, "10.0.0.0/8", got.Network.String())
+	assert.Equal(t, netip.MustParseAddr("::1"), got.Addr)
+	assert.Equal(t, netip.MustParseAddrPort("127.0.0.1:80"), got.AddrPort)
+	assert.Equal(t, netip.MustParsePrefix("192.168.0.0/16"), got.Prefix)
+	assert.Equal(t, "https://example.com/api", got.Endpoint.String())
+	assert.True(t, got.Match.MatchString("aaa"))
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), got.Since)
+	assert.True(t, got.At.IsZero())
+	assert.Equal(t, "UTC", got.Zone.String())
+	assert.Equal(t, os.FileMode(0o644), got.Mode)
+	assert.Equal(t, "18446744073709551616", got.Big.String())
+	assert.Nil(t, got.Ratio)
+	assert.Equal(t, []os.FileMode{0o600, 0o755}, got.Modes)
+
+	got, err = run(&Stdlib{}, "--ip", "::2", "--mirrors", "http://a", "--mirrors", "http://b", "--at", "2024-01-02T03:04:05Z",
+		"--zone", "Europe/Berlin", "--mode", "0o700", "--ratio", "1.5", "--peers", "[::1]:1,10.0.0.1:2")
+	assert.NoError(t, err)
+	assert.Equal(t, net.ParseIP("::2"), got.IP)
+	if assert.Len(t, got.Mirrors, 2) {
+		assert.Equal(t, "b", got.Mirrors[1].Host)
+	}
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), got.At)
+	assert.Equal(t, "Europe/Berlin", got.Zone.String())
+	assert.Equal(t, os.FileMode(0o700), got.Mode)
+	assert.Equal(t, "1.5", got.Ratio.String())
+	assert.Equal(t, []netip.AddrPort{netip.MustParseAddrPort("[::1]:1"), netip.MustParseAddrPort("10.0.0.1:2")}, got.Peers)
+
+	got, err = run(&Stdlib{}, "--zone", "Local")
+	assert.NoError(t, err)
+	assert.Same(t, time.Local, got.Zone)
+
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--ip", "nope"}, `failed to set field IP (type net.IP) from flag ip: invalid IP address "nope"`},
+		{[]string{"--network", "10.0.0.0"}, `failed to set field Network (type net.IPNet) from flag network: invalid CIDR address: 10.0.0.0`},
+		{[]string{"--match", "("}, "failed to set field Match (type *regexp.Regexp) from flag match: error parsing regexp: missing closing ): `(`"},
+		{[]string{"--since", "29.02.2024"}, `failed to set field Since (type time.Time) from flag since: parsing time "29.02.2024" as "2006-01-02": cannot parse "29.02.2024" as "2006"`},
+		{[]string{"--zone", "Mars/Base"}, `failed to set field Zone (type *time.Location) from flag zone: unknown time zone Mars/Base`},
+		{[]string{"--mode", "rw"}, `failed to set field Mode (type fs.FileMode) from flag mode: invalid file mode "rw", expected octal permissions like 0644`},
+		{[]string{"--big", "1e3"}, `failed to set field Big (type *big.Int) from flag big: invalid integer "1e3"`},
+		{[]string{"--ratio", "x"}, `failed to set field Ratio (type *big.Float) from flag ratio: invalid number "x"`},
+	} {
+		_, err = run(&Stdlib{}, tt.args...)
+		assert.EqualError(t, err, tt.err)
+	}
+
+	assert.PanicsWithError(t, `layout is not supported for field Since of type string`, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Since string `cli:"layout:2006-01-02"`
+		}{})
+	})
+	assert.Panics(t, func() {
+		clive.Build(&struct {
+			*clive.Command
+			IP net.IP `cli:"default:localhost"`
+		}{})
+	})
+
+	spec, err := clive.Describe(&Stdlib{})
+	assert.NoError(t, err)
+	var b bytes.Buffer
+	assert.NoError(t, clive.DefaultHelpRenderer.RenderHelp(&b, spec))
+	assert.Contains(t, b.String(), `--mode value`)
+	assert.Contains(t, b.String(), `(default: "0644")`)
+	assert.Contains(t, b.String(), `(default: "2024-02-29")`)
+
+	schemas, err := clive.JSONSchema(&Stdlib{})
+	assert.NoError(t, err)
+	schema := schemas[""]
+	assert.Equal(t, "string", schema.Properties["ip"].Type)
+	assert.Equal(t, "string", schema.Properties["mode"].Type)
+	assert.Equal(t, "string", schema.Properties["mirrors"].Items.Type)
+}
+
+type Units struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Cache     clive.ByteSize   `cli:"default:1.5GB"`
+	Spill     clive.ByteSize   `cli:"unit:MiB,default:10"`
+	Buffers   []clive.ByteSize `cli:"default:'4KiB,1M'"`
+	Share     clive.Percent    `cli:"default:50%"`
+	Retention clive.Duration   `cli:"default:2w"`
+	Grace     *clive.Duration  `cli:"unit:d"`
+	Backoff   []clive.Duration `cli:"unit:s"`
+}
+
+func TestUnitTypes(t *testing.T) {
+	got, err := run(&Units{})
+	assert.NoError(t, err)
+	assert.Equal(t, clive.ByteSize(1500000000), got.Cache)
+	assert.Equal(t, clive.ByteSize(10<<20), got.Spill)
+	assert.Equal(t, []clive.ByteSize{4 << 10, 1000000}, got.Buffers)
+	assert.Equal(t, clive.Percent(0.5), got.Share)
+	assert.Equal(t, clive.Duration(14*24*time.Hour), got.Retention)
+	assert.Nil(t, got.Grace)
+
+	got, err = run(&Units{}, "--cache", "10MiB", "--spill", "1GiB", "--share", "7", "--retention", "1w2d3h",
+		"--grace", "1.5", "--backoff", "1,2.5,1m")
+	assert.NoError(t, err)
+	assert.Equal(t, clive.ByteSize(10<<20), got.Cache)
+	assert.Equal(t, clive.ByteSize(1<<30), got.Spill)
+	assert.Equal(t, clive.Percent(0.07), got.Share)
+	assert.Equal(t, clive.Duration(9*24*time.Hour+3*time.Hour), got.Retention)
+	if assert.NotNil(t, got.Grace) {
+		assert.Equal(t, clive.Duration(36*time.Hour), *got.Grace)
+	}
+	assert.Equal(t, []clive.Duration{clive.Duration(time.Second), clive.Duration(2500 * time.Millisecond), clive.Duration(time.Minute)}, got.Backoff)
+
+	for _, tt := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--cache", "10 parsecs"}, `failed to set field Cache (type clive.ByteSize) from flag cache: invalid byte size "10 parsecs"`},
+		{[]string{"--cache", "20EiB"}, `failed to set field Cache (type clive.ByteSize) from flag cache: byte size "20EiB" out of range`},
+		{[]string{"--share", "half"}, `failed to set field Share (type clive.Percent) from flag share: invalid percentage "half"`},
+		{[]string{"--retention", "30"}, `failed to set field Retention (type clive.Duration) from flag retention: missing unit in duration "30"`},
+		{[]string{"--retention", "3 fortnights"}, `failed to set field Retention (type clive.Duration) from flag retention: invalid duration "3 fortnights"`},
+		{[]string{"--retention", "20000w"}, `failed to set field Retention (type clive.Duration) from flag retention: duration "20000w" out of range`},
+		{[]string{"--retention", "2000000h2000000h"}, `failed to set field Retention (type clive.Duration) from flag retention: duration "2000000h2000000h" out of range`},
+	} {
+		_, err = run(&Units{}, tt.args...)
+		assert.EqualError(t, err, tt.err)
+	}
+
+	assert.PanicsWithError(t, `bad unit "parsecs" for field Size of type clive.ByteSize: unknown unit "parsecs"`, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Size clive.ByteSize `cli:"unit:parsecs"`
+		}{})
+	})
+	assert.PanicsWithError(t, `bad unit "s" for field Count of type int: type takes no unit`, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Count int `cli:"unit:s"`
+		}{})
+	})
+}
+
+func TestUnitStrings(t *testing.T) {
+	for value, s := range map[fmt.Stringer]string{
+		clive.ByteSize(0):                          "0B",
+		clive.ByteSize(1000):                       "1KB",
+		clive.ByteSize(1536):                       "1.5KiB",
+		clive.ByteSize(10 << 20):                   "10MiB",
+		clive.ByteSize(1500000000):                 "1.5GB",
+		clive.ByteSize(1001):                       "1.001KB",
+		clive.ByteSize(1234567891):                 "1234567891B",
+		clive.Percent(0.07):                        "7%",
+		clive.Percent(0.125):                       "12.5%",
+		clive.Duration(90 * time.Minute):           "1h30m0s",
+		clive.Duration(14 * 24 * time.Hour):        "2w",
+		clive.Duration(-3 * 24 * time.Hour):        "-3d",
+		clive.Duration(36*time.Hour + time.Second): "1d12h0m1s",
+	} {
+		assert.Equal(t, s, value.String())
+	}
+}
@@ -0,0 +1,154 @@
+package clive2_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	clive "github.com/ASMfreaK/clive2"
+	clivev3 "github.com/ASMfreaK/clive2/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v3"
+)
+
+type V3Add struct {
+	*clive.Command `cli:"name:'add',usage:'add numbers'"`
+
+	Verbose clive.Counter `cli:"alias:v"`
+	Base    int           `cli:"default:10"`
+	Numbers []int         `cli:"positional"`
+
+	result int
+}
+
+func (add *V3Add) Action(_ context.Context, cmd *cli.Command) error {
+	root := clivev3.Root(cmd).(*V3App)
+	add.result = add.Base
+	for _, n := range add.Numbers {
+		add.result += n
+	}
+	root.ran = append(root.ran, "add")
+	return nil
+}
+
+type V3App struct {
+	*clive.Command `cli:"name:'calc',usage:'calculator'"`
+
+	Subcommands struct {
+		*V3Add
+	}
+
+	Debug  bool
+	Color  ColorT         `cli:"default:Blue"`
+	Input  ComposedOption `cli:"inline"`
+	Labels []string       `cli:"env:'CALC_LABELS'"`
+
+	ran []string
+}
+
+func (app *V3App) Before(ctx context.Context, _ *cli.Command) (context.Context, error) {
+	app.ran = append(app.ran, "before")
+	return ctx, nil
+}
+
+func TestV3(t *testing.T) {
+	t.Setenv("CALC_LABELS", "a,b")
+	app := &V3App{}
+	app.Subcommands.V3Add = &V3Add{}
+	cmd := clivev3.Build(app)
+	cmd.Writer, cmd.ErrWriter = io.Discard, io.Discard
+
+	err := cmd.Run(context.Background(), []string{"calc", "--debug", "--input-port", "80", "--input-role", "client",
+		"add", "-v", "-v", "--base", "1", "2", "3"})
+	assert.NoError(t, err)
+	assert.True(t, app.Debug)
+	assert.Equal(t, Blue, app.Color)
+	assert.Equal(t, ComposedOption{Role: Client, Port: 80}, app.Input)
+	assert.Equal(t, []string{"a", "b"}, app.Labels)
+	assert.Equal(t, []string{"before", "add"}, app.ran)
+	assert.Equal(t, 2, app.Subcommands.V3Add.Verbose.Value)
+	assert.Equal(t, []int{2, 3}, app.Subcommands.V3Add.Numbers)
+	assert.Equal(t, 6, app.Subcommands.V3Add.result)
+	assert.Same(t, app.Subcommands.V3Add, clivev3.Current(cmd.Command("add")))
+}
+
+func TestV3Errors(t *testing.T) {
+	app := &V3App{}
+	app.Subcommands.V3Add = &V3Add{}
+	cmd := clivev3.Build(app)
+	cmd.Writer, cmd.ErrWriter = io.Discard, io.Discard
+
+	err := cmd.Run(context.Background(), []string{"calc", "--input-port", "80", "--color", "Purple", "add", "1"})
+	assert.EqualError(t, err, "failed to set field Color (type clive2_test.ColorT) from flag color: invalid value \"Purple\", expected one of [Red, Green, Blue]")
+
+	err = cmd.Run(context.Background(), []string{"calc", "--input-port", "80", "add", "x"})
+	assert.EqualError(t, err, `failed to set field Numbers (type []int) from positional argument NUMBERS: strconv.ParseInt: parsing "x": invalid syntax`)
+
+	err = cmd.Run(context.Background(), []string{"calc", "--input-port", "80"})
+	assert.EqualError(t, err, clive.ErrCommandNotImplemented().Error())
+
+	assert.Panics(t, func() { clivev3.Build(&struct{}{}) })
+}
+
+type V3Global struct {
+	*clive.Command `cli:"name:'global'"`
+
+	Subcommands struct {
+		*V3Add
+	}
+
+	Config string `cli:"global"`
+}
+
+type V3Inject struct {
+	*clive.Command `cli:"name:'inject'"`
+
+	Config *InjectConfig `cli:"inject"`
+}
+
+type V3Hooks struct {
+	*clive.Command `cli:"name:'hooks'"`
+}
+
+func (*V3Hooks) Copyright() string { return "nobody" }
+
+func TestV3Unsupported(t *testing.T) {
+	global := &V3Global{}
+	global.Subcommands.V3Add = &V3Add{}
+	assert.PanicsWithError(t, (&clive.UnsupportedError{Command: "global", Feature: "global flag config"}).Error(), func() {
+		clivev3.Build(global)
+	})
+	assert.PanicsWithError(t, `command "inject" uses providers, which only clive.Build supports`, func() {
+		clivev3.BuildCustom(&V3Inject{}, clive.BuildOptions{Providers: []clive.Provider{
+			clive.Provide(func() *InjectConfig { return &InjectConfig{} }),
+		}})
+	})
+	assert.PanicsWithError(t, `command "inject" uses fields tagged inject, which only clive.Build supports`, func() {
+		clivev3.Build(&V3Inject{})
+	})
+	assert.PanicsWithError(t, `command "hooks" uses middleware, which only clive.Build supports`, func() {
+		clivev3.BuildCustom(&V3Hooks{}, clive.BuildOptions{Middleware: []clive.Middleware{
+			func(next clive.ActionFunc) clive.ActionFunc { return next },
+		}})
+	})
+	assert.PanicsWithError(t, `command "hooks" uses methods setting fields of the cli.App, which only clive.Build supports`, func() {
+		clivev3.Build(&V3Hooks{})
+	})
+}
+
+type NegatableV3 NegatableBackend
+
+func (*NegatableV3) Action(context.Context, *cli.Command) error { return nil }
+
+func TestV3Negatable(t *testing.T) {
+	t.Setenv("NEG_PLAIN", "")
+	obj := &NegatableV3{}
+	cmd := clivev3.BuildCustom(obj, clive.BuildOptions{NegatableBools: true})
+	cmd.Writer, cmd.ErrWriter = io.Discard, io.Discard
+	assert.NoError(t, cmd.Run(context.Background(), []string{"neg", "--no-color"}))
+	assert.False(t, obj.Color)
+	assert.False(t, obj.Plain)
+
+	err := cmd.Run(context.Background(), []string{"neg", "--color", "--no-color"})
+	assert.EqualError(t, err, "flags --color and --no-color can't be given together")
+}
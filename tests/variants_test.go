@@ -0,0 +1,176 @@
+package clive2_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/stretchr/testify/assert"
+)
+
+type Storage interface {
+	Location() string
+}
+
+type S3Storage struct {
+	Bucket string `cli:"required"`
+	Region string `cli:"default:us-east-1"`
+}
+
+func (s *S3Storage) Location() string { return fmt.Sprintf("s3://%s (%s)", s.Bucket, s.Region) }
+
+type LocalStorage struct {
+	Path string `cli:"default:/var/backups"`
+}
+
+func (s *LocalStorage) Location() string { return s.Path }
+
+type Broken interface{ Broken() }
+
+type BrokenStorage struct {
+	Bucket chan int
+}
+
+func (*BrokenStorage) Broken() {}
+
+type Nested interface{ Nested() }
+
+type NestedStorage struct {
+	Inner Nested
+}
+
+func (*NestedStorage) Nested() {}
+
+// StorageKind names the variants of Storage.
+type StorageKind string
+
+func (StorageKind) Variants() []string { return []string{"s3", "local"} }
+
+func init() {
+	clive.RegisterVariant[Storage](StorageKind("s3"), &S3Storage{})
+	clive.RegisterVariant[Storage]("local", &LocalStorage{})
+}
+
+type Backup struct {
+	*clive.Command
+	Run clive.RunFunc
+
+	Storage Storage `cli:"default:local,usage:where to keep backups"`
+}
+
+func TestVariants(t *testing.T) {
+	got, err := run(&Backup{})
+	assert.NoError(t, err)
+	assert.Equal(t, &LocalStorage{Path: "/var/backups"}, got.Storage)
+
+	got, err = run(&Backup{}, "--storage", "s3", "--s3-bucket", "logs")
+	assert.NoError(t, err)
+	assert.Equal(t, &S3Storage{Bucket: "logs", Region: "us-east-1"}, got.Storage)
+	assert.Equal(t, "s3://logs (us-east-1)", got.Storage.Location())
+
+	_, err = run(&Backup{}, "--storage", "s3")
+	assert.EqualError(t, err, `Required flag "s3-bucket" not set`)
+
+	_, err = run(&Backup{}, "--s3-bucket", "logs")
+	assert.EqualError(t, err, `flag --s3-bucket can only be used with --storage=s3`)
+	var unselected *clive.UnselectedVariantError
+	assert.ErrorAs(t, err, &unselected)
+
+	_, err = run(&Backup{}, "--storage", "ftp")
+	assert.EqualError(t, err, `failed to set field Storage (type clive2_test.Storage) from flag storage: unknown variant "ftp", expected one of: s3, local`)
+
+	// environment variables of other variants are ignored
+	t.Setenv("S_3_BUCKET", "logs")
+	got, err = run(&Backup{}, "--local-path", "/tmp")
+	assert.NoError(t, err)
+	assert.Equal(t, &LocalStorage{Path: "/tmp"}, got.Storage)
+	t.Setenv("STORAGE", "s3")
+	got, err = run(&Backup{})
+	assert.NoError(t, err)
+	assert.Equal(t, &S3Storage{Bucket: "logs", Region: "us-east-1"}, got.Storage)
+}
+
+func TestVariantsDescribe(t *testing.T) {
+	spec, err := clive.Describe(&Backup{})
+	assert.NoError(t, err)
+	var names []string
+	for _, flag := range spec.Flags {
+		names = append(names, flag.Name)
+	}
+	assert.Equal(t, []string{"storage", "s3-bucket", "s3-region", "local-path"}, names)
+	assert.Equal(t, []string{"s3", "local"}, spec.Flags[0].Variants)
+	assert.Equal(t, "storage", spec.Flags[1].Selector)
+	assert.Equal(t, "s3", spec.Flags[1].Variant)
+	assert.True(t, spec.Flags[1].Required)
+	assert.Equal(t, []string{"Storage", "Bucket"}, spec.Flags[1].FieldPath)
+
+	inline, err := clive.Describe(&struct {
+		*clive.Command
+		Target struct {
+			Storage Storage
+		} `cli:"inline"`
+	}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "target-s3-bucket", inline.Flags[1].Name)
+	assert.Equal(t, "target-storage", inline.Flags[1].Selector)
+
+	assert.PanicsWithError(t, `parsing variant s3 of field storage: cant find type for Bucket field: unsupported flag generator type: chan int`, func() {
+		clive.RegisterVariant[Broken]("s3", &BrokenStorage{})
+		clive.Build(&struct {
+			*clive.Command
+			Storage Broken
+		}{})
+	})
+	assert.PanicsWithError(t, `parsing variant nested of field storage: field inner of variant nested selects a variant of clive2_test.Nested again`, func() {
+		clive.RegisterVariant[Nested]("nested", &NestedStorage{})
+		clive.Build(&struct {
+			*clive.Command
+			Storage Nested
+		}{})
+	})
+	assert.PanicsWithError(t, `variant field Storage can't be positional`, func() {
+		clive.Build(&struct {
+			*clive.Command
+			Storage Storage `cli:"positional"`
+		}{})
+	})
+	assert.PanicsWithError(t, `variant s3 of clive2_test.Storage is already registered`, func() {
+		clive.RegisterVariant[Storage]("s3", &S3Storage{})
+	})
+	assert.PanicsWithError(t, `variant gcs of clive2_test.Storage is not one of the variants of clive2_test.StorageKind: s3, local`, func() {
+		clive.RegisterVariant[Storage](StorageKind("gcs"), &S3Storage{})
+	})
+	assert.PanicsWithError(t, `variant s of fmt.Stringer must be a pointer to a struct, got time.Duration`, func() {
+		clive.RegisterVariant[fmt.Stringer]("s", time.Second)
+	})
+
+	schemas, err := clive.JSONSchema(&Backup{})
+	assert.NoError(t, err)
+	schema := schemas[""]
+	assert.Equal(t, []string{"s3", "local"}, schema.Properties["storage"].Enum)
+	assert.Equal(t, []string{"bucket"}, schema.Properties["s3"].Required)
+	assert.Empty(t, schema.Required)
+
+	obj := &Backup{}
+	assert.NoError(t, clive.BindJSON(obj, "", []byte(`{"storage": "s3", "s3": {"bucket": "logs"}}`)))
+	assert.Equal(t, &S3Storage{Bucket: "logs", Region: "us-east-1"}, obj.Storage)
+	obj = &Backup{}
+	err = clive.BindJSON(obj, "", []byte(`{"s3": {"bucket": "logs"}}`))
+	assert.EqualError(t, err, `JSON property s3.bucket can only be used with storage=s3`)
+}
+
+func TestVariantsBind(t *testing.T) {
+	got, err := runFlag(&Backup{}, "-storage", "s3", "-s3-bucket", "logs", "-s3-region", "eu-west-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &S3Storage{Bucket: "logs", Region: "eu-west-1"}, got.Storage)
+
+	got, err = runFlag(&Backup{})
+	assert.NoError(t, err)
+	assert.Equal(t, &LocalStorage{Path: "/var/backups"}, got.Storage)
+
+	_, err = runFlag(&Backup{}, "-storage", "local", "-s3-region", "eu-west-1")
+	assert.EqualError(t, err, `flag --s3-region can only be used with --storage=s3`)
+	_, err = runFlag(&Backup{}, "-storage", "s3")
+	assert.EqualError(t, err, `Required flag "s3-bucket" not set`)
+}
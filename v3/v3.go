@@ -0,0 +1,276 @@
+// Package v3 builds urfave/cli v3 commands from clive command structs. The
+// structs, their tags and subcommands are the same ones clive.Build accepts,
+// only the methods taking a *cli.Context have v3 counterparts here.
+package v3
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+
+	clive "github.com/ASMfreaK/clive2"
+	"github.com/urfave/cli/v3"
+)
+
+type (
+	HasBefore interface {
+		Before(context.Context, *cli.Command) (context.Context, error)
+	}
+	Actionable interface {
+		Action(context.Context, *cli.Command) error
+	}
+	HasAfter interface {
+		After(context.Context, *cli.Command) error
+	}
+)
+
+const objectKey = "cliveObject"
+
+// Build constructs a urfave/cli v3 Command from an instance of a decorated
+// struct. Like clive.Build it panics on malformed structs.
+func Build(obj interface{}) *cli.Command {
+	return BuildCustom(obj, clive.DefaultBuildOptions)
+}
+
+func BuildCustom(obj interface{}, o clive.BuildOptions) *cli.Command {
+	cmd, err := build(obj, o)
+	if err != nil {
+		panic(err)
+	}
+	return cmd
+}
+
+// Current returns the command struct cmd was built from, with values bound.
+func Current(cmd *cli.Command) interface{} {
+	return cmd.Metadata[objectKey]
+}
+
+// Root returns the command struct of the root command.
+func Root(cmd *cli.Command) interface{} {
+	return Current(cmd.Root())
+}
+
+// Parent returns the command struct of the command cmd is a subcommand of,
+// or nil for the root command.
+func Parent(cmd *cli.Command) interface{} {
+	lineage := cmd.Lineage()
+	if len(lineage) < 2 {
+		return nil
+	}
+	return Current(lineage[1])
+}
+
+func build(obj interface{}, o clive.BuildOptions) (*cli.Command, error) {
+	o.IgnoreGenerated = true
+	spec, err := clive.DescribeCustom(obj, o)
+	if err != nil {
+		return nil, err
+	}
+	err = clive.CheckPortable(spec, o)
+	if err != nil {
+		return nil, err
+	}
+	return command(spec), nil
+}
+
+func command(spec *clive.CommandSpec) *cli.Command {
+	obj := spec.Object()
+	values := &flagValues{raw: clive.NewRawValues(spec), counters: map[string]*int{}, repeated: map[string]bool{}}
+	cmd := &cli.Command{
+		Name:        spec.Name,
+		Aliases:     spec.Aliases,
+		Usage:       spec.Usage,
+		Description: spec.Description,
+		Version:     spec.Version,
+		ArgsUsage:   spec.ArgsUsage(),
+		Metadata:    map[string]interface{}{objectKey: obj},
+	}
+	for _, field := range spec.Flags {
+		cmd.Flags = append(cmd.Flags, values.add(field, spec.ValueType(field)))
+		if field.Negatable {
+			cmd.Flags = append(cmd.Flags, &cli.BoolFlag{
+				Name:   field.NegatedName(),
+				Usage:  "set --" + field.Name + " to false",
+				Hidden: field.Hidden,
+			})
+		}
+	}
+	for _, sub := range spec.Subcommands {
+		cmd.Commands = append(cmd.Commands, command(sub))
+	}
+
+	cmd.Before = func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+		defer values.reset()
+		values.cmd = cmd
+		err := spec.Bind(values, cmd.Args().Slice())
+		if err == nil {
+			// After closes what is opened here, it runs even if Before fails
+			err = spec.Open()
+		}
+		if err != nil {
+			return ctx, err
+		}
+		if before, ok := obj.(HasBefore); ok {
+			return before.Before(ctx, cmd)
+		}
+		return ctx, nil
+	}
+	cmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+		if act, ok := obj.(Actionable); ok {
+			return act.Action(ctx, cmd)
+		}
+		err := cli.ShowSubcommandHelp(cmd)
+		if err == nil {
+			err = clive.ErrCommandNotImplemented()
+		}
+		return err
+	}
+	cmd.After = func(ctx context.Context, cmd *cli.Command) (err error) {
+		if after, ok := obj.(HasAfter); ok {
+			err = after.After(ctx, cmd)
+		}
+		cerr := spec.Close()
+		if err == nil {
+			err = cerr
+		}
+		return
+	}
+	return cmd
+}
+
+// flagValues collects the values of the flags of a command for
+// clive.CommandSpec.Bind. Bool flags and counters are parsed by urfave/cli,
+// everything else is kept raw and parsed by clive.
+type flagValues struct {
+	cmd      *cli.Command
+	raw      *clive.RawValues
+	counters map[string]*int
+	// repeated lists the flags of repeated groups, all kept raw
+	repeated map[string]bool
+}
+
+func (fv *flagValues) add(field *clive.FieldSpec, t reflect.Type) cli.Flag {
+	sources := cli.EnvVars(field.Envs...)
+	usage := clive.UsageWithVariants(field.Usage, field.Variants)
+	// clive checks required flags of variants once one is selected
+	required := field.Required && field.Selector == ""
+	switch {
+	case field.Repeated != "":
+		fv.repeated[field.Name] = true
+		// clive needs the order of the flags of repeated groups, bools
+		// included, and reads their indexed environment variables itself
+		return &cli.GenericFlag{
+			Name:      field.Name,
+			Aliases:   field.Aliases,
+			Usage:     usage,
+			Hidden:    field.Hidden,
+			TakesFile: field.TakesFile,
+			Value:     fv.raw.Value(field.Name),
+		}
+	case t == clive.Reflected[clive.Counter]():
+		fv.counters[field.Name] = new(int)
+		return &cli.BoolFlag{
+			Name:     field.Name,
+			Aliases:  field.Aliases,
+			Usage:    usage,
+			Sources:  sources,
+			Hidden:   field.Hidden,
+			Required: required,
+			Config:   cli.BoolConfig{Count: fv.counters[field.Name]},
+		}
+	case t.Kind() == reflect.Bool && field.Negatable:
+		// clive reads the environment variables of negatable flags and checks
+		// them for being required itself, urfave/cli can't tell values from
+		// the environment from those given on the command line
+		return &cli.BoolFlag{
+			Name:    field.Name,
+			Aliases: field.Aliases,
+			Usage:   usage,
+			Hidden:  field.Hidden,
+		}
+	case t.Kind() == reflect.Bool:
+		boolSources := cli.ValueSourceChain{}
+		for _, env := range field.Envs {
+			boolSources.Chain = append(boolSources.Chain, boolEnv(env))
+		}
+		return &cli.BoolFlag{
+			Name:     field.Name,
+			Aliases:  field.Aliases,
+			Usage:    usage,
+			Sources:  boolSources,
+			Hidden:   field.Hidden,
+			Required: required,
+		}
+	case field.EnvFormat != "":
+		// clive reads environment variables in other formats itself, urfave/cli
+		// would take them for values given on the command line
+		return &cli.GenericFlag{
+			Name:      field.Name,
+			Aliases:   field.Aliases,
+			Usage:     usage,
+			Hidden:    field.Hidden,
+			TakesFile: field.TakesFile,
+			Value:     fv.raw.Value(field.Name),
+		}
+	default:
+		return &cli.GenericFlag{
+			Name:      field.Name,
+			Aliases:   field.Aliases,
+			Usage:     usage,
+			Sources:   sources,
+			Hidden:    field.Hidden,
+			Required:  required,
+			TakesFile: field.TakesFile,
+			Value:     fv.raw.Value(field.Name),
+		}
+	}
+}
+
+func (fv *flagValues) FlagValues(name string) ([]string, bool) {
+	if !fv.cmd.IsSet(name) {
+		return nil, false
+	}
+	if count, ok := fv.counters[name]; ok {
+		return []string{strconv.Itoa(*count)}, true
+	}
+	if fv.raw.Value(name) != nil && (!fv.raw.Value(name).IsBoolFlag() || fv.repeated[name]) {
+		return fv.raw.FlagValues(name)
+	}
+	return []string{strconv.FormatBool(fv.cmd.Bool(name))}, true
+}
+
+func (fv *flagValues) FlagOrder() []string {
+	return fv.raw.FlagOrder()
+}
+
+// reset forgets values of the last run, flags keep their values between runs
+// of the same command.
+func (fv *flagValues) reset() {
+	fv.raw.Reset()
+	for _, count := range fv.counters {
+		*count = 0
+	}
+}
+
+// boolEnv reads a bool flag from an environment variable, accepting the same
+// spellings clive does.
+type boolEnv string
+
+func (e boolEnv) Lookup() (string, bool) {
+	value, ok := os.LookupEnv(string(e))
+	if !ok || value == "" {
+		return value, ok
+	}
+	var b bool
+	if err := clive.ParseValue(&b, value); err != nil {
+		return value, true
+	}
+	return strconv.FormatBool(b), true
+}
+
+func (e boolEnv) IsFromEnv() bool  { return true }
+func (e boolEnv) Key() string      { return string(e) }
+func (e boolEnv) String() string   { return fmt.Sprintf("environment variable %q", string(e)) }
+func (e boolEnv) GoString() string { return fmt.Sprintf("boolEnv(%q)", string(e)) }